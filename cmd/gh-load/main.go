@@ -0,0 +1,82 @@
+// gh-load writes the configuration files of a repository branch into a Terragrunt config path,
+// it replaces the gh-load-folder, gh-load-file, gh-get-default-branch and gh-list-branches targets of Makefile_infra
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	testGithubModule "github.com/vistimi/infrastructure-modules/test/github/module"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	options := testGithubModule.LoadOptions{}
+	flag.StringVar(&options.Owner, "owner", os.Getenv("ORGANIZATION_NAME"), "owner of the repository")
+	flag.StringVar(&options.Repository, "repository", os.Getenv("REPOSITORY_NAME"), "name of the repository")
+	flag.StringVar(&options.Branch, "branch", os.Getenv("BRANCH_NAME"), "branch to load, the default branch if empty")
+	flag.StringVar(&options.Path, "path", "", "file or folder of the repository to load")
+	flag.StringVar(&options.Destination, "destination", os.Getenv("TERRAGRUNT_CONFIG_PATH"), "folder where the files are written")
+	flag.StringVar(&options.Extension, "extension", os.Getenv("OVERRIDE_EXTENSION"), "extension appended to the file names")
+	flag.BoolVar(&options.Recursive, "recursive", false, "load the sub folders")
+	flag.BoolVar(&options.DryRun, "dry-run", false, "list the files without writing them")
+	cacheDir := flag.String("cache-dir", "", "folder to cache the answers with their ETag")
+	defaultBranch := flag.Bool("default-branch", false, "print the default branch of the repository")
+	listBranches := flag.Bool("list-branches", false, "print the branches of the repository")
+	flag.Parse()
+
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return fmt.Errorf("env variable empty: GITHUB_TOKEN")
+	}
+	client := testGithubModule.NewClient(token)
+	if *cacheDir != "" {
+		cache, err := testGithubModule.NewDirCache(*cacheDir)
+		if err != nil {
+			return err
+		}
+		client.Cache = cache
+	}
+
+	switch {
+	case *defaultBranch:
+		branch, err := client.GetDefaultBranch(options.Owner, options.Repository)
+		if err != nil {
+			return err
+		}
+		fmt.Println(branch)
+		return nil
+	case *listBranches:
+		branches, err := client.ListBranches(options.Owner, options.Repository)
+		if err != nil {
+			return err
+		}
+		for _, branch := range branches {
+			fmt.Println(branch)
+		}
+		return nil
+	}
+
+	if options.Path == "" || options.Destination == "" {
+		return fmt.Errorf("path and destination are required")
+	}
+	loaded, err := client.Load(options)
+	for _, file := range loaded {
+		switch {
+		case options.DryRun:
+			fmt.Printf("%s -> %s (%d bytes)\n", file.Path, file.Destination, file.Size)
+		case file.Cached:
+			fmt.Printf("GET Github file:: %s -> %s (not modified)\n", file.Path, file.Destination)
+		default:
+			fmt.Printf("GET Github file:: %s -> %s\n", file.Path, file.Destination)
+		}
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+// sweeper deletes the resources of the tests older than a TTL, it lists them without the -delete flag
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	"github.com/vistimi/infrastructure-modules/test/aws/sweeper"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	region := flag.String("region", os.Getenv("AWS_REGION_NAME"), "region of the resources")
+	account := flag.String("account", os.Getenv("AWS_PROFILE_NAME"), "Account tag of the resources, any if empty")
+	profile := flag.String("profile", os.Getenv("AWS_PROFILE_NAME"), "AWS profile of the credentials")
+	ttl := flag.Duration("ttl", 6*time.Hour, "age after which the resources of a test are deleted")
+	remove := flag.Bool("delete", false, "delete the resources instead of listing them")
+	flag.Parse()
+
+	if *region == "" {
+		return fmt.Errorf("region is required")
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Profile:           *profile,
+		Config:            awsSDK.Config{Region: region},
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return err
+	}
+
+	s := sweeper.Sweeper{
+		Kinds:   sweeper.Kinds(sess),
+		Account: *account,
+		Region:  *region,
+		TTL:     *ttl,
+		DryRun:  !*remove,
+		Log: func(format string, args ...any) {
+			fmt.Printf(format+"\n", args...)
+		},
+	}
+	result, err := s.Sweep()
+	if result != nil {
+		fmt.Printf("%d expired tests, %d resources, %d deleted, %d failed\n", len(result.ExpiredTestIDs), len(result.Resources), len(result.Deleted), len(result.Failures))
+	}
+	return err
+}
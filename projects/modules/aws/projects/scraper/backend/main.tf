@@ -4,7 +4,7 @@ locals {
 
   project_name = "sp"
   service_name = "be"
-  name         = join("-", [var.name_prefix, local.project_name, local.service_name, var.name_suffix])
+  name         = coalesce(var.name, join("-", [var.name_prefix, local.project_name, local.service_name, var.name_suffix]))
 }
 
 module "microservice" {
@@ -8,6 +8,12 @@ variable "name_suffix" {
   type        = string
 }
 
+variable "name" {
+  description = "The common name of the microservice resources, `<name_prefix>-sp-<service>-<name_suffix>` when null"
+  type        = string
+  default     = null
+}
+
 variable "vpc" {
   type = object({
     id   = string
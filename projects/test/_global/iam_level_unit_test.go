@@ -1,13 +1,12 @@
 package iam_team_test
 
 import (
-	"math/rand"
 	"testing"
-	"time"
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
 	testAwsModule "github.com/vistimi/infrastructure-modules/test/aws/module"
+	"github.com/vistimi/infrastructure-modules/test/config"
+	testGithubModule "github.com/vistimi/infrastructure-modules/test/github/module"
 	"github.com/vistimi/infrastructure-modules/test/util"
 )
 
@@ -16,11 +15,10 @@ const (
 )
 
 func Test_Unit_Global_Level(t *testing.T) {
-	// t.Parallel()
-	rand.Seed(time.Now().UnixNano())
-
+	t.Parallel()
+	cfg := config.Get(t, config.Aws, config.Github)
 	namePrefix := ""
-	id := util.RandomID(4)
+	id := util.TestID(t, 4)
 	orgName := util.Format("-", "org", id)
 	teamName := util.Format("-", "team", id)
 
@@ -83,8 +81,16 @@ func Test_Unit_Global_Level(t *testing.T) {
 
 	externalAssumeRoleArns := []string{}
 
+	githubAccesses := []testGithubModule.Access{
+		{
+			Owner: "vistimi",
+			Name:  "infrastructure-modules",
+		},
+	}
+
+	levelPath := util.CopyModule(t, pathLevel)
 	options := &terraform.Options{
-		TerraformDir: pathLevel,
+		TerraformDir: levelPath,
 		Vars: map[string]any{
 			"name_prefix": namePrefix,
 			"aws": map[string]any{
@@ -109,12 +115,9 @@ func Test_Unit_Global_Level(t *testing.T) {
 			},
 
 			"github": map[string]any{
-				"accesses": []map[string]any{
-					{
-						"owner": "vistimi",
-						"name":  "infrastructure-modules",
-					},
-				},
+				"accesses": util.Reduce(githubAccesses, func(access testGithubModule.Access) map[string]any {
+					return map[string]any{"owner": access.Owner, "name": access.Name}
+				}),
 				"repositories": []map[string]any{
 					{
 						"variables": []map[string]any{
@@ -136,21 +139,47 @@ func Test_Unit_Global_Level(t *testing.T) {
 		},
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			// destroy all resources if panic
-			terraform.Destroy(t, options)
-		}
-		terratestStructure.RunTestStage(t, "cleanup", func() {
-			terraform.Destroy(t, options)
-		})
-	}()
+	lifecycle := util.NewLifecycle(t, options)
+	defer lifecycle.Cleanup()
 
-	terratestStructure.RunTestStage(t, "deploy", func() {
+	util.RunTestStage(t, "deploy", func() {
 		terraform.InitAndApply(t, options)
 	})
-	terratestStructure.RunTestStage(t, "validate", func() {
+	util.RunTestStage(t, "validate", func() {
+		awsClient := testAwsModule.NewClient(testAwsModule.OptionsFromConfig(cfg))
 		prefixName := util.Format("-", orgName, teamName)
-		testAwsModule.ValidateLevel(t, util.GetEnvVariable("AWS_REGION_NAME"), prefixName, groups...)
+		awsClient.ValidateLevel(t, prefixName, groups...)
+
+		// one environment per user with its credentials, secrets are not forwarded for the repositories
+		outputGroups := terraform.OutputMapOfObjects(t, options, "aws")["groups"].(map[string]any)
+		environments := []testGithubModule.Environment{}
+		for _, group := range groups {
+			for _, user := range group.Users {
+				userName := user["name"].(string)
+				outputUser := outputGroups[group.Name].(map[string]any)["users"].(map[string]any)[userName].(map[string]any)["user"].(map[string]any)
+				environments = append(environments, testGithubModule.Environment{
+					Name:     userName,
+					Accesses: githubAccesses,
+					Variables: []testGithubModule.Variable{
+						{Key: "AWS_ACCESS_KEY", Value: util.Ptr(outputUser["iam_access_key_id"].(string))},
+						{Key: "AWS_ACCOUNT_ID", Value: util.Ptr(cfg.AccountId)},
+						{Key: "AWS_PROFILE_NAME", Value: util.Ptr(outputUser["iam_user_name"].(string))},
+						{Key: "AWS_REGION_NAME", Value: util.Ptr(cfg.AccountRegion)},
+					},
+					Secrets: []testGithubModule.Variable{{Key: "AWS_SECRET_KEY"}},
+				})
+			}
+		}
+
+		client := testGithubModule.NewClient(cfg.GithubToken)
+		testGithubModule.ValidateVariables(t, client, cfg.GithubOwner, testGithubModule.Variables{
+			Repositories: []testGithubModule.Repository{
+				{
+					Accesses:  githubAccesses,
+					Variables: []testGithubModule.Variable{{Key: "REPO_" + id, Value: util.Ptr("test")}},
+				},
+			},
+			Environments: environments,
+		})
 	})
 }
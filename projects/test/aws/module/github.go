@@ -0,0 +1,58 @@
+package module
+
+import (
+	"testing"
+
+	testAwsModule "github.com/vistimi/infrastructure-modules/test/aws/module"
+	"github.com/vistimi/infrastructure-modules/test/config"
+	testGithubModule "github.com/vistimi/infrastructure-modules/test/github/module"
+)
+
+const (
+	// same as the Makefile_infra targets
+	OverrideExtension = "override"
+)
+
+// GithubClient is nil without token, the GITHUB_TOKEN of the settings
+func GithubClient(token string) *testGithubModule.Client {
+	if token == "" {
+		return nil
+	}
+	return testGithubModule.NewClient(token)
+}
+
+// Branches returns the branches of the repository to test, the patterns of testAwsModule.BranchNamesEnv require GITHUB_TOKEN
+func Branches(t *testing.T, owner, repository string, defaults ...string) []string {
+	var lister testAwsModule.BranchLister
+	if client := GithubClient(config.Get(t).GithubToken); client != nil {
+		lister = client
+	}
+	return testAwsModule.Branches(t, lister, owner, repository, defaults...)
+}
+
+// LoadOverrideFiles writes the override files of the branch in a temporary folder and returns it.
+// Without GITHUB_TOKEN, the files are expected in the current folder, loaded by the prepare targets of the Makefile
+func LoadOverrideFiles(t *testing.T, owner, repository, branch, path string) (folder string) {
+	client := GithubClient(config.Get(t).GithubToken)
+	if client == nil {
+		t.Logf("GITHUB_TOKEN empty, using the override files of the current folder for branch %s", branch)
+		return "."
+	}
+
+	folder = t.TempDir()
+	loaded, err := client.Load(testGithubModule.LoadOptions{
+		Owner:       owner,
+		Repository:  repository,
+		Branch:      branch,
+		Path:        path,
+		Destination: folder,
+		Extension:   OverrideExtension,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range loaded {
+		t.Logf("GET Github file:: %s@%s -> %s", file.Path, branch, file.Destination)
+	}
+	return folder
+}
@@ -2,39 +2,32 @@ package module
 
 import (
 	"fmt"
-	"math/rand"
+	"path/filepath"
 	"strings"
 	"testing"
-	"time"
 
+	"github.com/vistimi/infrastructure-modules/test/config"
+	"github.com/vistimi/infrastructure-modules/test/dotenv"
 	"github.com/vistimi/infrastructure-modules/test/util"
 
 	testAwsModule "github.com/vistimi/infrastructure-modules/test/aws/module"
 )
 
-var (
-	AccountName   = util.GetEnvVariable("AWS_PROFILE_NAME")
-	AccountId     = util.GetEnvVariable("AWS_ACCOUNT_ID")
-	AccountRegion = util.GetEnvVariable("AWS_REGION_NAME")
-	DomainName    = fmt.Sprintf("%s.%s", util.GetEnvVariable("DOMAIN_NAME"), util.GetEnvVariable("DOMAIN_SUFFIX"))
-)
-
 const (
 	// https://docs.aws.amazon.com/AmazonECS/latest/developerguide/memory-management.html#ecs-reserved-memory
 	ECSReservedMemory = 100
 )
 
 func SetupMicroservice(t *testing.T, microserviceInformation testAwsModule.MicroserviceInformation, traffics []testAwsModule.Traffic) (namePrefix string, nameSuffix string, tags map[string]string, trafficsMap []map[string]any, docker map[string]any, bucketEnv map[string]any) {
-	rand.Seed(time.Now().UnixNano())
-
 	// global variables
+	cfg := config.Get(t, config.Aws)
 	namePrefix = "vi"
-	id := util.RandomID(4)
-	nameSuffix = strings.ToLower(util.Format("-", util.GetEnvVariable("AWS_PROFILE_NAME"), id))
+	id := util.TestID(t, 4, testAwsModule.NewClient(testAwsModule.OptionsFromConfig(cfg)).TaggedIDInUse())
+	nameSuffix = strings.ToLower(util.Format("-", cfg.AccountName, id))
 	tags = map[string]string{
 		"TestID":  id,
-		"Account": AccountName,
-		"Region":  AccountRegion,
+		"Account": cfg.AccountName,
+		"Region":  cfg.AccountRegion,
 	}
 
 	for _, traffic := range traffics {
@@ -86,8 +79,35 @@ func SetupMicroservice(t *testing.T, microserviceInformation testAwsModule.Micro
 		"force_destroy": true,
 		"versioning":    false,
 		"file_key":      fmt.Sprintf("%s.env", microserviceInformation.Branch),
-		"file_path":     "override.env",
+		"file_path":     WriteEnvFile(t, microserviceInformation.Env, microserviceInformation.EnvFiles...),
 	}
 
 	return namePrefix, nameSuffix, tags, trafficsMap, docker, bucketEnv
 }
+
+// WriteEnvFile merges the env files and the variables in a file of the test, the shared module folders are not modified,
+// the file is kept with the state of the test to resume it
+func WriteEnvFile(t *testing.T, variables map[string]string, files ...string) (path string) {
+	env := dotenv.New()
+	for _, file := range files {
+		fileEnv, err := dotenv.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env.Merge(fileEnv)
+	}
+	variablesEnv, err := dotenv.FromMap(variables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Merge(variablesEnv)
+
+	path, err = filepath.Abs(filepath.Join(util.TestStateDir(t), "override.env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dotenv.WriteFile(path, env); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
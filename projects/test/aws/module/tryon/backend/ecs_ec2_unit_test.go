@@ -1,16 +1,16 @@
 package microservice_test
 
 import (
-	"fmt"
 	"testing"
 
 	"golang.org/x/exp/maps"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
 	testAwsProjectModule "github.com/vistimi/infrastructure-modules/projects/test/aws/module"
 	testAwsModule "github.com/vistimi/infrastructure-modules/test/aws/module"
+	"github.com/vistimi/infrastructure-modules/test/aws/naming"
+	"github.com/vistimi/infrastructure-modules/test/config"
 	"github.com/vistimi/infrastructure-modules/test/util"
 )
 
@@ -20,17 +20,17 @@ const (
 
 	Rootpath         = "../../../../../.."
 	MicroservicePath = Rootpath + "/modules/aws/container/microservice"
+
+	organizationName = "vistimi"
+	repositoryName   = "viton-hd"
+	defaultBranch    = "trunk"
+	healthCheckPath  = "/ping"
 )
 
-var (
-	AccountName   = util.GetEnvVariable("AWS_PROFILE_NAME")
-	AccountId     = util.GetEnvVariable("AWS_ACCOUNT_ID")
-	AccountRegion = util.GetEnvVariable("AWS_REGION_NAME")
-	DomainName    = fmt.Sprintf("%s.%s", util.GetEnvVariable("DOMAIN_NAME"), util.GetEnvVariable("DOMAIN_SUFFIX"))
-
-	MicroserviceInformation = testAwsModule.MicroserviceInformation{
-		Branch:          "trunk", // TODO: make it flexible for testing other branches
-		HealthCheckPath: "/ping",
+func MicroserviceInformation(branch string) testAwsModule.MicroserviceInformation {
+	return testAwsModule.MicroserviceInformation{
+		Branch:          branch,
+		HealthCheckPath: healthCheckPath,
 		Docker: testAwsModule.Docker{
 			Registry: &testAwsModule.Registry{
 				Ecr: &testAwsModule.Ecr{
@@ -38,14 +38,21 @@ var (
 				},
 			},
 			Repository: testAwsModule.Repository{
-				Name: "viton-hd-trunk-rest", // TODO: make it flexible for testing other branches
+				Name: testAwsModule.EcrRepositoryName(repositoryName, branch, "-rest"),
 			},
 			Image: &testAwsModule.Image{
 				Tag: "latest",
 			},
 		},
 	}
+}
+
+// Branches are the branches of the repository to test, see testAwsModule.BranchNamesEnv
+func Branches(t *testing.T) []string {
+	return testAwsProjectModule.Branches(t, organizationName, repositoryName, defaultBranch)
+}
 
+var (
 	Traffics = []testAwsModule.Traffic{
 		{
 			Listener: testAwsModule.TrafficPoint{
@@ -79,18 +86,26 @@ func SetupVars(t *testing.T) (vars map[string]any) {
 // https://docs.aws.amazon.com/elastic-inference/latest/developerguide/ei-dlc-ecs-pytorch.html
 // https://docs.aws.amazon.com/deep-learning-containers/latest/devguide/deep-learning-containers-ecs-tutorials-training.html
 func Test_Unit_Microservice_FPGA_ECS_EC2_VtonHd(t *testing.T) {
-	// t.Parallel()
-	namePrefix, nameSuffix, tags, traffics, docker, _ := testAwsProjectModule.SetupMicroservice(t, MicroserviceInformation, Traffics)
+	t.Parallel()
+	testAwsModule.RunBranches(t, Branches(t), testMicroserviceFPGAECSEC2VtonHd)
+}
+
+func testMicroserviceFPGAECSEC2VtonHd(t *testing.T, branch string) {
+	cfg := config.Get(t, config.Aws, config.Vpc)
+	namePrefix, nameSuffix, tags, traffics, docker, _ := testAwsProjectModule.SetupMicroservice(t, MicroserviceInformation(branch), Traffics)
 	vars := SetupVars(t)
 	serviceNameSuffix := "unique"
 
+	// the module and the validators share the name built within the limits of the resources
+	name := naming.Microservice.Build(namePrefix, projectName, serviceName, nameSuffix)
+	microservicePath := util.CopyModule(t, MicroservicePath)
 	options := util.Ptr(terraform.Options{
-		TerraformDir: MicroservicePath,
+		TerraformDir: microservicePath,
 		Vars: map[string]interface{}{
-			"name": util.Format("-", namePrefix, projectName, serviceName, nameSuffix),
+			"name": name,
 
 			"vpc": map[string]any{
-				"id":   util.GetEnvVariable("VPC_ID"),
+				"id":   cfg.VpcId,
 				"tier": "public",
 			},
 
@@ -155,26 +170,23 @@ func Test_Unit_Microservice_FPGA_ECS_EC2_VtonHd(t *testing.T) {
 	})
 	maps.Copy(options.Vars, vars)
 
-	defer func() {
-		if r := recover(); r != nil {
-			// destroy all resources if panic
-			terraform.Destroy(t, options)
-		}
-		terratestStructure.RunTestStage(t, "cleanup", func() {
-			terraform.Destroy(t, options)
-		})
-	}()
-
-	terratestStructure.RunTestStage(t, "deploy", func() {
+	lifecycle := util.NewLifecycle(t, options)
+	defer lifecycle.Cleanup()
+
+	util.RunTestStage(t, "deploy", func() {
 		terraform.Init(t, options)
 		terraform.Plan(t, options)
 		terraform.Apply(t, options)
 	})
-	terratestStructure.RunTestStage(t, "validate", func() {
+	util.RunTestStage(t, "validate", func() {
+		client := testAwsModule.NewClient(testAwsModule.OptionsFromConfig(cfg))
 		// TODO: test that /etc/ecs/ecs.config is not empty, requires key_name coming from terratest maybe
-		name := util.Format("-", namePrefix, projectName, serviceName, nameSuffix)
-		serviceName := util.Format("-", name, serviceNameSuffix)
-		testAwsModule.ValidateMicroservice(t, name, Deployment, serviceName)
-		testAwsModule.ValidateRestEndpoints(t, MicroservicePath, Deployment, Traffics, name, "")
+		serviceName := naming.Service(name, serviceNameSuffix)
+		defer client.DiagnosticsOnFailure(t, testAwsModule.DiagnosticsTest{ClusterName: name, ServiceName: serviceName, MicroservicePath: microservicePath, ModulePath: ""})
+		client.ValidateMicroservice(t, name, Deployment, serviceName)
+		client.ValidateAutoScaling(t, name, serviceName, testAwsModule.AutoScalingFromVars(t, options.Vars))
+		// no route53 records, the endpoints are only reached through the load balancer
+		client.ValidateRestEndpoints(t, microservicePath, Deployment, Traffics, name, "")
+		client.ValidateLoadBalancer(t, testAwsModule.LoadBalancerArnFromState(t, microservicePath, ""), Traffics, healthCheckPath)
 	})
 }
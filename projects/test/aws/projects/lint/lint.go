@@ -0,0 +1,479 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// relative to the root of the repository, same as `projects_path` in projects/modules/aws/iam/statements/project
+	ProjectsPath = "projects/modules/aws/projects"
+
+	MicroserviceFile = "microservice.yml"
+	ProjectsFile     = "projects.yml"
+	RepositoryFile   = "repository.yml"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a problem found in a file at a given position
+type Issue struct {
+	File     string
+	Line     int
+	Column   int
+	Severity Severity
+	Rule     string
+	Message  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s [%s] %s", i.File, i.Line, i.Column, i.Severity, i.Rule, i.Message)
+}
+
+var (
+	// `template_vars` in projects/modules/aws/iam/statements/project
+	TemplateVars = []string{"name_prefix", "user_name", "branch_name", "bucket_picture_name", "bucket_env_name"}
+	// microservice.yml is also templated with the repository names
+	MicroserviceTemplateVars = append(append([]string{}, TemplateVars...), "project_name", "service_name")
+
+	DeploymentTypes = []string{"microservice", "standalone"}
+	Effects         = []string{"Allow", "Deny"}
+	Partitions      = []string{"aws", "aws-cn", "aws-us-gov", "*"}
+
+	// services where a wildcard action on a wildcard resource gives away the account
+	SensitiveServices = []string{"iam", "sts", "organizations", "kms", "secretsmanager"}
+
+	statementKeys = []string{"sid", "actions", "effect", "resources", "conditions"}
+	conditionKeys = []string{"test", "variable", "values"}
+
+	nameRegexp     = regexp.MustCompile(`^[0-9A-Za-z!_-]+$`)
+	sidRegexp      = regexp.MustCompile(`^[0-9A-Za-z]+$`)
+	actionRegexp   = regexp.MustCompile(`^[a-z0-9-]+:[A-Za-z0-9*?]+$`)
+	templateRegexp = regexp.MustCompile(`\$\{([^}]*)\}`)
+	identRegexp    = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)\s*$`)
+)
+
+// Lint validates all the configuration files of the projects from the root of the repository
+func Lint(rootPath string) ([]Issue, error) {
+	projectsPath := filepath.Join(rootPath, ProjectsPath)
+	l := &linter{rootPath: rootPath}
+
+	microservicePath := filepath.Join(projectsPath, MicroserviceFile)
+	microservice, err := l.parse(microservicePath)
+	if os.IsNotExist(err) {
+		l.add(microservicePath, nil, SeverityError, "missing-file", "microservice file does not exist")
+	} else if err != nil {
+		return nil, err
+	}
+	var microserviceSids map[string]position
+	if microservice != nil {
+		microserviceSids = l.lintMicroservice(microservicePath, microservice)
+	}
+
+	projectsFilePath := filepath.Join(projectsPath, ProjectsFile)
+	projects, err := l.parse(projectsFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if projects != nil {
+		l.lintProjects(projectsFilePath, projects)
+	}
+
+	// every service folder must have a repository file, see the precondition in the statements module
+	projectDirs, err := os.ReadDir(projectsPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, projectDir := range projectDirs {
+		if !projectDir.IsDir() {
+			continue
+		}
+		serviceDirs, err := os.ReadDir(filepath.Join(projectsPath, projectDir.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, serviceDir := range serviceDirs {
+			if !serviceDir.IsDir() {
+				continue
+			}
+			repositoryPath := filepath.Join(projectsPath, projectDir.Name(), serviceDir.Name(), RepositoryFile)
+			repository, err := l.parse(repositoryPath)
+			if os.IsNotExist(err) {
+				l.add(repositoryPath, nil, SeverityError, "missing-file", "repository file does not exist")
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			l.lintRepository(repositoryPath, repository, microserviceSids)
+		}
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		if l.issues[i].File != l.issues[j].File {
+			return l.issues[i].File < l.issues[j].File
+		}
+		return l.issues[i].Line < l.issues[j].Line
+	})
+	return l.issues, nil
+}
+
+// LintRepository validates the content of a repository.yml file
+func LintRepository(file string, data []byte) ([]Issue, error) {
+	l := &linter{}
+	root, err := l.decode(file, data)
+	if err != nil || root == nil {
+		return l.issues, err
+	}
+	l.lintRepository(file, root, nil)
+	return l.issues, nil
+}
+
+// LintMicroservice validates the content of a microservice.yml file
+func LintMicroservice(file string, data []byte) ([]Issue, error) {
+	l := &linter{}
+	root, err := l.decode(file, data)
+	if err != nil || root == nil {
+		return l.issues, err
+	}
+	l.lintMicroservice(file, root)
+	return l.issues, nil
+}
+
+// LintProjects validates the content of a projects.yml file, paths are resolved from the root of the repository
+func LintProjects(rootPath, file string, data []byte) ([]Issue, error) {
+	l := &linter{rootPath: rootPath}
+	root, err := l.decode(file, data)
+	if err != nil || root == nil {
+		return l.issues, err
+	}
+	l.lintProjects(file, root)
+	return l.issues, nil
+}
+
+// HasErrors returns true if any issue is an error
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// position of a declaration, used to report duplicates across files
+type position struct {
+	file string
+	line int
+}
+
+type linter struct {
+	rootPath string
+	issues   []Issue
+}
+
+func (l *linter) add(file string, node *yaml.Node, severity Severity, rule, format string, args ...any) {
+	issue := Issue{File: file, Severity: severity, Rule: rule, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+	}
+	l.issues = append(l.issues, issue)
+}
+
+func (l *linter) parse(file string) (*yaml.Node, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return l.decode(file, data)
+}
+
+// decode returns the root mapping of the document, nil if the document is empty or invalid
+func (l *linter) decode(file string, data []byte) (*yaml.Node, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		l.add(file, nil, SeverityError, "syntax", "%s", err.Error())
+		return nil, nil
+	}
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		l.add(file, root, SeverityError, "schema", "document must be a mapping")
+		return nil, nil
+	}
+	return root, nil
+}
+
+func (l *linter) lintRepository(file string, root *yaml.Node, microserviceSids map[string]position) {
+	for _, key := range []string{"project_name", "service_name", "deployment_type"} {
+		value := l.requiredScalar(file, root, key)
+		if value == nil {
+			continue
+		}
+		switch key {
+		case "project_name", "service_name":
+			if !nameRegexp.MatchString(value.Value) {
+				l.add(file, value, SeverityError, "schema", "%s %q must match %s", key, value.Value, nameRegexp)
+			}
+		case "deployment_type":
+			if !contains(DeploymentTypes, value.Value) {
+				l.add(file, value, SeverityError, "schema", "deployment_type %q must be one of %v", value.Value, DeploymentTypes)
+			}
+		}
+	}
+
+	sids := map[string]position{}
+	if deploymentType := lookup(root, "deployment_type"); deploymentType != nil && deploymentType.Value == "microservice" {
+		// statements of microservice.yml are concatenated with the ones of the repository
+		for sid, declaration := range microserviceSids {
+			sids[sid] = declaration
+		}
+	}
+	l.lintStatements(file, lookup(root, "statements"), TemplateVars, sids)
+}
+
+func (l *linter) lintMicroservice(file string, root *yaml.Node) map[string]position {
+	statements := lookup(root, "statements")
+	if statements == nil {
+		l.add(file, root, SeverityError, "schema", "missing required key statements")
+		return nil
+	}
+	sids := map[string]position{}
+	l.lintStatements(file, statements, MicroserviceTemplateVars, sids)
+	return sids
+}
+
+func (l *linter) lintProjects(file string, root *yaml.Node) {
+	projects := lookup(root, "projects")
+	if projects == nil {
+		return
+	}
+	if projects.Kind != yaml.MappingNode {
+		l.add(file, projects, SeverityError, "schema", "projects must be a mapping")
+		return
+	}
+	for i := 0; i+1 < len(projects.Content); i += 2 {
+		projectKey, project := projects.Content[i], projects.Content[i+1]
+		if !nameRegexp.MatchString(projectKey.Value) {
+			l.add(file, projectKey, SeverityError, "schema", "project name %q must match %s", projectKey.Value, nameRegexp)
+		}
+		services := lookup(project, "services")
+		if services == nil {
+			continue
+		}
+		if services.Kind != yaml.MappingNode {
+			l.add(file, services, SeverityError, "schema", "services of project %s must be a mapping", projectKey.Value)
+			continue
+		}
+		for j := 0; j+1 < len(services.Content); j += 2 {
+			serviceKey, service := services.Content[j], services.Content[j+1]
+			if !nameRegexp.MatchString(serviceKey.Value) {
+				l.add(file, serviceKey, SeverityError, "schema", "service name %q must match %s", serviceKey.Value, nameRegexp)
+			}
+			path := l.requiredScalar(file, service, "path")
+			if path == nil || l.rootPath == "" {
+				continue
+			}
+			repositoryPath := filepath.Join(l.rootPath, path.Value, RepositoryFile)
+			if _, err := os.Stat(repositoryPath); err != nil {
+				l.add(file, path, SeverityError, "missing-file", "repository file does not exist: %s", filepath.Join(path.Value, RepositoryFile))
+			}
+		}
+	}
+}
+
+func (l *linter) lintStatements(file string, statements *yaml.Node, templateVars []string, sids map[string]position) {
+	if statements == nil {
+		return
+	}
+	if statements.Kind != yaml.SequenceNode {
+		l.add(file, statements, SeverityError, "schema", "statements must be a list")
+		return
+	}
+	for _, statement := range statements.Content {
+		if statement.Kind != yaml.MappingNode {
+			l.add(file, statement, SeverityError, "schema", "statement must be a mapping")
+			continue
+		}
+		l.unknownKeys(file, statement, statementKeys)
+
+		if sid := l.requiredScalar(file, statement, "sid"); sid != nil {
+			if !sidRegexp.MatchString(sid.Value) {
+				l.add(file, sid, SeverityError, "sid", "sid %q must be alphanumeric", sid.Value)
+			}
+			if previous, ok := sids[sid.Value]; ok {
+				l.add(file, sid, SeverityError, "sid", "duplicate sid %q, first declared at %s:%d", sid.Value, previous.file, previous.line)
+			} else {
+				sids[sid.Value] = position{file: file, line: sid.Line}
+			}
+		}
+
+		if effect := l.requiredScalar(file, statement, "effect"); effect != nil && !contains(Effects, effect.Value) {
+			l.add(file, effect, SeverityError, "effect", "effect %q must be one of %v", effect.Value, Effects)
+		}
+
+		actions := l.requiredList(file, statement, "actions")
+		for _, action := range actions {
+			l.templates(file, action, templateVars)
+			if action.Value != "*" && !actionRegexp.MatchString(action.Value) {
+				l.add(file, action, SeverityError, "action", "action %q must be of the form service:Action", action.Value)
+			}
+		}
+
+		resources := l.requiredList(file, statement, "resources")
+		wildcardResource := false
+		for _, resource := range resources {
+			l.templates(file, resource, templateVars)
+			if resource.Value == "*" {
+				wildcardResource = true
+				continue
+			}
+			if err := validateArn(templateRegexp.ReplaceAllString(resource.Value, "x")); err != nil {
+				l.add(file, resource, SeverityError, "arn", "resource %q: %s", resource.Value, err.Error())
+			}
+		}
+
+		if effect := lookup(statement, "effect"); wildcardResource && effect != nil && effect.Value == "Allow" {
+			for _, action := range actions {
+				service, _, _ := strings.Cut(action.Value, ":")
+				if action.Value == "*" || (strings.HasSuffix(action.Value, ":*") && contains(SensitiveServices, service)) {
+					l.add(file, action, SeverityWarning, "over-broad", "action %q is allowed on all resources", action.Value)
+				}
+			}
+		}
+
+		if conditions := lookup(statement, "conditions"); conditions != nil {
+			if conditions.Kind != yaml.SequenceNode {
+				l.add(file, conditions, SeverityError, "schema", "conditions must be a list")
+				continue
+			}
+			for _, condition := range conditions.Content {
+				l.unknownKeys(file, condition, conditionKeys)
+				l.requiredScalar(file, condition, "test")
+				l.requiredScalar(file, condition, "variable")
+				for _, value := range l.requiredList(file, condition, "values") {
+					l.templates(file, value, templateVars)
+				}
+			}
+		}
+	}
+}
+
+// templates checks that only the variables given to `templatefile` are referenced
+func (l *linter) templates(file string, node *yaml.Node, templateVars []string) {
+	if strings.Contains(node.Value, "%{") {
+		l.add(file, node, SeverityError, "template", "template directives are not supported in %q", node.Value)
+	}
+	for _, match := range templateRegexp.FindAllStringSubmatchIndex(node.Value, -1) {
+		if match[0] > 0 && node.Value[match[0]-1] == '$' {
+			continue // escaped with $${
+		}
+		expression := node.Value[match[2]:match[3]]
+		ident := identRegexp.FindStringSubmatch(expression)
+		if ident == nil {
+			l.add(file, node, SeverityError, "template", "template expression ${%s} must be a single variable", expression)
+			continue
+		}
+		if !contains(templateVars, ident[1]) {
+			l.add(file, node, SeverityError, "template", "unknown template variable ${%s}, available: %v", ident[1], templateVars)
+		}
+	}
+}
+
+func (l *linter) requiredScalar(file string, mapping *yaml.Node, key string) *yaml.Node {
+	value := lookup(mapping, key)
+	if value == nil {
+		l.add(file, mapping, SeverityError, "schema", "missing required key %s", key)
+		return nil
+	}
+	if value.Kind != yaml.ScalarNode || value.Value == "" {
+		l.add(file, value, SeverityError, "schema", "%s must be a non empty string", key)
+		return nil
+	}
+	return value
+}
+
+func (l *linter) requiredList(file string, mapping *yaml.Node, key string) []*yaml.Node {
+	value := lookup(mapping, key)
+	if value == nil {
+		l.add(file, mapping, SeverityError, "schema", "missing required key %s", key)
+		return nil
+	}
+	if value.Kind != yaml.SequenceNode || len(value.Content) == 0 {
+		l.add(file, value, SeverityError, "schema", "%s must be a non empty list", key)
+		return nil
+	}
+	var items []*yaml.Node
+	for _, item := range value.Content {
+		if item.Kind != yaml.ScalarNode || item.Value == "" {
+			l.add(file, item, SeverityError, "schema", "%s must only contain non empty strings", key)
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func (l *linter) unknownKeys(file string, mapping *yaml.Node, keys []string) {
+	if mapping.Kind != yaml.MappingNode {
+		l.add(file, mapping, SeverityError, "schema", "expected a mapping")
+		return
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if !contains(keys, mapping.Content[i].Value) {
+			l.add(file, mapping.Content[i], SeverityError, "schema", "unknown key %s, expected one of %v", mapping.Content[i].Value, keys)
+		}
+	}
+}
+
+// validateArn checks the structure arn:partition:service:region:account-id:resource
+func validateArn(arn string) error {
+	fields := strings.SplitN(arn, ":", 6)
+	if len(fields) != 6 || fields[0] != "arn" {
+		return fmt.Errorf("arn must be of the form arn:partition:service:region:account-id:resource")
+	}
+	if !contains(Partitions, fields[1]) {
+		return fmt.Errorf("partition %q must be one of %v", fields[1], Partitions)
+	}
+	if fields[2] == "" {
+		return fmt.Errorf("service is empty")
+	}
+	if fields[5] == "" {
+		return fmt.Errorf("resource is empty")
+	}
+	return nil
+}
+
+func lookup(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	Rootpath = "../../../../.."
+)
+
+func Test_Unit_Lint_Projects(t *testing.T) {
+	issues, err := Lint(Rootpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			t.Error(issue)
+		} else {
+			t.Log(issue)
+		}
+	}
+}
+
+func Test_Unit_Lint_Repository(t *testing.T) {
+	testCases := []struct {
+		name  string
+		yml   string
+		rules []string // expected rule per line, in order
+	}{
+		{
+			name: "valid",
+			yml: `project_name: sp
+service_name: be
+deployment_type: microservice
+statements:
+  - sid: DynamodbFull
+    actions: ["dynamodb:*"]
+    effect: "Allow"
+    resources: ["arn:aws:dynamodb:*:*:table/${name_prefix}sp-${user_name}-${branch_name}-*"]
+`,
+		},
+		{
+			name: "missing keys",
+			yml: `project_name: sp
+statements: []
+`,
+			rules: []string{"schema", "schema"},
+		},
+		{
+			name: "effect and duplicate sid",
+			yml: `project_name: sp
+service_name: be
+deployment_type: microservice
+statements:
+  - sid: Read
+    actions: ["s3:Get*"]
+    effect: allow
+    resources: ["arn:aws:s3:::bucket"]
+  - sid: Read
+    actions: ["s3:List*"]
+    effect: Deny
+    resources: ["arn:aws:s3:::bucket"]
+`,
+			rules: []string{"effect", "sid"},
+		},
+		{
+			name: "malformed arn and unknown template variable",
+			yml: `project_name: sp
+service_name: be
+deployment_type: microservice
+statements:
+  - sid: Read
+    actions: ["s3:Get*"]
+    effect: Allow
+    resources: ["arn:aws:s3:bucket", "arn:aws:s3:::${project_name}-bucket"]
+`,
+			rules: []string{"arn", "template"},
+		},
+		{
+			name: "over broad",
+			yml: `project_name: sp
+service_name: be
+deployment_type: standalone
+statements:
+  - sid: Iam
+    actions: ["iam:*", "s3:*"]
+    effect: Allow
+    resources: ["*"]
+`,
+			rules: []string{"over-broad"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			issues, err := LintRepository("repository.yml", []byte(testCase.yml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(issues) != len(testCase.rules) {
+				t.Fatalf("expected rules %v, got issues:\n%s", testCase.rules, issuesString(issues))
+			}
+			for i, issue := range issues {
+				if issue.Rule != testCase.rules[i] {
+					t.Errorf("expected rule %s, got issue %s", testCase.rules[i], issue)
+				}
+				if issue.Line == 0 {
+					t.Errorf("no line for issue %s", issue)
+				}
+			}
+		})
+	}
+}
+
+func Test_Unit_Lint_Repository_Line(t *testing.T) {
+	yml := `project_name: sp
+service_name: be
+deployment_type: microservice
+statements:
+  - sid: Read
+    actions: ["s3:Get*"]
+    effect: Allows
+    resources: ["*"]
+`
+	issues, err := LintRepository("repository.yml", []byte(yml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("expected one issue, got:\n%s", issuesString(issues))
+	}
+	if issues[0].Line != 7 || issues[0].Column != 13 {
+		t.Errorf("expected position 7:13, got %s", issues[0])
+	}
+}
+
+func Test_Unit_Lint_Microservice(t *testing.T) {
+	yml := `statements:
+  - sid: Env
+    actions: ["s3:*"]
+    effect: "Allow"
+    resources: ["arn:aws:s3:::${name_prefix}${project_name}-${service_name}-${user_name}-${branch_name}-${bucket_env_name}"]
+  - sid: Ecr
+    actions: ["ecr:GetAuthorizationToken"]
+    effect: "Allow"
+    resources: ["arn:aws:ecr:*:*:repository/${organization_name}"]
+`
+	issues, err := LintMicroservice("microservice.yml", []byte(yml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Rule != "template" || issues[0].Line != 9 {
+		t.Fatalf("expected one template issue at line 9, got:\n%s", issuesString(issues))
+	}
+}
+
+func Test_Unit_Lint_ProjectsFile(t *testing.T) {
+	yml := `projects:
+  scraper:
+    services:
+      backend:
+        path: projects/modules/aws/projects/scraper/backend
+      unknown:
+        path: projects/modules/aws/projects/scraper/unknown
+`
+	issues, err := LintProjects(Rootpath, "projects.yml", []byte(yml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Rule != "missing-file" || issues[0].Line != 7 {
+		t.Fatalf("expected one missing-file issue at line 7, got:\n%s", issuesString(issues))
+	}
+}
+
+func issuesString(issues []Issue) string {
+	lines := []string{}
+	for _, issue := range issues {
+		lines = append(lines, issue.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -6,26 +6,36 @@ import (
 	"golang.org/x/exp/maps"
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
 	testAwsProjectModule "github.com/vistimi/infrastructure-modules/projects/test/aws/module"
 	testAwsModule "github.com/vistimi/infrastructure-modules/test/aws/module"
+	"github.com/vistimi/infrastructure-modules/test/aws/naming"
+	"github.com/vistimi/infrastructure-modules/test/config"
 	"github.com/vistimi/infrastructure-modules/test/util"
 )
 
 func Test_Unit_Microservice_ScraperBackend_ECS_EC2(t *testing.T) {
-	// t.Parallel()
-	namePrefix, nameSuffix, tags, traffics, docker, bucketEnv := testAwsProjectModule.SetupMicroservice(t, MicroserviceInformation, Traffics)
-	vars := SetupVars(t)
+	t.Parallel()
+	testAwsModule.RunBranches(t, Branches(t), testMicroserviceScraperBackendEcsEC2)
+}
+
+func testMicroserviceScraperBackendEcsEC2(t *testing.T, branch string) {
+	cfg := config.Get(t, config.Aws, config.Vpc, config.Domain)
+	namePrefix, nameSuffix, tags, traffics, docker, bucketEnv := testAwsProjectModule.SetupMicroservice(t, MicroserviceInformation(branch), Traffics)
+	vars := SetupVars(t, branch)
 	serviceNameSuffix := "unique"
 
+	// the module and the validators share the name built within the limits of the resources
+	name := naming.Microservice.Build(namePrefix, projectName, serviceName, nameSuffix)
+	microservicePath := util.CopyModule(t, MicroservicePath)
 	options := util.Ptr(terraform.Options{
-		TerraformDir: MicroservicePath,
+		TerraformDir: microservicePath,
 		Vars: map[string]interface{}{
+			"name":        name,
 			"name_prefix": namePrefix,
 			"name_suffix": nameSuffix,
 
 			"vpc": map[string]any{
-				"id":   util.GetEnvVariable("VPC_ID"),
+				"id":   cfg.VpcId,
 				"tier": "public",
 			},
 
@@ -64,6 +74,10 @@ func Test_Unit_Microservice_ScraperBackend_ECS_EC2(t *testing.T) {
 					"traffics": traffics,
 					"ecs":      map[string]any{},
 				},
+				"route53": map[string]any{
+					"zones":  []map[string]any{{"name": cfg.Domain()}},
+					"record": map[string]any{"subdomain_name": name},
+				},
 				"iam": map[string]any{
 					"scope":        "accounts",
 					"requires_mfa": false,
@@ -76,26 +90,25 @@ func Test_Unit_Microservice_ScraperBackend_ECS_EC2(t *testing.T) {
 	})
 	maps.Copy(options.Vars, vars)
 
-	defer func() {
-		if r := recover(); r != nil {
-			// destroy all resources if panic
-			terraform.Destroy(t, options)
-		}
-		terratestStructure.RunTestStage(t, "cleanup", func() {
-			terraform.Destroy(t, options)
-		})
-	}()
+	lifecycle := util.NewLifecycle(t, options)
+	defer lifecycle.Cleanup()
 
-	terratestStructure.RunTestStage(t, "deploy", func() {
+	util.RunTestStage(t, "deploy", func() {
 		terraform.Init(t, options)
 		terraform.Plan(t, options)
 		terraform.Apply(t, options)
 	})
-	terratestStructure.RunTestStage(t, "validate", func() {
+	util.RunTestStage(t, "validate", func() {
+		client := testAwsModule.NewClient(testAwsModule.OptionsFromConfig(cfg))
 		// TODO: test that /etc/ecs/ecs.config is not empty, requires key_name coming from terratest maybe
-		name := util.Format("-", namePrefix, projectName, serviceName, nameSuffix)
-		serviceName := util.Format("-", name, serviceNameSuffix)
-		testAwsModule.ValidateMicroservice(t, name, Deployment, serviceName)
-		testAwsModule.ValidateRestEndpoints(t, MicroservicePath, Deployment, Traffics, name, "microservice")
+		serviceName := naming.Service(name, serviceNameSuffix)
+		defer client.DiagnosticsOnFailure(t, testAwsModule.DiagnosticsTest{ClusterName: name, ServiceName: serviceName, MicroservicePath: microservicePath, ModulePath: "microservice"})
+		client.ValidateMicroservice(t, name, Deployment, serviceName)
+		client.ValidateAutoScaling(t, name, serviceName, testAwsModule.AutoScalingFromVars(t, options.Vars))
+		client.ValidateEnvBucket(t, name, serviceName, testAwsModule.EnvBucketFromState(t, microservicePath, "microservice", bucketEnv))
+		client.ValidateDynamodbTables(t, testAwsModule.DynamodbTablesFromState(t, microservicePath, vars["dynamodb_tables"].([]map[string]any)))
+		client.ValidateRoute53(t, testAwsModule.Route53FromVars(t, options.Vars), testAwsModule.LoadBalancerAliasFromState(t, microservicePath, "microservice"))
+		client.ValidateRestEndpoints(t, microservicePath, Deployment, Traffics, name, "microservice")
+		client.ValidateLoadBalancer(t, testAwsModule.LoadBalancerArnFromState(t, microservicePath, "microservice"), Traffics, healthCheckPath)
 	})
 }
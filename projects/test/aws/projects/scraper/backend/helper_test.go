@@ -1,14 +1,14 @@
 package microservice_scraper_backend_test
 
 import (
-	"fmt"
 	"path/filepath"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/vistimi/infrastructure-modules/test/util"
 
-	terratestShell "github.com/gruntwork-io/terratest/modules/shell"
+	testAwsProjectModule "github.com/vistimi/infrastructure-modules/projects/test/aws/module"
+	scraperConfig "github.com/vistimi/infrastructure-modules/projects/test/aws/projects/scraper/config"
 	testAwsModule "github.com/vistimi/infrastructure-modules/test/aws/module"
 )
 
@@ -18,12 +18,22 @@ const (
 
 	Rootpath         = "../../../../.."
 	MicroservicePath = Rootpath + "/modules/aws/projects/scraper/backend"
+
+	organizationName = "vistimi"
+	repositoryName   = "scraper-backend"
+	defaultBranch    = "trunk"
+	healthCheckPath  = "/healthz"
 )
 
-var (
-	MicroserviceInformation = testAwsModule.MicroserviceInformation{
-		Branch:          "trunk", // TODO: make it flexible for testing other branches
-		HealthCheckPath: "/healthz",
+func MicroserviceInformation(branch string) testAwsModule.MicroserviceInformation {
+	return testAwsModule.MicroserviceInformation{
+		Branch:          branch,
+		HealthCheckPath: healthCheckPath,
+		// prepared by the Makefile of the repository
+		EnvFiles: []string{MicroservicePath + "/override.env"},
+		Env: map[string]string{
+			"COMMON_NAME": "name-not-needed-for-test",
+		},
 		Docker: testAwsModule.Docker{
 			Registry: &testAwsModule.Registry{
 				Ecr: &testAwsModule.Ecr{
@@ -31,14 +41,21 @@ var (
 				},
 			},
 			Repository: testAwsModule.Repository{
-				Name: "scraper-backend-trunk", // TODO: make it flexible for testing other branches
+				Name: testAwsModule.EcrRepositoryName(repositoryName, branch, ""),
 			},
 			Image: &testAwsModule.Image{
 				Tag: "latest",
 			},
 		},
 	}
+}
 
+// Branches are the branches of the repository to test, see testAwsModule.BranchNamesEnv
+func Branches(t *testing.T) []string {
+	return testAwsProjectModule.Branches(t, organizationName, repositoryName, defaultBranch)
+}
+
+var (
 	Traffics = []testAwsModule.Traffic{
 		{
 			Listener: testAwsModule.TrafficPoint{
@@ -65,7 +82,7 @@ var (
 		MaxRetries: aws.Int(10),
 		Endpoints: []testAwsModule.EndpointTest{
 			{
-				Path:           MicroserviceInformation.HealthCheckPath,
+				Path:           healthCheckPath,
 				ExpectedStatus: 200,
 				ExpectedBody:   util.Ptr(`"ok"`),
 				MaxRetries:     aws.Int(3),
@@ -80,50 +97,26 @@ var (
 	}
 )
 
-func SetupVars(t *testing.T) (vars map[string]any) {
-
-	// override.env
-	bashCode := fmt.Sprintf("echo COMMON_NAME=%s >> %s/override.env", "name-not-needed-for-test", MicroservicePath)
-	command := terratestShell.Command{
-		Command: "bash",
-		Args:    []string{"-c", bashCode},
-	}
-	terratestShell.RunCommandAndGetOutput(t, command)
-
+func SetupVars(t *testing.T, branch string) (vars map[string]any) {
 	// yml
-	path, err := filepath.Abs("config_override.yml")
-	if err != nil {
-		t.Error(err)
-	}
-	configYml, err := ReadConfigFile(path)
+	configFolder := testAwsProjectModule.LoadOverrideFiles(t, organizationName, repositoryName, branch, "config")
+	configYml, err := scraperConfig.ReadConfigFile(filepath.Join(configFolder, "config_override.yml"))
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
 	// yml variables
-	var dynamodb_tables []map[string]any
-	for _, db := range configYml.Databases {
-		dynamodb_tables = append(dynamodb_tables, map[string]any{
-			"name":                 *db.Name,
-			"primary_key_name":     *db.PrimaryKeyName,
-			"primary_key_type":     *db.PrimaryKeyType,
-			"sort_key_name":        *db.SortKeyName,
-			"sort_key_type":        *db.SortKeyType,
-			"predictable_workload": false,
-		})
+	dynamodbTables, err := configYml.DynamodbTables(false)
+	if err != nil {
+		t.Fatal(err)
 	}
-	bucket_picture_name_extension, ok := configYml.Buckets["picture"]
-	if !ok {
-		t.Errorf("config.yml file missing buckets.picture")
+	bucketPicture, err := configYml.BucketPicture(true, false)
+	if err != nil {
+		t.Fatal(err)
 	}
-	bucket_picture_name := *bucket_picture_name_extension.Name
 	vars = map[string]any{
-		"dynamodb_tables": dynamodb_tables,
-		"bucket_picture": map[string]any{
-			"name":          bucket_picture_name,
-			"force_destroy": true,
-			"versioning":    false,
-		},
+		"dynamodb_tables": dynamodbTables,
+		"bucket_picture":  bucketPicture,
 	}
 	return vars
 }
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// bucket of the pictures in the `buckets` section
+	PictureBucket = "picture"
+)
+
+var (
+	// https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/HowItWorks.NamingRulesDataTypes.html
+	KeyTypes = []string{"S", "N", "B"}
+)
+
+// Config is the part of the scraper configuration used by the infrastructure
+type Config struct {
+	Databases []Database        `yaml:"databases"`
+	Buckets   map[string]Bucket `yaml:"buckets"`
+}
+
+type Database struct {
+	Name           *string `yaml:"name"`
+	PrimaryKeyName *string `yaml:"primaryKeyName"`
+	PrimaryKeyType *string `yaml:"primaryKeyType"`
+	SortKeyName    *string `yaml:"sortKeyName"`
+	SortKeyType    *string `yaml:"sortKeyType"`
+}
+
+type Bucket struct {
+	Name *string `yaml:"name"`
+}
+
+// ValidationError lists all the problems of a configuration
+type ValidationError struct {
+	File     string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	prefix := "invalid config"
+	if e.File != "" {
+		prefix = fmt.Sprintf("invalid config %s", e.File)
+	}
+	return fmt.Sprintf("%s: %s", prefix, strings.Join(e.Problems, "; "))
+}
+
+// ReadConfigFile reads and validates the scraper configuration, e.g. config_override.yml
+func ReadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		if validationError, ok := err.(*ValidationError); ok {
+			validationError.File = path
+			return nil, validationError
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+func ParseConfig(data []byte) (*Config, error) {
+	config := &Config{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the required fields and the key types
+func (c Config) Validate() error {
+	problems := []string{}
+	names := map[string]bool{}
+	for i, database := range c.Databases {
+		field := func(name string) string { return fmt.Sprintf("databases[%d].%s", i, name) }
+		if empty(database.Name) {
+			problems = append(problems, fmt.Sprintf("%s is required", field("name")))
+		} else if names[*database.Name] {
+			problems = append(problems, fmt.Sprintf("%s %q is duplicated", field("name"), *database.Name))
+		} else {
+			names[*database.Name] = true
+		}
+
+		for _, key := range []struct {
+			name, typ            string
+			nameValue, typeValue *string
+		}{
+			{"primaryKeyName", "primaryKeyType", database.PrimaryKeyName, database.PrimaryKeyType},
+			{"sortKeyName", "sortKeyType", database.SortKeyName, database.SortKeyType},
+		} {
+			if empty(key.nameValue) {
+				problems = append(problems, fmt.Sprintf("%s is required", field(key.name)))
+			}
+			if empty(key.typeValue) {
+				problems = append(problems, fmt.Sprintf("%s is required", field(key.typ)))
+			} else if !contains(KeyTypes, *key.typeValue) {
+				problems = append(problems, fmt.Sprintf("%s %q must be one of %s", field(key.typ), *key.typeValue, strings.Join(KeyTypes, ", ")))
+			}
+		}
+		if !empty(database.PrimaryKeyName) && !empty(database.SortKeyName) && *database.PrimaryKeyName == *database.SortKeyName {
+			problems = append(problems, fmt.Sprintf("%s must differ from %s", field("sortKeyName"), field("primaryKeyName")))
+		}
+	}
+
+	if bucket, ok := c.Buckets[PictureBucket]; !ok {
+		problems = append(problems, fmt.Sprintf("buckets.%s is required", PictureBucket))
+	} else if empty(bucket.Name) {
+		problems = append(problems, fmt.Sprintf("buckets.%s.name is required", PictureBucket))
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// DynamodbTables maps the databases to the `dynamodb_tables` variable of the scraper backend module
+func (c Config) DynamodbTables(predictableWorkload bool) ([]map[string]any, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	tables := []map[string]any{}
+	for _, database := range c.Databases {
+		tables = append(tables, map[string]any{
+			"name":                 *database.Name,
+			"primary_key_name":     *database.PrimaryKeyName,
+			"primary_key_type":     *database.PrimaryKeyType,
+			"sort_key_name":        *database.SortKeyName,
+			"sort_key_type":        *database.SortKeyType,
+			"predictable_workload": predictableWorkload,
+		})
+	}
+	return tables, nil
+}
+
+// BucketPicture maps buckets.picture to the `bucket_picture` variable of the scraper backend module
+func (c Config) BucketPicture(forceDestroy, versioning bool) (map[string]any, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"name":          *c.Buckets[PictureBucket].Name,
+		"force_destroy": forceDestroy,
+		"versioning":    versioning,
+	}, nil
+}
+
+func empty(value *string) bool {
+	return value == nil || strings.TrimSpace(*value) == ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
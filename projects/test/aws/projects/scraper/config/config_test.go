@@ -0,0 +1,118 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_Unit_ReadConfigFile(t *testing.T) {
+	config, err := ReadConfigFile(filepath.Join("testdata", "config.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := config.DynamodbTables(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedTables := []map[string]any{
+		{"name": "picture-process", "primary_key_name": "Origin", "primary_key_type": "S", "sort_key_name": "ID", "sort_key_type": "S", "predictable_workload": false},
+		{"name": "picture-validation", "primary_key_name": "Origin", "primary_key_type": "S", "sort_key_name": "CreationDate", "sort_key_type": "N", "predictable_workload": false},
+	}
+	if !reflect.DeepEqual(expectedTables, tables) {
+		t.Errorf("expected %v, got %v", expectedTables, tables)
+	}
+
+	bucket, err := config.BucketPicture(true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedBucket := map[string]any{"name": "picture", "force_destroy": true, "versioning": false}
+	if !reflect.DeepEqual(expectedBucket, bucket) {
+		t.Errorf("expected %v, got %v", expectedBucket, bucket)
+	}
+}
+
+func Test_Unit_ReadConfigFile_Missing(t *testing.T) {
+	if _, err := ReadConfigFile(filepath.Join(t.TempDir(), "config_override.yml")); err == nil {
+		t.Fatal("expected error for a missing file")
+	}
+}
+
+func Test_Unit_ParseConfig_Invalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		yml      string
+		problems []string
+	}{
+		{
+			name: "missing fields",
+			yml: `
+databases:
+  - name: picture-process
+    primaryKeyType: S
+buckets:
+  picture: {}
+`,
+			problems: []string{
+				"databases[0].primaryKeyName is required",
+				"databases[0].sortKeyName is required",
+				"databases[0].sortKeyType is required",
+				"buckets.picture.name is required",
+			},
+		},
+		{
+			name: "key types",
+			yml: `
+databases:
+  - name: picture-process
+    primaryKeyName: Origin
+    primaryKeyType: string
+    sortKeyName: Origin
+    sortKeyType: BOOL
+buckets:
+  picture:
+    name: picture
+`,
+			problems: []string{
+				`databases[0].primaryKeyType "string" must be one of S, N, B`,
+				`databases[0].sortKeyType "BOOL" must be one of S, N, B`,
+				"databases[0].sortKeyName must differ from databases[0].primaryKeyName",
+			},
+		},
+		{
+			name: "duplicated and missing bucket",
+			yml: `
+databases:
+  - {name: a, primaryKeyName: A, primaryKeyType: S, sortKeyName: B, sortKeyType: B}
+  - {name: a, primaryKeyName: A, primaryKeyType: N, sortKeyName: B, sortKeyType: S}
+`,
+			problems: []string{
+				`databases[1].name "a" is duplicated`,
+				"buckets.picture is required",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(testCase.yml))
+			var validationError *ValidationError
+			if !errors.As(err, &validationError) {
+				t.Fatalf("expected validation error, got %v", err)
+			}
+			if !reflect.DeepEqual(testCase.problems, validationError.Problems) {
+				t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(testCase.problems, "\n"), strings.Join(validationError.Problems, "\n"))
+			}
+		})
+	}
+}
+
+func Test_Unit_ParseConfig_Syntax(t *testing.T) {
+	if _, err := ParseConfig([]byte("databases: [")); err == nil {
+		t.Fatal("expected syntax error")
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 
+	testAwsProjectModule "github.com/vistimi/infrastructure-modules/projects/test/aws/module"
 	testAwsModule "github.com/vistimi/infrastructure-modules/test/aws/module"
 	"github.com/vistimi/infrastructure-modules/test/util"
 )
@@ -15,12 +16,19 @@ const (
 
 	Rootpath         = "../../../../.."
 	MicroservicePath = Rootpath + "/modules/aws/projects/scraper/frontend"
+
+	organizationName = "vistimi"
+	repositoryName   = "scraper-frontend"
+	defaultBranch    = "trunk"
+	healthCheckPath  = "/healthz"
 )
 
-var (
-	MicroserviceInformation = testAwsModule.MicroserviceInformation{
-		Branch:          "trunk", // TODO: make it flexible for testing other branches
-		HealthCheckPath: "/healthz",
+func MicroserviceInformation(branch string) testAwsModule.MicroserviceInformation {
+	return testAwsModule.MicroserviceInformation{
+		Branch:          branch,
+		HealthCheckPath: healthCheckPath,
+		// prepared by the Makefile of the repository
+		EnvFiles: []string{MicroservicePath + "/override.env"},
 		Docker: testAwsModule.Docker{
 			Registry: &testAwsModule.Registry{
 				Ecr: &testAwsModule.Ecr{
@@ -28,14 +36,21 @@ var (
 				},
 			},
 			Repository: testAwsModule.Repository{
-				Name: "scraper-frontend-trunk", // TODO: make it flexible for testing other branches
+				Name: testAwsModule.EcrRepositoryName(repositoryName, branch, ""),
 			},
 			Image: &testAwsModule.Image{
 				Tag: "latest",
 			},
 		},
 	}
+}
 
+// Branches are the branches of the repository to test, see testAwsModule.BranchNamesEnv
+func Branches(t *testing.T) []string {
+	return testAwsProjectModule.Branches(t, organizationName, repositoryName, defaultBranch)
+}
+
+var (
 	Traffics = []testAwsModule.Traffic{
 		{
 			Listener: testAwsModule.TrafficPoint{
@@ -62,7 +77,7 @@ var (
 		MaxRetries: aws.Int(10),
 		Endpoints: []testAwsModule.EndpointTest{
 			{
-				Path:           MicroserviceInformation.HealthCheckPath,
+				Path:           healthCheckPath,
 				ExpectedStatus: 200,
 				// ExpectedBody:   util.Ptr(`"ok"`),
 				MaxRetries: aws.Int(3),
@@ -1,16 +1,13 @@
 package microservice_scraper_backend_test
 
 import (
-	"fmt"
-	"math/rand"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
 	testAwsModule "github.com/vistimi/infrastructure-modules/test/aws/module"
+	"github.com/vistimi/infrastructure-modules/test/config"
 	"github.com/vistimi/infrastructure-modules/test/util"
 )
 
@@ -23,11 +20,6 @@ const (
 )
 
 var (
-	AccountName   = util.GetEnvVariable("AWS_PROFILE_NAME")
-	AccountId     = util.GetEnvVariable("AWS_ACCOUNT_ID")
-	AccountRegion = util.GetEnvVariable("AWS_REGION_NAME")
-	DomainName    = fmt.Sprintf("%s.%s", util.GetEnvVariable("DOMAIN_NAME"), util.GetEnvVariable("DOMAIN_SUFFIX"))
-
 	Traffic = []testAwsModule.Traffic{
 		{
 			Listener: testAwsModule.TrafficPoint{
@@ -54,24 +46,24 @@ var (
 )
 
 func Test_Unit_External_Scraper_LabelStudio(t *testing.T) {
-	// t.Parallel()
-	rand.Seed(time.Now().UnixNano())
-
+	t.Parallel()
+	cfg := config.Get(t, config.Aws)
 	// global variables
 	namePrefix := "vi"
-	id := util.RandomID(4)
-	nameSuffix := strings.ToLower(util.Format("-", util.GetEnvVariable("AWS_PROFILE_NAME"), id))
+	id := util.TestID(t, 4, testAwsModule.NewClient(testAwsModule.OptionsFromConfig(cfg)).TaggedIDInUse())
+	nameSuffix := strings.ToLower(util.Format("-", cfg.AccountName, id))
 	tags := map[string]string{
 		"TestID":  id,
-		"Account": AccountName,
-		"Region":  AccountRegion,
+		"Account": cfg.AccountName,
+		"Region":  cfg.AccountRegion,
 		"Project": projectName,
 		"Service": serviceName,
 	}
 
 	// instance := testAwsModule.T3Small
+	microservicePath := util.CopyModule(t, MicroservicePath)
 	options := &terraform.Options{
-		TerraformDir: MicroservicePath,
+		TerraformDir: microservicePath,
 		Vars: map[string]any{
 			"name_prefix": namePrefix,
 			"name_suffix": nameSuffix,
@@ -96,7 +88,7 @@ func Test_Unit_External_Scraper_LabelStudio(t *testing.T) {
 			// "create_acm_certificate": true,
 			// "route53": map[string]any{
 			// 	"zone": map[string]any{
-			// 		"name": DomainName,
+			// 		"name": cfg.Domain(),
 			// 	},
 			// 	"record": map[string]any{
 			// 		"subdomain_name": id,
@@ -114,19 +106,12 @@ func Test_Unit_External_Scraper_LabelStudio(t *testing.T) {
 		},
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			// destroy all resources if panic
-			terraform.Destroy(t, options)
-		}
-		terratestStructure.RunTestStage(t, "cleanup", func() {
-			terraform.Destroy(t, options)
-		})
-	}()
+	lifecycle := util.NewLifecycle(t, options)
+	defer lifecycle.Cleanup()
 
-	terratestStructure.RunTestStage(t, "deploy", func() {
+	util.RunTestStage(t, "deploy", func() {
 		terraform.InitAndApply(t, options)
 	})
-	terratestStructure.RunTestStage(t, "validate", func() {
+	util.RunTestStage(t, "validate", func() {
 	})
 }
@@ -1,13 +1,11 @@
 package iam_team_test
 
 import (
-	"math/rand"
 	"testing"
-	"time"
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
 	testAwsModule "github.com/vistimi/infrastructure-modules/test/aws/module"
+	"github.com/vistimi/infrastructure-modules/test/config"
 	"github.com/vistimi/infrastructure-modules/test/util"
 )
 
@@ -16,10 +14,9 @@ const (
 )
 
 func Test_Unit_IAM_Group(t *testing.T) {
-	// t.Parallel()
-	rand.Seed(time.Now().UnixNano())
-
-	teamName := "team" + util.RandomID(4)
+	t.Parallel()
+	cfg := config.Get(t, config.Aws)
+	teamName := "team" + util.TestID(t, 4)
 	group := testAwsModule.GroupInfo{
 		Name: "dev",
 		Users: []map[string]any{{
@@ -45,8 +42,9 @@ func Test_Unit_IAM_Group(t *testing.T) {
 		},
 	}
 
+	groupPath := util.CopyModule(t, pathGroup)
 	options := &terraform.Options{
-		TerraformDir: pathGroup,
+		TerraformDir: groupPath,
 		Vars: map[string]any{
 			"name": group.Name,
 
@@ -65,20 +63,14 @@ func Test_Unit_IAM_Group(t *testing.T) {
 		},
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			// destroy all resources if panic
-			terraform.Destroy(t, options)
-		}
-		terratestStructure.RunTestStage(t, "cleanup", func() {
-			terraform.Destroy(t, options)
-		})
-	}()
+	lifecycle := util.NewLifecycle(t, options)
+	defer lifecycle.Cleanup()
 
-	terratestStructure.RunTestStage(t, "deploy", func() {
+	util.RunTestStage(t, "deploy", func() {
 		terraform.InitAndApply(t, options)
 	})
-	terratestStructure.RunTestStage(t, "validate", func() {
-		testAwsModule.ValidateGroup(t, util.GetEnvVariable("AWS_REGION_NAME"), teamName, group)
+	util.RunTestStage(t, "validate", func() {
+		client := testAwsModule.NewClient(testAwsModule.OptionsFromConfig(cfg))
+		client.ValidateGroup(t, teamName, group)
 	})
 }
@@ -1,13 +1,11 @@
 package iam_team_test
 
 import (
-	"math/rand"
 	"testing"
-	"time"
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
 	testAwsModule "github.com/vistimi/infrastructure-modules/test/aws/module"
+	"github.com/vistimi/infrastructure-modules/test/config"
 	"github.com/vistimi/infrastructure-modules/test/util"
 )
 
@@ -16,10 +14,9 @@ const (
 )
 
 func Test_Unit_IAM_Level(t *testing.T) {
-	// t.Parallel()
-	rand.Seed(time.Now().UnixNano())
-
-	id := util.RandomID(4)
+	t.Parallel()
+	cfg := config.Get(t, config.Aws)
+	id := util.TestID(t, 4)
 
 	orgName := "org" + id
 	teamName := "team" + id
@@ -80,8 +77,9 @@ func Test_Unit_IAM_Level(t *testing.T) {
 
 	externalAssumeRoleArns := []string{}
 
+	levelPath := util.CopyModule(t, pathLevel)
 	options := &terraform.Options{
-		TerraformDir: pathLevel,
+		TerraformDir: levelPath,
 		Vars: map[string]any{
 			"levels": []map[string]any{
 				{
@@ -104,21 +102,15 @@ func Test_Unit_IAM_Level(t *testing.T) {
 		},
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			// destroy all resources if panic
-			terraform.Destroy(t, options)
-		}
-		terratestStructure.RunTestStage(t, "cleanup", func() {
-			terraform.Destroy(t, options)
-		})
-	}()
+	lifecycle := util.NewLifecycle(t, options)
+	defer lifecycle.Cleanup()
 
-	terratestStructure.RunTestStage(t, "deploy", func() {
+	util.RunTestStage(t, "deploy", func() {
 		terraform.InitAndApply(t, options)
 	})
-	terratestStructure.RunTestStage(t, "validate", func() {
+	util.RunTestStage(t, "validate", func() {
+		client := testAwsModule.NewClient(testAwsModule.OptionsFromConfig(cfg))
 		prefixName := util.Format("-", orgName, teamName)
-		testAwsModule.ValidateLevel(t, util.GetEnvVariable("AWS_REGION_NAME"), prefixName, groups...)
+		client.ValidateLevel(t, prefixName, groups...)
 	})
 }
@@ -1,16 +1,16 @@
 package microservice_test
 
 import (
-	"fmt"
 	"testing"
 
 	"golang.org/x/exp/maps"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
 	testAwsProjectModule "github.com/vistimi/infrastructure-modules/projects/test/aws/module"
 	testAwsModule "github.com/vistimi/infrastructure-modules/test/aws/module"
+	"github.com/vistimi/infrastructure-modules/test/aws/naming"
+	"github.com/vistimi/infrastructure-modules/test/config"
 	"github.com/vistimi/infrastructure-modules/test/util"
 )
 
@@ -20,17 +20,15 @@ const (
 
 	Rootpath         = "../../../.."
 	MicroservicePath = Rootpath + "/modules/aws/container/microservice"
+
+	defaultBranch   = "trunk"
+	healthCheckPath = "/"
 )
 
-var (
-	AccountName   = util.GetEnvVariable("AWS_PROFILE_NAME")
-	AccountId     = util.GetEnvVariable("AWS_ACCOUNT_ID")
-	AccountRegion = util.GetEnvVariable("AWS_REGION_NAME")
-	DomainName    = fmt.Sprintf("%s.%s", util.GetEnvVariable("DOMAIN_NAME"), util.GetEnvVariable("DOMAIN_SUFFIX"))
-
-	MicroserviceInformation = testAwsModule.MicroserviceInformation{
-		Branch:          "trunk", // TODO: make it flexible for testing other branches
-		HealthCheckPath: "/",
+func MicroserviceInformation(branch string) testAwsModule.MicroserviceInformation {
+	return testAwsModule.MicroserviceInformation{
+		Branch:          branch,
+		HealthCheckPath: healthCheckPath,
 		Docker: testAwsModule.Docker{
 			Registry: &testAwsModule.Registry{
 				Ecr: &testAwsModule.Ecr{
@@ -48,7 +46,14 @@ var (
 			},
 		},
 	}
+}
+
+// Branches only change the env file, the image is not built from a repository
+func Branches(t *testing.T) []string {
+	return testAwsModule.Branches(t, nil, "", "", defaultBranch)
+}
 
+var (
 	Traffics = []testAwsModule.Traffic{
 		{
 			Listener: testAwsModule.TrafficPoint{
@@ -84,19 +89,26 @@ func SetupVars(t *testing.T) (vars map[string]any) {
 // https://docs.aws.amazon.com/elastic-inference/latest/developerguide/ei-dlc-ecs-pytorch.html
 // https://docs.aws.amazon.com/deep-learning-containers/latest/devguide/deep-learning-containers-ecs-tutorials-training.html
 func Test_Unit_Microservice_GPU_ECS_EC2_Mnist(t *testing.T) {
-	// t.Parallel()
-	namePrefix, nameSuffix, tags, traffics, docker, bucketEnv := testAwsProjectModule.SetupMicroservice(t, MicroserviceInformation, Traffics)
+	t.Parallel()
+	testAwsModule.RunBranches(t, Branches(t), testMicroserviceGPUECSEC2Mnist)
+}
+
+func testMicroserviceGPUECSEC2Mnist(t *testing.T, branch string) {
+	cfg := config.Get(t, config.Aws, config.Vpc)
+	namePrefix, nameSuffix, tags, traffics, docker, bucketEnv := testAwsProjectModule.SetupMicroservice(t, MicroserviceInformation(branch), Traffics)
 	vars := SetupVars(t)
 	serviceNameSuffix := "unique"
 
+	// the module and the validators share the name built within the limits of the resources
+	name := naming.Microservice.Build(namePrefix, projectName, serviceName, nameSuffix)
+	microservicePath := util.CopyModule(t, MicroservicePath)
 	options := util.Ptr(terraform.Options{
-		TerraformDir: MicroservicePath,
+		TerraformDir: microservicePath,
 		Vars: map[string]interface{}{
-			"name_prefix": namePrefix,
-			"name_suffix": nameSuffix,
+			"name": name,
 
 			"vpc": map[string]any{
-				"id":   util.GetEnvVariable("VPC_ID"),
+				"id":   cfg.VpcId,
 				"tier": "public",
 			},
 
@@ -153,26 +165,25 @@ func Test_Unit_Microservice_GPU_ECS_EC2_Mnist(t *testing.T) {
 	})
 	maps.Copy(options.Vars, vars)
 
-	defer func() {
-		if r := recover(); r != nil {
-			// destroy all resources if panic
-			terraform.Destroy(t, options)
-		}
-		terratestStructure.RunTestStage(t, "cleanup", func() {
-			terraform.Destroy(t, options)
-		})
-	}()
-
-	terratestStructure.RunTestStage(t, "deploy", func() {
+	lifecycle := util.NewLifecycle(t, options)
+	defer lifecycle.Cleanup()
+
+	util.RunTestStage(t, "deploy", func() {
 		terraform.Init(t, options)
 		terraform.Plan(t, options)
 		terraform.Apply(t, options)
 	})
-	terratestStructure.RunTestStage(t, "validate", func() {
+	util.RunTestStage(t, "validate", func() {
+		client := testAwsModule.NewClient(testAwsModule.OptionsFromConfig(cfg))
 		// TODO: test that /etc/ecs/ecs.config is not empty, requires key_name coming from terratest maybe
-		name := util.Format("-", namePrefix, projectName, serviceName, nameSuffix)
-		serviceName := util.Format("-", name, serviceNameSuffix)
-		testAwsModule.ValidateMicroservice(t, name, Deployment, serviceName)
-		testAwsModule.ValidateRestEndpoints(t, MicroservicePath, Deployment, Traffics, name, "")
+		serviceName := naming.Service(name, serviceNameSuffix)
+		defer client.DiagnosticsOnFailure(t, testAwsModule.DiagnosticsTest{ClusterName: name, ServiceName: serviceName, MicroservicePath: microservicePath, ModulePath: ""})
+		client.ValidateMicroservice(t, name, Deployment, serviceName)
+		client.ValidateAutoScaling(t, name, serviceName, testAwsModule.AutoScalingFromVars(t, options.Vars))
+		client.ValidateEnvBucket(t, name, serviceName, testAwsModule.EnvBucketFromState(t, microservicePath, "", bucketEnv))
+		// no route53 records, the endpoints are only reached through the load balancer
+		client.ValidateRestEndpoints(t, microservicePath, Deployment, Traffics, name, "")
+		client.ValidateLoadBalancer(t, testAwsModule.LoadBalancerArnFromState(t, microservicePath, ""), Traffics, healthCheckPath)
+		client.ValidateLogs(t, name, serviceName, testAwsModule.LogPatternsTest{}, Deployment)
 	})
 }
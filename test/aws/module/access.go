@@ -0,0 +1,142 @@
+package module
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+// Access tells how to reach the resources of a test kept after a failure
+type Access struct {
+	ClusterName string
+	ServiceName string
+	Urls        []string
+	// ExecCommand opens a shell in a container of a running task, SessionCommand opens one on its instance when the task runs on EC2
+	ExecCommand    string
+	SessionCommand string
+	DestroyCommand string
+}
+
+// AccessE builds the urls of the hosts with the protocols of the listeners and the commands to open a shell in the first running task of the service,
+// the commands are empty when no task is running
+func AccessE(client ecsiface.ECSAPI, region, clusterName, serviceName string, listeners []*elbv2.Listener, hosts []string) (*Access, error) {
+	access := &Access{ClusterName: clusterName, ServiceName: serviceName, Urls: accessUrls(listeners, hosts)}
+
+	tasks, err := client.ListTasks(&ecs.ListTasksInput{Cluster: awsSDK.String(clusterName), ServiceName: awsSDK.String(serviceName), DesiredStatus: awsSDK.String(ecs.DesiredStatusRunning)})
+	if err != nil {
+		return access, fmt.Errorf("list running tasks of service %s: %w", serviceName, err)
+	}
+	if len(tasks.TaskArns) == 0 {
+		return access, nil
+	}
+	described, err := client.DescribeTasks(&ecs.DescribeTasksInput{Cluster: awsSDK.String(clusterName), Tasks: tasks.TaskArns[:1]})
+	if err != nil {
+		return access, fmt.Errorf("describe task %s: %w", awsSDK.StringValue(tasks.TaskArns[0]), err)
+	}
+	if len(described.Tasks) == 0 {
+		return access, nil
+	}
+	task := described.Tasks[0]
+
+	if container := runningContainer(task); container != "" {
+		access.ExecCommand = fmt.Sprintf(`aws ecs execute-command --region %s --cluster %s --task %s --container %s --interactive --command "/bin/sh"`, region, clusterName, awsSDK.StringValue(task.TaskArn), container)
+	}
+	if task.ContainerInstanceArn == nil {
+		return access, nil
+	}
+	instances, err := client.DescribeContainerInstances(&ecs.DescribeContainerInstancesInput{Cluster: awsSDK.String(clusterName), ContainerInstances: []*string{task.ContainerInstanceArn}})
+	if err != nil {
+		return access, fmt.Errorf("describe container instance %s: %w", awsSDK.StringValue(task.ContainerInstanceArn), err)
+	}
+	if len(instances.ContainerInstances) > 0 && instances.ContainerInstances[0].Ec2InstanceId != nil {
+		access.SessionCommand = fmt.Sprintf("aws ssm start-session --region %s --target %s", region, awsSDK.StringValue(instances.ContainerInstances[0].Ec2InstanceId))
+	}
+	return access, nil
+}
+
+// runningContainer is the first running container of the task, the first container otherwise
+func runningContainer(task *ecs.Task) string {
+	for _, container := range task.Containers {
+		if awsSDK.StringValue(container.LastStatus) == ecs.DesiredStatusRunning {
+			return awsSDK.StringValue(container.Name)
+		}
+	}
+	if len(task.Containers) > 0 {
+		return awsSDK.StringValue(task.Containers[0].Name)
+	}
+	return ""
+}
+
+// accessUrls gives each host the http and https listeners, the default ports are omitted and plain http is assumed without listeners
+func accessUrls(listeners []*elbv2.Listener, hosts []string) []string {
+	schemes := map[string]int64{}
+	for _, listener := range listeners {
+		switch protocol := awsSDK.StringValue(listener.Protocol); protocol {
+		case elbv2.ProtocolEnumHttp, elbv2.ProtocolEnumHttps:
+			schemes[strings.ToLower(protocol)+":"+fmt.Sprint(awsSDK.Int64Value(listener.Port))] = awsSDK.Int64Value(listener.Port)
+		}
+	}
+	if len(schemes) == 0 {
+		schemes["http:80"] = 80
+	}
+
+	urls := []string{}
+	for _, host := range hosts {
+		for key, port := range schemes {
+			scheme := strings.Split(key, ":")[0]
+			if (scheme == "http" && port == 80) || (scheme == "https" && port == 443) {
+				urls = append(urls, fmt.Sprintf("%s://%s", scheme, host))
+				continue
+			}
+			urls = append(urls, fmt.Sprintf("%s://%s:%d", scheme, host, port))
+		}
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// accessHostsFromOutputs is the dns name of the load balancer and the names of the A records of each zone, empty when missing
+func accessHostsFromOutputs(outputs map[string]any, modulePath string) []string {
+	hosts := []string{}
+	if dnsName, ok := outputValue(outputs, modulePath, "ecs.elb.lb.dns_name").(string); ok && dnsName != "" {
+		hosts = append(hosts, dnsName)
+	}
+	zones, _ := outputValue(outputs, modulePath, "ecs.route53.records").(map[string]any)
+	records := []string{}
+	for _, zoneRecords := range zones {
+		fields, _ := zoneRecords.(map[string]any)
+		// the names are keyed by `<name> <type>`
+		names, _ := fields["fqdn"].(map[string]any)
+		for key, name := range names {
+			if strings.HasSuffix(key, " A") {
+				records = append(records, normalizeDnsName(fmt.Sprint(name)))
+			}
+		}
+	}
+	sort.Strings(records)
+	return append(hosts, records...)
+}
+
+func (a *Access) String() string {
+	lines := []string{"cluster: " + a.ClusterName, "service: " + a.ServiceName, "urls:"}
+	for _, url := range a.Urls {
+		lines = append(lines, "  "+url)
+	}
+	if a.ExecCommand != "" {
+		lines = append(lines, "shell in the container:", "  "+a.ExecCommand)
+	} else {
+		lines = append(lines, "no running task to open a shell in")
+	}
+	if a.SessionCommand != "" {
+		lines = append(lines, "shell on the instance:", "  "+a.SessionCommand)
+	}
+	if a.DestroyCommand != "" {
+		lines = append(lines, "destroy:", "  "+a.DestroyCommand)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,86 @@
+package module
+
+import (
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+const fakeContainerInstanceArn = "arn:aws:ecs:us-east-1:123456789012:container-instance/vi-rest-test/0123456789abcdef"
+
+var fakeAccessListeners = []*elbv2.Listener{
+	{Protocol: awsSDK.String(elbv2.ProtocolEnumHttp), Port: awsSDK.Int64(80)},
+	{Protocol: awsSDK.String(elbv2.ProtocolEnumHttps), Port: awsSDK.Int64(8443)},
+}
+
+func Test_Unit_Access(t *testing.T) {
+	testCases := []struct {
+		name            string
+		task            *ecs.Task
+		expectedExec    string
+		expectedSession string
+	}{
+		{
+			name:         "no running task",
+			task:         &ecs.Task{TaskArn: awsSDK.String(fakeTaskArn), DesiredStatus: awsSDK.String(ecs.DesiredStatusStopped)},
+			expectedExec: "",
+		},
+		{
+			name: "fargate",
+			task: &ecs.Task{TaskArn: awsSDK.String(fakeTaskArn), DesiredStatus: awsSDK.String(ecs.DesiredStatusRunning), Containers: []*ecs.Container{
+				{Name: awsSDK.String("init"), LastStatus: awsSDK.String(ecs.DesiredStatusStopped)},
+				{Name: awsSDK.String("unique"), LastStatus: awsSDK.String(ecs.DesiredStatusRunning)},
+			}},
+			expectedExec: `aws ecs execute-command --region us-east-1 --cluster vi-rest-test --task ` + fakeTaskArn + ` --container unique --interactive --command "/bin/sh"`,
+		},
+		{
+			name: "ec2",
+			task: &ecs.Task{TaskArn: awsSDK.String(fakeTaskArn), DesiredStatus: awsSDK.String(ecs.DesiredStatusRunning), ContainerInstanceArn: awsSDK.String(fakeContainerInstanceArn), Containers: []*ecs.Container{
+				{Name: awsSDK.String("unique"), LastStatus: awsSDK.String(ecs.DesiredStatusPending)},
+			}},
+			expectedExec:    `aws ecs execute-command --region us-east-1 --cluster vi-rest-test --task ` + fakeTaskArn + ` --container unique --interactive --command "/bin/sh"`,
+			expectedSession: "aws ssm start-session --region us-east-1 --target i-0123456789abcdef0",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, _, _ := newFakeEcsService()
+			client.putTask(fakeClusterName, fakeServiceName, tc.task)
+			client.instances[fakeContainerInstanceArn] = &ecs.ContainerInstance{ContainerInstanceArn: awsSDK.String(fakeContainerInstanceArn), Ec2InstanceId: awsSDK.String("i-0123456789abcdef0")}
+
+			access, err := AccessE(client, "us-east-1", fakeClusterName, fakeServiceName, fakeAccessListeners, []string{fakeLbDnsName})
+			if err != nil {
+				t.Fatal(err)
+			}
+			util.Equal(t, tc.expectedExec, access.ExecCommand)
+			util.Equal(t, tc.expectedSession, access.SessionCommand)
+			util.Equal(t, "http://"+fakeLbDnsName+",https://"+fakeLbDnsName+":8443", strings.Join(access.Urls, ","))
+		})
+	}
+}
+
+func Test_Unit_AccessHostsFromOutputs(t *testing.T) {
+	outputs := map[string]any{"microservice": map[string]any{"ecs": map[string]any{
+		"elb": map[string]any{"lb": map[string]any{"dns_name": fakeLbDnsName}},
+		"route53": map[string]any{"records": map[string]any{
+			"vistimi.com": map[string]any{"fqdn": map[string]any{
+				"api A":    "api.vistimi.com.",
+				"api AAAA": "api.vistimi.com.",
+				"www A":    "www.api.vistimi.com",
+			}},
+		}},
+	}}}
+	util.Equal(t, fakeLbDnsName+",api.vistimi.com,www.api.vistimi.com", strings.Join(accessHostsFromOutputs(outputs, "microservice"), ","))
+	// the outputs of a failed apply may be missing
+	util.Equal(t, 0, len(accessHostsFromOutputs(map[string]any{}, "")))
+}
+
+func Test_Unit_Access_String(t *testing.T) {
+	access := Access{ClusterName: fakeClusterName, ServiceName: fakeServiceName, Urls: []string{"http://" + fakeLbDnsName}, DestroyCommand: "SKIP_deploy=true SKIP_validate=true go test"}
+	util.Equal(t, "cluster: vi-rest-test\nservice: vi-rest-test-unique\nurls:\n  http://"+fakeLbDnsName+"\nno running task to open a shell in\ndestroy:\n  SKIP_deploy=true SKIP_validate=true go test\n", access.String())
+}
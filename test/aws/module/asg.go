@@ -0,0 +1,370 @@
+package module
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+const (
+	CapacityTypeOnDemand = "ON_DEMAND"
+	CapacityTypeSpot     = "SPOT"
+)
+
+// CapacityTest is an element of the `capacities` variable of the ec2 group
+type CapacityTest struct {
+	Type   string
+	Base   int
+	Weight int
+}
+
+// AutoScalingTest is the deployment sizes and the ec2 group of a microservice
+type AutoScalingTest struct {
+	MinSize       int
+	MaxSize       int
+	DesiredSize   int
+	InstanceTypes []string
+	Capacities    []CapacityTest
+}
+
+// CapacityProviderName is the name given by the ecs module to the capacity provider of a capacity type
+func CapacityProviderName(clusterName, capacityType string) string {
+	return clusterName + "-" + capacityType
+}
+
+// AutoScalingFromVars reads the first `group` with an `ec2` object in the terraform variables
+func AutoScalingFromVars(t *testing.T, vars map[string]any) AutoScalingTest {
+	group := findGroup(vars, "ec2")
+	if group == nil {
+		t.Fatalf("no group with ec2 in the variables")
+	}
+	deployment, _ := group["deployment"].(map[string]any)
+	ec2Group, _ := group["ec2"].(map[string]any)
+
+	autoScaling := AutoScalingTest{}
+	autoScaling.MinSize, _ = deployment["min_size"].(int)
+	autoScaling.MaxSize, _ = deployment["max_size"].(int)
+	autoScaling.DesiredSize, _ = deployment["desired_size"].(int)
+	autoScaling.InstanceTypes, _ = ec2Group["instance_types"].([]string)
+	capacities, _ := ec2Group["capacities"].([]map[string]any)
+	autoScaling.Capacities = capacitiesFromVars(capacities)
+	if len(autoScaling.InstanceTypes) == 0 || len(autoScaling.Capacities) == 0 {
+		t.Fatalf("no instance_types or capacities in the ec2 group: %+v", ec2Group)
+	}
+	return autoScaling
+}
+
+func capacitiesFromVars(capacities []map[string]any) []CapacityTest {
+	capacityTests := []CapacityTest{}
+	for _, capacity := range capacities {
+		// defaults of the `capacities` variable
+		capacityTest := CapacityTest{Type: CapacityTypeOnDemand, Weight: 1}
+		if capacityType, ok := capacity["type"].(string); ok {
+			capacityTest.Type = capacityType
+		}
+		if base, ok := capacity["base"].(int); ok {
+			capacityTest.Base = base
+		}
+		if weight, ok := capacity["weight"].(int); ok {
+			capacityTest.Weight = weight
+		}
+		capacityTests = append(capacityTests, capacityTest)
+	}
+	return capacityTests
+}
+
+// findGroup returns the first `group` with the deployment type, `ec2` or `fargate`
+func findGroup(vars map[string]any, deploymentType string) map[string]any {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, ok := vars[key].(map[string]any)
+		if !ok {
+			continue
+		}
+		if _, ok := value[deploymentType].(map[string]any); ok && key == "group" {
+			return value
+		}
+		if group := findGroup(value, deploymentType); group != nil {
+			return group
+		}
+	}
+	return nil
+}
+
+func (c *Client) ValidateAutoScaling(t *testing.T, clusterName, serviceName string, autoScaling AutoScalingTest) {
+	util.RunTestStage(t, "validate_auto_scaling", func() {
+		c.options.Logger.Logf(t, "auto scaling :: %+v", autoScaling)
+		util.ReportResource(t, "ecs_cluster", clusterName)
+		util.ReportResource(t, "ecs_service", serviceName)
+		sess := c.Session(t)
+		if err := ValidateAutoScalingE(ecs.New(sess), autoscaling.New(sess), ec2.New(sess), clusterName, serviceName, autoScaling); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// ValidateAutoScalingE checks the capacity provider strategy of the service, then the sizes, the instance types
+// and the spot/on-demand split of the auto scaling groups behind the capacity providers of the cluster
+func ValidateAutoScalingE(ecsClient ecsiface.ECSAPI, autoScalingClient autoscalingiface.AutoScalingAPI, ec2Client ec2iface.EC2API, clusterName, serviceName string, autoScaling AutoScalingTest) error {
+	clusters, err := ecsClient.DescribeClusters(&ecs.DescribeClustersInput{Clusters: []*string{awsSDK.String(clusterName)}})
+	if err != nil {
+		return fmt.Errorf("describe cluster %s: %w", clusterName, err)
+	}
+	if len(clusters.Clusters) != 1 {
+		return fmt.Errorf("cluster %s not found", clusterName)
+	}
+	cluster := clusters.Clusters[0]
+	services, err := ecsClient.DescribeServices(&ecs.DescribeServicesInput{Cluster: awsSDK.String(clusterName), Services: []*string{awsSDK.String(serviceName)}})
+	if err != nil {
+		return fmt.Errorf("describe service %s: %w", serviceName, err)
+	}
+	if len(services.Services) != 1 {
+		return fmt.Errorf("service %s not found in cluster %s", serviceName, clusterName)
+	}
+
+	problems := []string{}
+
+	strategyItems := capacityProviderStrategy(cluster, services.Services[0])
+	providerNames := []*string{}
+	for _, capacity := range autoScaling.Capacities {
+		name := CapacityProviderName(clusterName, capacity.Type)
+		if !contains(awsSDK.StringValueSlice(cluster.CapacityProviders), name) {
+			problems = append(problems, fmt.Sprintf("capacity provider %s: not in the cluster %v", name, awsSDK.StringValueSlice(cluster.CapacityProviders)))
+			continue
+		}
+		providerNames = append(providerNames, awsSDK.String(name))
+		item, ok := strategyItems[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("capacity provider %s: not in the strategy of the service", name))
+			continue
+		}
+		if base := awsSDK.Int64Value(item.Base); base != int64(capacity.Base) {
+			problems = append(problems, fmt.Sprintf("capacity provider %s: base %d, expected %d", name, base, capacity.Base))
+		}
+		if weight := awsSDK.Int64Value(item.Weight); weight != int64(capacity.Weight) {
+			problems = append(problems, fmt.Sprintf("capacity provider %s: weight %d, expected %d", name, weight, capacity.Weight))
+		}
+	}
+	if len(providerNames) == 0 {
+		return fmt.Errorf("auto scaling validation of cluster %s failed:\n%s", clusterName, strings.Join(problems, "\n"))
+	}
+
+	// auto scaling groups
+	providers, err := ecsClient.DescribeCapacityProviders(&ecs.DescribeCapacityProvidersInput{CapacityProviders: providerNames})
+	if err != nil {
+		return fmt.Errorf("describe capacity providers of %s: %w", clusterName, err)
+	}
+	groupNames := []*string{}
+	for _, provider := range providers.CapacityProviders {
+		if provider.AutoScalingGroupProvider == nil {
+			problems = append(problems, fmt.Sprintf("capacity provider %s: no auto scaling group", awsSDK.StringValue(provider.Name)))
+			continue
+		}
+		groupName := autoScalingGroupName(awsSDK.StringValue(provider.AutoScalingGroupProvider.AutoScalingGroupArn))
+		if !contains(awsSDK.StringValueSlice(groupNames), groupName) {
+			groupNames = append(groupNames, awsSDK.String(groupName))
+		}
+	}
+	groups := []*autoscaling.Group{}
+	if len(groupNames) > 0 {
+		err = autoScalingClient.DescribeAutoScalingGroupsPages(&autoscaling.DescribeAutoScalingGroupsInput{AutoScalingGroupNames: groupNames}, func(page *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
+			groups = append(groups, page.AutoScalingGroups...)
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("describe auto scaling groups %v: %w", awsSDK.StringValueSlice(groupNames), err)
+		}
+		if len(groups) != len(groupNames) {
+			problems = append(problems, fmt.Sprintf("auto scaling groups %v: found %d", awsSDK.StringValueSlice(groupNames), len(groups)))
+		}
+	}
+
+	weightTotal := 0
+	weights := map[string]int{}
+	for _, capacity := range autoScaling.Capacities {
+		weightTotal += capacity.Weight
+		weights[capacity.Type] += capacity.Weight
+	}
+	instanceTypesFound := map[string]bool{}
+	capacityTypesFound := map[string]bool{}
+	for _, group := range groups {
+		groupName := awsSDK.StringValue(group.AutoScalingGroupName)
+		instanceTypes, onDemandPercentage, err := autoScalingGroupInstances(ec2Client, group)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("auto scaling group %s: %s", groupName, err))
+			continue
+		}
+
+		// split, a group is either on-demand, spot or mixed
+		weight := weightTotal
+		switch onDemandPercentage {
+		case 100:
+			capacityTypesFound[CapacityTypeOnDemand] = true
+			weight = weights[CapacityTypeOnDemand]
+		case 0:
+			capacityTypesFound[CapacityTypeSpot] = true
+			weight = weights[CapacityTypeSpot]
+		default:
+			capacityTypesFound[CapacityTypeOnDemand] = true
+			capacityTypesFound[CapacityTypeSpot] = true
+			if expected := weights[CapacityTypeOnDemand] * 100 / weightTotal; onDemandPercentage != expected {
+				problems = append(problems, fmt.Sprintf("auto scaling group %s: on-demand percentage %d, expected %d", groupName, onDemandPercentage, expected))
+			}
+		}
+		if weight == 0 {
+			problems = append(problems, fmt.Sprintf("auto scaling group %s: %d%% on-demand, no capacity of that type expected", groupName, onDemandPercentage))
+			continue
+		}
+
+		// sizes, each group gets its share of the deployment from the weight of its capacity
+		mismatches := []string{}
+		for _, size := range []struct {
+			name     string
+			actual   int64
+			expected int
+		}{
+			{"min_size", awsSDK.Int64Value(group.MinSize), int(math.Floor(float64(autoScaling.MinSize*weight) / float64(weightTotal)))},
+			{"max_size", awsSDK.Int64Value(group.MaxSize), int(math.Ceil(float64(autoScaling.MaxSize*weight) / float64(weightTotal)))},
+			{"desired_size", awsSDK.Int64Value(group.DesiredCapacity), int(math.Ceil(float64(autoScaling.DesiredSize*weight) / float64(weightTotal)))},
+		} {
+			if size.actual != int64(size.expected) {
+				mismatches = append(mismatches, fmt.Sprintf("%s %d, expected %d", size.name, size.actual, size.expected))
+			}
+		}
+
+		// instance types
+		for _, instanceType := range instanceTypes {
+			instanceTypesFound[instanceType] = true
+			if !contains(autoScaling.InstanceTypes, instanceType) {
+				mismatches = append(mismatches, fmt.Sprintf("instance type %s, expected one of %v", instanceType, autoScaling.InstanceTypes))
+			}
+		}
+		if len(mismatches) > 0 {
+			problems = append(problems, fmt.Sprintf("auto scaling group %s: %s", groupName, strings.Join(mismatches, ", ")))
+		}
+	}
+	for _, capacity := range autoScaling.Capacities {
+		if len(groups) > 0 && !capacityTypesFound[capacity.Type] {
+			problems = append(problems, fmt.Sprintf("capacity %s: no auto scaling group", capacity.Type))
+		}
+	}
+	for _, instanceType := range autoScaling.InstanceTypes {
+		if len(groups) > 0 && !instanceTypesFound[instanceType] {
+			problems = append(problems, fmt.Sprintf("instance type %s: no auto scaling group", instanceType))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("auto scaling validation of cluster %s failed:\n%s", clusterName, strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// capacityProviderStrategy returns the strategy by capacity provider, the service without its own strategy uses the default one of the cluster
+func capacityProviderStrategy(cluster *ecs.Cluster, service *ecs.Service) map[string]*ecs.CapacityProviderStrategyItem {
+	strategy := service.CapacityProviderStrategy
+	if len(strategy) == 0 {
+		strategy = cluster.DefaultCapacityProviderStrategy
+	}
+	items := map[string]*ecs.CapacityProviderStrategyItem{}
+	for _, item := range strategy {
+		items[awsSDK.StringValue(item.CapacityProvider)] = item
+	}
+	return items
+}
+
+// autoScalingGroupName extracts the name from `arn:aws:autoscaling:<region>:<account>:autoScalingGroup:<uuid>:autoScalingGroupName/<name>`
+func autoScalingGroupName(arn string) string {
+	if index := strings.LastIndex(arn, "autoScalingGroupName/"); index >= 0 {
+		return arn[index+len("autoScalingGroupName/"):]
+	}
+	return arn
+}
+
+// autoScalingGroupInstances returns the instance types of the launch template or of the mixed instances policy, and the percentage of on-demand instances
+func autoScalingGroupInstances(client ec2iface.EC2API, group *autoscaling.Group) (instanceTypes []string, onDemandPercentage int, err error) {
+	if policy := group.MixedInstancesPolicy; policy != nil {
+		onDemandPercentage = 100
+		if policy.InstancesDistribution != nil && policy.InstancesDistribution.OnDemandPercentageAboveBaseCapacity != nil {
+			onDemandPercentage = int(awsSDK.Int64Value(policy.InstancesDistribution.OnDemandPercentageAboveBaseCapacity))
+		}
+		if policy.LaunchTemplate == nil {
+			return nil, 0, fmt.Errorf("no launch template in the mixed instances policy")
+		}
+		for _, override := range policy.LaunchTemplate.Overrides {
+			if override.InstanceType != nil {
+				instanceTypes = append(instanceTypes, awsSDK.StringValue(override.InstanceType))
+			}
+		}
+		if len(instanceTypes) > 0 {
+			return instanceTypes, onDemandPercentage, nil
+		}
+		data, err := launchTemplateData(client, policy.LaunchTemplate.LaunchTemplateSpecification)
+		if err != nil {
+			return nil, 0, err
+		}
+		return []string{awsSDK.StringValue(data.InstanceType)}, onDemandPercentage, nil
+	}
+
+	if group.LaunchTemplate == nil {
+		return nil, 0, fmt.Errorf("no launch template")
+	}
+	data, err := launchTemplateData(client, group.LaunchTemplate)
+	if err != nil {
+		return nil, 0, err
+	}
+	onDemandPercentage = 100
+	if data.InstanceMarketOptions != nil && awsSDK.StringValue(data.InstanceMarketOptions.MarketType) == ec2.MarketTypeSpot {
+		onDemandPercentage = 0
+	}
+	return []string{awsSDK.StringValue(data.InstanceType)}, onDemandPercentage, nil
+}
+
+func launchTemplateData(client ec2iface.EC2API, specification *autoscaling.LaunchTemplateSpecification) (*ec2.ResponseLaunchTemplateData, error) {
+	if specification == nil {
+		return nil, fmt.Errorf("no launch template specification")
+	}
+	version := awsSDK.StringValue(specification.Version)
+	if version == "" {
+		version = "$Default"
+	}
+	input := &ec2.DescribeLaunchTemplateVersionsInput{Versions: []*string{awsSDK.String(version)}}
+	if specification.LaunchTemplateId != nil {
+		input.LaunchTemplateId = specification.LaunchTemplateId
+	} else {
+		input.LaunchTemplateName = specification.LaunchTemplateName
+	}
+	output, err := client.DescribeLaunchTemplateVersions(input)
+	if err != nil {
+		return nil, fmt.Errorf("describe launch template %s%s version %s: %w", awsSDK.StringValue(specification.LaunchTemplateId), awsSDK.StringValue(specification.LaunchTemplateName), version, err)
+	}
+	if len(output.LaunchTemplateVersions) != 1 || output.LaunchTemplateVersions[0].LaunchTemplateData == nil {
+		return nil, fmt.Errorf("launch template %s%s version %s not found", awsSDK.StringValue(specification.LaunchTemplateId), awsSDK.StringValue(specification.LaunchTemplateName), version)
+	}
+	return output.LaunchTemplateVersions[0].LaunchTemplateData, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package module
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// same as the rest scenario
+var fakeRestAutoScaling = AutoScalingTest{
+	MinSize:       1,
+	MaxSize:       1,
+	DesiredSize:   1,
+	InstanceTypes: []string{"t3.small"},
+	Capacities:    []CapacityTest{{Type: CapacityTypeOnDemand, Weight: 50}},
+}
+
+func setupFakeAutoScaling() (*fakeEcs, *fakeAutoScaling, *fakeEc2) {
+	ec2Client := newFakeEc2()
+	ec2Client.launchTemplates["lt-0"] = &ec2.ResponseLaunchTemplateData{InstanceType: awsSDK.String("t3.small")}
+
+	autoScalingClient := newFakeAutoScaling()
+	groupArn := autoScalingClient.putGroup(&autoscaling.Group{
+		AutoScalingGroupName: awsSDK.String("vi-rest-test-ON-t3-s"),
+		MinSize:              awsSDK.Int64(1),
+		MaxSize:              awsSDK.Int64(1),
+		DesiredCapacity:      awsSDK.Int64(1),
+		LaunchTemplate:       &autoscaling.LaunchTemplateSpecification{LaunchTemplateId: awsSDK.String("lt-0"), Version: awsSDK.String("$Default")},
+	})
+
+	ecsClient, _, _ := newFakeEcsService()
+	ecsClient.putCapacityProvider(fakeClusterName, CapacityProviderName(fakeClusterName, CapacityTypeOnDemand), groupArn, 0, 50)
+	return ecsClient, autoScalingClient, ec2Client
+}
+
+func Test_Unit_AutoScalingFromVars(t *testing.T) {
+	ec2Group := map[string]any{
+		"instance_types": []string{"t3.small"},
+		"capacities": []map[string]any{
+			{"type": "SPOT", "base": 1, "weight": 50},
+			{"base": nil},
+		},
+	}
+	deployment := map[string]any{"min_size": 1, "max_size": 3, "desired_size": 2}
+	expected := AutoScalingTest{
+		MinSize:       1,
+		MaxSize:       3,
+		DesiredSize:   2,
+		InstanceTypes: []string{"t3.small"},
+		Capacities:    []CapacityTest{{Type: CapacityTypeSpot, Base: 1, Weight: 50}, {Type: CapacityTypeOnDemand, Weight: 1}},
+	}
+
+	for name, vars := range map[string]map[string]any{
+		"microservice": {"microservice": map[string]any{"container": map[string]any{"group": map[string]any{"deployment": deployment, "ec2": ec2Group}}}},
+		"orchestrator": {"orchestrator": map[string]any{"group": map[string]any{"deployment": deployment, "ec2": ec2Group}, "ecs": map[string]any{}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if autoScaling := AutoScalingFromVars(t, vars); !reflect.DeepEqual(expected, autoScaling) {
+				t.Fatalf("expected %+v, got %+v", expected, autoScaling)
+			}
+		})
+	}
+}
+
+func Test_Unit_ValidateAutoScaling(t *testing.T) {
+	ecsClient, autoScalingClient, ec2Client := setupFakeAutoScaling()
+	if err := ValidateAutoScalingE(ecsClient, autoScalingClient, ec2Client, fakeClusterName, fakeServiceName, fakeRestAutoScaling); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Unit_ValidateAutoScaling_Spot(t *testing.T) {
+	ecsClient, autoScalingClient, ec2Client := setupFakeAutoScaling()
+	group := autoScalingClient.groups["vi-rest-test-ON-t3-s"]
+	group.LaunchTemplate = nil
+	group.MixedInstancesPolicy = &autoscaling.MixedInstancesPolicy{
+		InstancesDistribution: &autoscaling.InstancesDistribution{OnDemandPercentageAboveBaseCapacity: awsSDK.Int64(0)},
+		LaunchTemplate: &autoscaling.LaunchTemplate{
+			LaunchTemplateSpecification: &autoscaling.LaunchTemplateSpecification{LaunchTemplateId: awsSDK.String("lt-0")},
+			Overrides:                   []*autoscaling.LaunchTemplateOverrides{{InstanceType: awsSDK.String("t3.small")}, {InstanceType: awsSDK.String("t3.medium")}},
+		},
+	}
+	ecsClient.clusters[fakeClusterName] = &ecs.Cluster{ClusterName: awsSDK.String(fakeClusterName)}
+	ecsClient.putCapacityProvider(fakeClusterName, CapacityProviderName(fakeClusterName, CapacityTypeSpot), awsSDK.StringValue(group.AutoScalingGroupARN), 1, 50)
+
+	autoScaling := fakeRestAutoScaling
+	autoScaling.InstanceTypes = []string{"t3.small", "t3.medium"}
+	autoScaling.Capacities = []CapacityTest{{Type: CapacityTypeSpot, Base: 1, Weight: 50}}
+	if err := ValidateAutoScalingE(ecsClient, autoScalingClient, ec2Client, fakeClusterName, fakeServiceName, autoScaling); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Unit_ValidateAutoScaling_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		setup   func(ecsClient *fakeEcs, autoScalingClient *fakeAutoScaling, ec2Client *fakeEc2, autoScaling *AutoScalingTest)
+		errMsgs []string
+	}{
+		{
+			name: "missing capacity provider",
+			setup: func(ecsClient *fakeEcs, autoScalingClient *fakeAutoScaling, ec2Client *fakeEc2, autoScaling *AutoScalingTest) {
+				autoScaling.Capacities = append(autoScaling.Capacities, CapacityTest{Type: CapacityTypeSpot, Weight: 50})
+			},
+			errMsgs: []string{
+				"capacity provider vi-rest-test-SPOT: not in the cluster",
+				"capacity SPOT: no auto scaling group",
+			},
+		},
+		{
+			name: "strategy",
+			setup: func(ecsClient *fakeEcs, autoScalingClient *fakeAutoScaling, ec2Client *fakeEc2, autoScaling *AutoScalingTest) {
+				autoScaling.Capacities[0].Base = 1
+				autoScaling.Capacities[0].Weight = 20
+			},
+			errMsgs: []string{"capacity provider vi-rest-test-ON_DEMAND: base 0, expected 1\ncapacity provider vi-rest-test-ON_DEMAND: weight 50, expected 20"},
+		},
+		{
+			name: "service strategy",
+			setup: func(ecsClient *fakeEcs, autoScalingClient *fakeAutoScaling, ec2Client *fakeEc2, autoScaling *AutoScalingTest) {
+				ecsClient.services[fakeClusterName+"/"+fakeServiceName].CapacityProviderStrategy = []*ecs.CapacityProviderStrategyItem{{
+					CapacityProvider: awsSDK.String(CapacityProviderName(fakeClusterName, CapacityTypeOnDemand)),
+					Weight:           awsSDK.Int64(1),
+				}}
+			},
+			errMsgs: []string{"capacity provider vi-rest-test-ON_DEMAND: weight 1, expected 50"},
+		},
+		{
+			name: "sizes and instance type",
+			setup: func(ecsClient *fakeEcs, autoScalingClient *fakeAutoScaling, ec2Client *fakeEc2, autoScaling *AutoScalingTest) {
+				autoScaling.MaxSize = 3
+				autoScaling.DesiredSize = 2
+				ec2Client.launchTemplates["lt-0"].InstanceType = awsSDK.String("t3.medium")
+			},
+			errMsgs: []string{
+				"auto scaling group vi-rest-test-ON-t3-s: max_size 1, expected 3, desired_size 1, expected 2, instance type t3.medium, expected one of [t3.small]",
+				"instance type t3.small: no auto scaling group",
+			},
+		},
+		{
+			name: "spot instead of on-demand",
+			setup: func(ecsClient *fakeEcs, autoScalingClient *fakeAutoScaling, ec2Client *fakeEc2, autoScaling *AutoScalingTest) {
+				ec2Client.launchTemplates["lt-0"].InstanceMarketOptions = &ec2.LaunchTemplateInstanceMarketOptions{MarketType: awsSDK.String(ec2.MarketTypeSpot)}
+			},
+			errMsgs: []string{
+				"auto scaling group vi-rest-test-ON-t3-s: 0% on-demand, no capacity of that type expected",
+				"capacity ON_DEMAND: no auto scaling group",
+			},
+		},
+		{
+			name: "launch template",
+			setup: func(ecsClient *fakeEcs, autoScalingClient *fakeAutoScaling, ec2Client *fakeEc2, autoScaling *AutoScalingTest) {
+				delete(ec2Client.launchTemplates, "lt-0")
+			},
+			errMsgs: []string{"auto scaling group vi-rest-test-ON-t3-s: describe launch template lt-0 version $Default"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ecsClient, autoScalingClient, ec2Client := setupFakeAutoScaling()
+			autoScaling := fakeRestAutoScaling
+			autoScaling.Capacities = append([]CapacityTest{}, fakeRestAutoScaling.Capacities...)
+			testCase.setup(ecsClient, autoScalingClient, ec2Client, &autoScaling)
+			err := ValidateAutoScalingE(ecsClient, autoScalingClient, ec2Client, fakeClusterName, fakeServiceName, autoScaling)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, errMsg := range testCase.errMsgs {
+				if !strings.Contains(err.Error(), errMsg) {
+					t.Errorf("expected error containing %q, got %v", errMsg, err)
+				}
+			}
+		})
+	}
+}
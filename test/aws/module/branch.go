@@ -0,0 +1,107 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/vistimi/infrastructure-modules/test/aws/naming"
+)
+
+const (
+	// comma separated branches or patterns, e.g. `trunk,feature-*`, patterns are matched against the branches of the repository
+	BranchNamesEnv = "BRANCH_NAMES"
+	// single branch, same as the Makefile targets
+	BranchNameEnv = "BRANCH_NAME"
+)
+
+type BranchLister interface {
+	ListBranches(owner, repository string) ([]string, error)
+}
+
+// EcrRepositoryName is the name of the repository pushed by .github/workflows/ecr.yml for a branch
+func EcrRepositoryName(repository, branch, extension string) string {
+	return naming.EcrRepository.Build(repository, branch+extension)
+}
+
+// Branches returns the branches to test from the environment, the defaults otherwise
+func Branches(t *testing.T, lister BranchLister, owner, repository string, defaults ...string) []string {
+	branches, err := BranchesE(os.Getenv, lister, owner, repository, defaults...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return branches
+}
+
+func BranchesE(getenv func(string) string, lister BranchLister, owner, repository string, defaults ...string) ([]string, error) {
+	patterns := []string{}
+	for _, pattern := range strings.Split(getenv(BranchNamesEnv), ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	if len(patterns) == 0 {
+		if branch := strings.TrimSpace(getenv(BranchNameEnv)); branch != "" {
+			return []string{branch}, nil
+		}
+		if len(defaults) == 0 {
+			return nil, fmt.Errorf("no branch to test, set %s or %s", BranchNamesEnv, BranchNameEnv)
+		}
+		return defaults, nil
+	}
+
+	var repositoryBranches []string
+	branches := []string{}
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		if !strings.ContainsAny(pattern, "*?[") {
+			if !seen[pattern] {
+				seen[pattern] = true
+				branches = append(branches, pattern)
+			}
+			continue
+		}
+
+		if repositoryBranches == nil {
+			if lister == nil {
+				return nil, fmt.Errorf("branch pattern %q requires the branches of the repository", pattern)
+			}
+			listed, err := lister.ListBranches(owner, repository)
+			if err != nil {
+				return nil, fmt.Errorf("list branches of %s/%s: %w", owner, repository, err)
+			}
+			repositoryBranches = listed
+		}
+		matched := false
+		for _, branch := range repositoryBranches {
+			ok, err := path.Match(pattern, branch)
+			if err != nil {
+				return nil, fmt.Errorf("branch pattern %q: %w", pattern, err)
+			}
+			if ok {
+				matched = true
+				if !seen[branch] {
+					seen[branch] = true
+					branches = append(branches, branch)
+				}
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("branch pattern %q matches no branch of %s/%s", pattern, owner, repository)
+		}
+	}
+	return branches, nil
+}
+
+// RunBranches runs the scenario once per branch as parallel sub tests, each in its own copy of the module
+func RunBranches(t *testing.T, branches []string, scenario func(t *testing.T, branch string)) {
+	for _, branch := range branches {
+		branch := branch
+		t.Run(branch, func(t *testing.T) {
+			t.Parallel()
+			scenario(t, branch)
+		})
+	}
+}
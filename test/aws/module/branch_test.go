@@ -0,0 +1,61 @@
+package module
+
+import (
+	"reflect"
+	"testing"
+)
+
+type fakeBranchLister []string
+
+func (f fakeBranchLister) ListBranches(owner, repository string) ([]string, error) {
+	return f, nil
+}
+
+func Test_Unit_Branches(t *testing.T) {
+	lister := fakeBranchLister{"trunk", "feature-a", "feature-b", "fix-c"}
+	testCases := []struct {
+		name     string
+		env      map[string]string
+		expected []string
+		err      bool
+	}{
+		{name: "default", env: map[string]string{}, expected: []string{"trunk"}},
+		{name: "branch name", env: map[string]string{BranchNameEnv: "fix-c"}, expected: []string{"fix-c"}},
+		{name: "list", env: map[string]string{BranchNamesEnv: "trunk, fix-c,trunk"}, expected: []string{"trunk", "fix-c"}},
+		{name: "pattern", env: map[string]string{BranchNamesEnv: "trunk,feature-*"}, expected: []string{"trunk", "feature-a", "feature-b"}},
+		{name: "all", env: map[string]string{BranchNamesEnv: "*"}, expected: []string(lister)},
+		{name: "no match", env: map[string]string{BranchNamesEnv: "release-*"}, err: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			branches, err := BranchesE(func(key string) string { return testCase.env[key] }, lister, "vistimi", "scraper-backend", "trunk")
+			if testCase.err {
+				if err == nil {
+					t.Fatalf("expected error, got %v", branches)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(testCase.expected, branches) {
+				t.Errorf("expected %v, got %v", testCase.expected, branches)
+			}
+		})
+	}
+}
+
+func Test_Unit_EcrRepositoryName(t *testing.T) {
+	for _, testCase := range []struct {
+		repository, branch, extension, expected string
+	}{
+		{"scraper-backend", "trunk", "", "scraper-backend-trunk"},
+		{"viton-hd", "Trunk", "-rest", "viton-hd-trunk-rest"},
+		{"viton-hd", "feature/Try On", "", "viton-hd-feature/try-on"},
+	} {
+		if name := EcrRepositoryName(testCase.repository, testCase.branch, testCase.extension); name != testCase.expected {
+			t.Errorf("expected %s, got %s", testCase.expected, name)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package module
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+
+	"github.com/vistimi/infrastructure-modules/test/dotenv"
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+// EnvBucketTest is the env file uploaded in the env bucket of a microservice
+type EnvBucketTest struct {
+	BucketName string
+	FileKey    string
+	// local file uploaded by terraform
+	FilePath   string
+	Versioning bool
+}
+
+// EnvBucketFromState reads the name of the bucket from the `env` output, the rest comes from the `bucket_env` variable
+func EnvBucketFromState(t *testing.T, microservicePath, modulePath string, bucketEnv map[string]any) EnvBucketTest {
+	env := ExtractFromState(t, microservicePath, util.Format(".", modulePath, "env"))
+	buckets, ok := env.(map[string]any)
+	if !ok || len(buckets) != 1 {
+		t.Fatalf("expected one env bucket in the state, got %+v", env)
+	}
+	bucketName := ""
+	for _, bucket := range buckets {
+		bucketName, _ = bucket.(map[string]any)["bucket"].(map[string]any)["name"].(string)
+	}
+	if bucketName == "" {
+		t.Fatalf("no env bucket name in the state: %+v", env)
+	}
+
+	envBucket := EnvBucketTest{BucketName: bucketName}
+	envBucket.FileKey, _ = bucketEnv["file_key"].(string)
+	envBucket.FilePath, _ = bucketEnv["file_path"].(string)
+	envBucket.Versioning, _ = bucketEnv["versioning"].(bool)
+	return envBucket
+}
+
+func (c *Client) ValidateEnvBucket(t *testing.T, clusterName, serviceName string, envBucket EnvBucketTest) {
+	util.RunTestStage(t, "validate_env_bucket", func() {
+		c.options.Logger.Logf(t, "env bucket :: %+v", envBucket)
+		util.ReportResource(t, "s3_bucket", envBucket.BucketName)
+		sess := c.Session(t)
+		if err := ValidateEnvBucketE(s3.New(sess), ecs.New(sess), clusterName, serviceName, envBucket); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// ValidateEnvBucketE checks the keys of the uploaded file, the versioning of the bucket and the reference in the task definition of the service
+func ValidateEnvBucketE(s3Client s3iface.S3API, ecsClient ecsiface.ECSAPI, clusterName, serviceName string, envBucket EnvBucketTest) error {
+	// file
+	object, err := s3Client.GetObject(&s3.GetObjectInput{
+		Bucket: awsSDK.String(envBucket.BucketName),
+		Key:    awsSDK.String(envBucket.FileKey),
+	})
+	if err != nil {
+		return fmt.Errorf("get env file s3://%s/%s: %w", envBucket.BucketName, envBucket.FileKey, err)
+	}
+	defer object.Body.Close()
+	remoteEnv, err := dotenv.Parse(object.Body)
+	if err != nil {
+		return fmt.Errorf("parse env file s3://%s/%s: %w", envBucket.BucketName, envBucket.FileKey, err)
+	}
+	localEnv, err := dotenv.ReadFile(envBucket.FilePath)
+	if err != nil {
+		return err
+	}
+	if missing, extra := diffKeys(localEnv.Keys(), remoteEnv.Keys()); len(missing) > 0 || len(extra) > 0 {
+		return fmt.Errorf("env file s3://%s/%s differs from %s: missing keys %v, extra keys %v", envBucket.BucketName, envBucket.FileKey, envBucket.FilePath, missing, extra)
+	}
+
+	// versioning
+	versioning, err := s3Client.GetBucketVersioning(&s3.GetBucketVersioningInput{Bucket: awsSDK.String(envBucket.BucketName)})
+	if err != nil {
+		return fmt.Errorf("get versioning of bucket %s: %w", envBucket.BucketName, err)
+	}
+	versioningEnabled := awsSDK.StringValue(versioning.Status) == s3.BucketVersioningStatusEnabled
+	if versioningEnabled != envBucket.Versioning {
+		return fmt.Errorf("bucket %s versioning status %q, expected enabled %t", envBucket.BucketName, awsSDK.StringValue(versioning.Status), envBucket.Versioning)
+	}
+
+	// task definition
+	services, err := ecsClient.DescribeServices(&ecs.DescribeServicesInput{
+		Cluster:  awsSDK.String(clusterName),
+		Services: []*string{awsSDK.String(serviceName)},
+	})
+	if err != nil {
+		return fmt.Errorf("describe service %s: %w", serviceName, err)
+	}
+	if len(services.Services) != 1 {
+		return fmt.Errorf("expected one service %s in cluster %s, got %d", serviceName, clusterName, len(services.Services))
+	}
+	taskDefinitionArn := awsSDK.StringValue(services.Services[0].TaskDefinition)
+	taskDefinition, err := ecsClient.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{TaskDefinition: awsSDK.String(taskDefinitionArn)})
+	if err != nil {
+		return fmt.Errorf("describe task definition %s: %w", taskDefinitionArn, err)
+	}
+	// the partition is not known, e.g. arn:aws:s3:::bucket/key
+	fileArnSuffix := fmt.Sprintf(":s3:::%s/%s", envBucket.BucketName, envBucket.FileKey)
+	for _, container := range taskDefinition.TaskDefinition.ContainerDefinitions {
+		for _, environmentFile := range container.EnvironmentFiles {
+			if awsSDK.StringValue(environmentFile.Type) == ecs.EnvironmentFileTypeS3 && strings.HasSuffix(awsSDK.StringValue(environmentFile.Value), fileArnSuffix) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("task definition %s has no environmentFiles referencing s3://%s/%s", taskDefinitionArn, envBucket.BucketName, envBucket.FileKey)
+}
+
+func diffKeys(expected, actual []string) (missing, extra []string) {
+	actualKeys := map[string]bool{}
+	for _, key := range actual {
+		actualKeys[key] = true
+	}
+	expectedKeys := map[string]bool{}
+	for _, key := range expected {
+		expectedKeys[key] = true
+		if !actualKeys[key] {
+			missing = append(missing, key)
+		}
+	}
+	for _, key := range actual {
+		if !expectedKeys[key] {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	return missing, extra
+}
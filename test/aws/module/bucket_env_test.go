@@ -0,0 +1,96 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func setupFakeEnvBucket(t *testing.T, versioning bool, environmentFile string) (*fakeS3, *fakeEcs, EnvBucketTest) {
+	envBucket := EnvBucketTest{
+		BucketName: "vi-sp-be-test-env",
+		FileKey:    "trunk.env",
+		FilePath:   filepath.Join(t.TempDir(), "override.env"),
+		Versioning: versioning,
+	}
+	if err := os.WriteFile(envBucket.FilePath, []byte("COMMON_NAME=test\nCLOUD_HOST=aws\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s3Client := newFakeS3()
+	s3Client.putObject(envBucket.BucketName, envBucket.FileKey, "COMMON_NAME=test\nCLOUD_HOST=aws\n")
+	if versioning {
+		s3Client.versioning[envBucket.BucketName] = s3.BucketVersioningStatusEnabled
+	}
+
+	ecsClient, _, taskDefinition := newFakeEcsService()
+	taskDefinition.ContainerDefinitions[0].EnvironmentFiles = []*ecs.EnvironmentFile{{
+		Type:  awsSDK.String(ecs.EnvironmentFileTypeS3),
+		Value: awsSDK.String(environmentFile),
+	}}
+	return s3Client, ecsClient, envBucket
+}
+
+func Test_Unit_ValidateEnvBucket(t *testing.T) {
+	for _, versioning := range []bool{false, true} {
+		s3Client, ecsClient, envBucket := setupFakeEnvBucket(t, versioning, "arn:aws:s3:::vi-sp-be-test-env/trunk.env")
+		if err := ValidateEnvBucketE(s3Client, ecsClient, fakeClusterName, fakeServiceName, envBucket); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_Unit_ValidateEnvBucket_Errors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		setup  func(s3Client *fakeS3, ecsClient *fakeEcs, envBucket *EnvBucketTest)
+		errMsg string
+	}{
+		{
+			name: "missing object",
+			setup: func(s3Client *fakeS3, ecsClient *fakeEcs, envBucket *EnvBucketTest) {
+				envBucket.FileKey = "feature.env"
+			},
+			errMsg: "get env file",
+		},
+		{
+			name: "different keys",
+			setup: func(s3Client *fakeS3, ecsClient *fakeEcs, envBucket *EnvBucketTest) {
+				s3Client.putObject(envBucket.BucketName, envBucket.FileKey, "COMMON_NAME=test\nPORT=8080\n")
+			},
+			errMsg: "missing keys [CLOUD_HOST], extra keys [PORT]",
+		},
+		{
+			name: "versioning",
+			setup: func(s3Client *fakeS3, ecsClient *fakeEcs, envBucket *EnvBucketTest) {
+				envBucket.Versioning = true
+			},
+			errMsg: "versioning",
+		},
+		{
+			name: "task definition",
+			setup: func(s3Client *fakeS3, ecsClient *fakeEcs, envBucket *EnvBucketTest) {
+				for _, taskDefinition := range ecsClient.taskDefinitions {
+					taskDefinition.ContainerDefinitions[0].EnvironmentFiles[0].Value = awsSDK.String("arn:aws:s3:::vi-sp-be-test-env/feature.env")
+				}
+			},
+			errMsg: "no environmentFiles",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			s3Client, ecsClient, envBucket := setupFakeEnvBucket(t, false, "arn:aws:s3:::vi-sp-be-test-env/trunk.env")
+			testCase.setup(s3Client, ecsClient, &envBucket)
+			err := ValidateEnvBucketE(s3Client, ecsClient, fakeClusterName, fakeServiceName, envBucket)
+			if err == nil || !strings.Contains(err.Error(), testCase.errMsg) {
+				t.Fatalf("expected error containing %q, got %v", testCase.errMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package module
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+
+	terratestAws "github.com/gruntwork-io/terratest/modules/aws"
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/vistimi/infrastructure-modules/test/config"
+)
+
+// DefaultPollPolicy is the policy of the checks waiting for the resources, when neither the options nor the test give one
+var DefaultPollPolicy = PollPolicy{MaxRetries: 5, SleepBetweenRetries: 30 * time.Second}
+
+const (
+	defaultArch        = "x86_64"
+	defaultHttpTimeout = 10 * time.Second
+)
+
+// PollPolicy is the retries of the checks waiting for the resources, e.g. the tasks, the targets or the logs
+type PollPolicy struct {
+	MaxRetries          int
+	SleepBetweenRetries time.Duration
+}
+
+// Options are the settings of the validators, the empty fields take the defaults
+type Options struct {
+	Region string
+	// Domain is the hosted zone of the records when the test gives none, `<name>.<suffix>`
+	Domain string
+	// Credentials of the AWS sessions, the credentials of the environment when nil
+	Credentials *credentials.Credentials
+	Logger      *terratestLogger.Logger
+	// HttpClient requests the endpoints of the microservices
+	HttpClient *http.Client
+	Poll       PollPolicy
+	// Arch is the architecture of the tools downloaded to check the endpoints
+	Arch string
+}
+
+// OptionsFromConfig are the options of the settings of the tests
+func OptionsFromConfig(cfg *config.Config) Options {
+	options := Options{Region: cfg.AccountRegion, Arch: cfg.Arch}
+	if cfg.DomainName != "" && cfg.DomainSuffix != "" {
+		options.Domain = cfg.Domain()
+	}
+	return options
+}
+
+// Client runs the validators with its options, the clients of several regions can be used at once
+type Client struct {
+	options Options
+
+	sessionOnce sync.Once
+	session     *session.Session
+	sessionErr  error
+}
+
+func NewClient(options Options) *Client {
+	if options.Logger == nil {
+		options.Logger = terratestLogger.Default
+	}
+	if options.HttpClient == nil {
+		options.HttpClient = &http.Client{Timeout: defaultHttpTimeout}
+	}
+	if options.Poll.MaxRetries == 0 {
+		options.Poll.MaxRetries = DefaultPollPolicy.MaxRetries
+	}
+	if options.Poll.SleepBetweenRetries == 0 {
+		options.Poll.SleepBetweenRetries = DefaultPollPolicy.SleepBetweenRetries
+	}
+	if options.Arch == "" {
+		options.Arch = defaultArch
+	}
+	return &Client{options: options}
+}
+
+// NewClientFromConfig is the client of the settings of the tests, the test is skipped without the AWS settings
+func NewClientFromConfig(t *testing.T) *Client {
+	return NewClient(OptionsFromConfig(config.Get(t, config.Aws)))
+}
+
+func (c *Client) Options() Options {
+	return c.options
+}
+
+// SessionE is created once, with the credentials of the options or of the environment like terratest
+func (c *Client) SessionE() (*session.Session, error) {
+	c.sessionOnce.Do(func() {
+		if c.options.Credentials == nil {
+			c.session, c.sessionErr = terratestAws.NewAuthenticatedSession(c.options.Region)
+			return
+		}
+		c.session, c.sessionErr = session.NewSession(awsSDK.NewConfig().WithRegion(c.options.Region).WithCredentials(c.options.Credentials))
+	})
+	return c.session, c.sessionErr
+}
+
+// Session fails the test when the session cannot be created
+func (c *Client) Session(t *testing.T) *session.Session {
+	sess, err := c.SessionE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sess
+}
+
+// poll is the policy of the options overridden by the retries of the test
+func (c *Client) poll(maxRetries *int, sleepBetweenRetries *time.Duration) PollPolicy {
+	poll := c.options.Poll
+	if maxRetries != nil {
+		poll.MaxRetries = *maxRetries
+	}
+	if sleepBetweenRetries != nil {
+		poll.SleepBetweenRetries = *sleepBetweenRetries
+	}
+	return poll
+}
@@ -0,0 +1,94 @@
+package module
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+
+	"github.com/vistimi/infrastructure-modules/test/config"
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+func Test_Unit_Client_Options(t *testing.T) {
+	testCases := []struct {
+		name     string
+		options  Options
+		expected Options
+	}{
+		{
+			name:     "defaults",
+			options:  Options{Region: "us-east-1"},
+			expected: Options{Region: "us-east-1", Logger: terratestLogger.Default, Poll: DefaultPollPolicy, Arch: defaultArch},
+		},
+		{
+			name:     "overrides",
+			options:  Options{Region: "eu-west-1", Logger: terratestLogger.Discard, Poll: PollPolicy{MaxRetries: 2}, Arch: "arm64"},
+			expected: Options{Region: "eu-west-1", Logger: terratestLogger.Discard, Poll: PollPolicy{MaxRetries: 2, SleepBetweenRetries: DefaultPollPolicy.SleepBetweenRetries}, Arch: "arm64"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			options := NewClient(testCase.options).Options()
+			if options.HttpClient == nil || options.HttpClient.Timeout != defaultHttpTimeout {
+				t.Fatalf("expected http client with timeout %s, got %+v", defaultHttpTimeout, options.HttpClient)
+			}
+			options.HttpClient = nil
+			if !reflect.DeepEqual(testCase.expected, options) {
+				t.Errorf("expected %+v, got %+v", testCase.expected, options)
+			}
+		})
+	}
+}
+
+func Test_Unit_Client_Poll(t *testing.T) {
+	client := NewClient(Options{Poll: PollPolicy{MaxRetries: 3, SleepBetweenRetries: time.Second}})
+
+	testCases := []struct {
+		name                string
+		maxRetries          *int
+		sleepBetweenRetries *time.Duration
+		expected            PollPolicy
+	}{
+		{name: "options", expected: PollPolicy{MaxRetries: 3, SleepBetweenRetries: time.Second}},
+		{name: "retries", maxRetries: util.Ptr(1), expected: PollPolicy{MaxRetries: 1, SleepBetweenRetries: time.Second}},
+		{name: "sleep", sleepBetweenRetries: util.Ptr(time.Minute), expected: PollPolicy{MaxRetries: 3, SleepBetweenRetries: time.Minute}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if poll := client.poll(testCase.maxRetries, testCase.sleepBetweenRetries); poll != testCase.expected {
+				t.Errorf("expected %+v, got %+v", testCase.expected, poll)
+			}
+		})
+	}
+}
+
+func Test_Unit_OptionsFromConfig(t *testing.T) {
+	testCases := []struct {
+		name     string
+		cfg      config.Config
+		expected Options
+	}{
+		{
+			name:     "domain",
+			cfg:      config.Config{AccountRegion: "us-east-1", DomainName: "name", DomainSuffix: "com", Arch: "arm64"},
+			expected: Options{Region: "us-east-1", Domain: "name.com", Arch: "arm64"},
+		},
+		{
+			name:     "no domain suffix",
+			cfg:      config.Config{AccountRegion: "us-east-1", DomainName: "name"},
+			expected: Options{Region: "us-east-1"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if options := OptionsFromConfig(&testCase.cfg); !reflect.DeepEqual(testCase.expected, options) {
+				t.Errorf("expected %+v, got %+v", testCase.expected, options)
+			}
+		})
+	}
+}
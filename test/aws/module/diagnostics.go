@@ -0,0 +1,454 @@
+package module
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+const (
+	diagnosticsLogsWindow    = 30 * time.Minute
+	diagnosticsLogsMaxEvents = 200
+	diagnosticsMaxActivities = 20
+	diagnosticsMaxTasks      = 100
+)
+
+// sensitiveOutputKeys are redacted from the terraform outputs, on top of the outputs marked as sensitive
+var sensitiveOutputKeys = regexp.MustCompile(`(?i)secret|password|token|private_key|access_key|credentials`)
+
+// DiagnosticsTest locates the resources of a scenario
+type DiagnosticsTest struct {
+	ClusterName string
+	ServiceName string
+	// the directory of the terraform state and the path of the microservice in its outputs, the load balancer and the outputs are skipped when empty
+	MicroservicePath string
+	ModulePath       string
+}
+
+type StoppedTask struct {
+	TaskArn       string
+	StopCode      string
+	StoppedReason string
+	StoppedAt     *time.Time
+	Containers    []StoppedContainer
+}
+
+type StoppedContainer struct {
+	Name     string
+	ExitCode *int64
+	Reason   string
+}
+
+// Diagnostics is the state of a service when a validation failed,
+// each part is collected independently and the failures are kept in Errors
+type Diagnostics struct {
+	Events            []*ecs.ServiceEvent
+	Deployments       []*ecs.Deployment
+	StoppedTasks      []StoppedTask
+	TaskDefinition    *ecs.TaskDefinition
+	Listeners         []*elbv2.Listener
+	TargetHealth      map[string][]*elbv2.TargetHealthDescription // target group arn
+	Logs              []string
+	ScalingActivities map[string][]*autoscaling.Activity // auto scaling group name
+	Outputs           map[string]any
+	Errors            []string
+}
+
+func (d *Diagnostics) addError(err error) {
+	if err != nil {
+		d.Errors = append(d.Errors, err.Error())
+	}
+}
+
+// DiagnosticsOnFailure writes the diagnostics bundle when the test failed or panicked,
+// it is deferred at the beginning of the validate stage so that it runs before the cleanup
+func (c *Client) DiagnosticsOnFailure(t *testing.T, diagnostics DiagnosticsTest) {
+	r := recover()
+	if r == nil && !t.Failed() {
+		return
+	}
+	c.WriteDiagnostics(t, diagnostics)
+	if r != nil {
+		panic(r)
+	}
+}
+
+// WriteDiagnostics collects the diagnostics of the service and writes them in the artifacts directory, it never fails the test.
+// With KEEP_ON_FAILURE the access to the kept resources is logged and written in `access.txt`
+func (c *Client) WriteDiagnostics(t *testing.T, diagnostics DiagnosticsTest) {
+	dir := util.ArtifactsDir(t.Name())
+	c.options.Logger.Logf(t, "writing diagnostics of service %s in %s", diagnostics.ServiceName, dir)
+
+	session, err := c.SessionE()
+	if err != nil {
+		c.options.Logger.Logf(t, "diagnostics not collected: %v", err)
+		return
+	}
+
+	outputs, loadBalancerArn := map[string]any{}, ""
+	var outputsErr error
+	if diagnostics.MicroservicePath != "" {
+		outputs, outputsErr = SanitizedOutputsFromState(diagnostics.MicroservicePath)
+		loadBalancerArn = loadBalancerArnFromOutputs(outputs, diagnostics.ModulePath)
+	}
+	bundle := CollectDiagnosticsE(ecs.New(session), elbv2.New(session), autoscaling.New(session), cloudwatchlogs.New(session), diagnostics.ClusterName, diagnostics.ServiceName, loadBalancerArn)
+	bundle.Outputs = outputs
+	bundle.addError(outputsErr)
+
+	var access *Access
+	if util.KeepOnFailure() {
+		var accessErr error
+		access, accessErr = AccessE(ecs.New(session), c.options.Region, diagnostics.ClusterName, diagnostics.ServiceName, bundle.Listeners, accessHostsFromOutputs(outputs, diagnostics.ModulePath))
+		access.DestroyCommand = util.DestroyCommand(t.Name())
+		bundle.addError(accessErr)
+	}
+
+	if err := WriteDiagnosticsE(dir, bundle); err != nil {
+		c.options.Logger.Logf(t, "diagnostics not written: %v", err)
+	}
+	if access != nil {
+		c.options.Logger.Logf(t, "%s is set, access to the kept resources:\n%s", util.KeepOnFailureEnv, access)
+		if err := os.WriteFile(filepath.Join(dir, "access.txt"), []byte(access.String()), 0o644); err != nil {
+			c.options.Logger.Logf(t, "access not written: %v", err)
+		}
+	}
+}
+
+// CollectDiagnosticsE describes the service, its tasks, load balancer, auto scaling groups and logs,
+// the load balancer is skipped when its arn is empty
+func CollectDiagnosticsE(ecsClient ecsiface.ECSAPI, elbClient elbv2iface.ELBV2API, autoScalingClient autoscalingiface.AutoScalingAPI, logsClient cloudwatchlogsiface.CloudWatchLogsAPI, clusterName, serviceName, loadBalancerArn string) *Diagnostics {
+	diagnostics := &Diagnostics{TargetHealth: map[string][]*elbv2.TargetHealthDescription{}, ScalingActivities: map[string][]*autoscaling.Activity{}}
+
+	services, err := ecsClient.DescribeServices(&ecs.DescribeServicesInput{Cluster: awsSDK.String(clusterName), Services: []*string{awsSDK.String(serviceName)}})
+	if err != nil {
+		diagnostics.addError(fmt.Errorf("describe service %s: %w", serviceName, err))
+	} else if len(services.Services) != 1 {
+		diagnostics.addError(fmt.Errorf("service %s not found in cluster %s", serviceName, clusterName))
+	} else {
+		service := services.Services[0]
+		diagnostics.Events = service.Events
+		diagnostics.Deployments = service.Deployments
+
+		taskDefinition, err := ecsClient.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{TaskDefinition: service.TaskDefinition})
+		if err != nil {
+			diagnostics.addError(fmt.Errorf("describe task definition of service %s: %w", serviceName, err))
+		} else {
+			diagnostics.TaskDefinition = taskDefinition.TaskDefinition
+		}
+
+		diagnostics.ScalingActivities, err = scalingActivities(ecsClient, autoScalingClient, clusterName, service)
+		diagnostics.addError(err)
+	}
+
+	diagnostics.StoppedTasks, err = stoppedTasks(ecsClient, clusterName, serviceName)
+	diagnostics.addError(err)
+
+	if loadBalancerArn != "" {
+		diagnostics.Listeners, err = describeListeners(elbClient, loadBalancerArn)
+		diagnostics.addError(err)
+		for _, listener := range diagnostics.Listeners {
+			targetGroupArn := forwardTargetGroupArn(listener)
+			if _, ok := diagnostics.TargetHealth[targetGroupArn]; ok || targetGroupArn == "" {
+				continue
+			}
+			health, err := elbClient.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{TargetGroupArn: awsSDK.String(targetGroupArn)})
+			if err != nil {
+				diagnostics.addError(fmt.Errorf("describe target health of %s: %w", targetGroupArn, err))
+				continue
+			}
+			diagnostics.TargetHealth[targetGroupArn] = health.TargetHealthDescriptions
+		}
+	}
+
+	diagnostics.Logs, err = recentLogs(ecsClient, logsClient, clusterName, serviceName)
+	diagnostics.addError(err)
+
+	return diagnostics
+}
+
+func stoppedTasks(client ecsiface.ECSAPI, clusterName, serviceName string) ([]StoppedTask, error) {
+	list, err := client.ListTasks(&ecs.ListTasksInput{
+		Cluster:       awsSDK.String(clusterName),
+		ServiceName:   awsSDK.String(serviceName),
+		DesiredStatus: awsSDK.String(ecs.DesiredStatusStopped),
+		MaxResults:    awsSDK.Int64(diagnosticsMaxTasks),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list stopped tasks of service %s: %w", serviceName, err)
+	}
+	if len(list.TaskArns) == 0 {
+		return nil, nil
+	}
+	tasks, err := client.DescribeTasks(&ecs.DescribeTasksInput{Cluster: awsSDK.String(clusterName), Tasks: list.TaskArns})
+	if err != nil {
+		return nil, fmt.Errorf("describe stopped tasks of service %s: %w", serviceName, err)
+	}
+
+	stopped := []StoppedTask{}
+	for _, task := range tasks.Tasks {
+		stoppedTask := StoppedTask{
+			TaskArn:       awsSDK.StringValue(task.TaskArn),
+			StopCode:      awsSDK.StringValue(task.StopCode),
+			StoppedReason: awsSDK.StringValue(task.StoppedReason),
+			StoppedAt:     task.StoppedAt,
+		}
+		for _, container := range task.Containers {
+			stoppedTask.Containers = append(stoppedTask.Containers, StoppedContainer{
+				Name:     awsSDK.StringValue(container.Name),
+				ExitCode: container.ExitCode,
+				Reason:   awsSDK.StringValue(container.Reason),
+			})
+		}
+		stopped = append(stopped, stoppedTask)
+	}
+	return stopped, nil
+}
+
+// scalingActivities returns the latest activities of the auto scaling groups of the capacity providers of the service, none for fargate
+func scalingActivities(ecsClient ecsiface.ECSAPI, autoScalingClient autoscalingiface.AutoScalingAPI, clusterName string, service *ecs.Service) (map[string][]*autoscaling.Activity, error) {
+	activities := map[string][]*autoscaling.Activity{}
+	clusters, err := ecsClient.DescribeClusters(&ecs.DescribeClustersInput{Clusters: []*string{awsSDK.String(clusterName)}})
+	if err != nil {
+		return activities, fmt.Errorf("describe cluster %s: %w", clusterName, err)
+	}
+	if len(clusters.Clusters) != 1 {
+		return activities, fmt.Errorf("cluster %s not found", clusterName)
+	}
+
+	providerNames := []*string{}
+	for name := range capacityProviderStrategy(clusters.Clusters[0], service) {
+		if name != fargateCapacityProviders[CapacityTypeOnDemand] && name != fargateCapacityProviders[CapacityTypeSpot] {
+			providerNames = append(providerNames, awsSDK.String(name))
+		}
+	}
+	if len(providerNames) == 0 {
+		return activities, nil
+	}
+	providers, err := ecsClient.DescribeCapacityProviders(&ecs.DescribeCapacityProvidersInput{CapacityProviders: providerNames})
+	if err != nil {
+		return activities, fmt.Errorf("describe capacity providers of %s: %w", clusterName, err)
+	}
+
+	problems := []string{}
+	for _, provider := range providers.CapacityProviders {
+		if provider.AutoScalingGroupProvider == nil {
+			continue
+		}
+		groupName := autoScalingGroupName(awsSDK.StringValue(provider.AutoScalingGroupProvider.AutoScalingGroupArn))
+		output, err := autoScalingClient.DescribeScalingActivities(&autoscaling.DescribeScalingActivitiesInput{
+			AutoScalingGroupName: awsSDK.String(groupName),
+			MaxRecords:           awsSDK.Int64(diagnosticsMaxActivities),
+		})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("describe scaling activities of %s: %v", groupName, err))
+			continue
+		}
+		activities[groupName] = output.Activities
+	}
+	if len(problems) > 0 {
+		return activities, fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return activities, nil
+}
+
+// recentLogs returns the latest events of the containers with the awslogs driver, as `<time> <group>:<stream> <message>`
+func recentLogs(ecsClient ecsiface.ECSAPI, logsClient cloudwatchlogsiface.CloudWatchLogsAPI, clusterName, serviceName string) ([]string, error) {
+	logs, err := LogsFromTaskDefinitionE(ecsClient, clusterName, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	startTime := time.Now().Add(-diagnosticsLogsWindow).UnixMilli()
+	lines := []string{}
+	problems := []string{}
+	for _, log := range logs {
+		events := []*cloudwatchlogs.FilteredLogEvent{}
+		err := logsClient.FilterLogEventsPages(&cloudwatchlogs.FilterLogEventsInput{
+			LogGroupName:        awsSDK.String(log.Group),
+			LogStreamNamePrefix: awsSDK.String(log.Stream + "/"),
+			StartTime:           awsSDK.Int64(startTime),
+		}, func(page *cloudwatchlogs.FilterLogEventsOutput, lastPage bool) bool {
+			events = append(events, page.Events...)
+			// keep the latest events only
+			if len(events) > diagnosticsLogsMaxEvents {
+				events = events[len(events)-diagnosticsLogsMaxEvents:]
+			}
+			return true
+		})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("filter log events %s:%s: %v", log.Group, log.Stream, err))
+			continue
+		}
+		for _, event := range events {
+			timestamp := time.UnixMilli(awsSDK.Int64Value(event.Timestamp)).UTC().Format(time.RFC3339)
+			lines = append(lines, fmt.Sprintf("%s %s:%s %s", timestamp, log.Group, awsSDK.StringValue(event.LogStreamName), strings.TrimRight(awsSDK.StringValue(event.Message), "\n")))
+		}
+	}
+	if len(problems) > 0 {
+		return lines, fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	return lines, nil
+}
+
+// SanitizedOutputsFromState returns the values of the outputs of the terraform state,
+// the sensitive outputs and the fields looking like secrets are redacted
+func SanitizedOutputsFromState(microservicePath string) (map[string]any, error) {
+	content, err := os.ReadFile(filepath.Join(microservicePath, "terraform.tfstate"))
+	if err != nil {
+		return nil, fmt.Errorf("read terraform state: %w", err)
+	}
+	var state struct {
+		Outputs map[string]struct {
+			Value     any  `json:"value"`
+			Sensitive bool `json:"sensitive"`
+		} `json:"outputs"`
+	}
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("decode terraform state: %w", err)
+	}
+	outputs := map[string]any{}
+	for name, output := range state.Outputs {
+		if output.Sensitive || sensitiveOutputKeys.MatchString(name) {
+			outputs[name] = "<redacted>"
+			continue
+		}
+		outputs[name] = redact(output.Value)
+	}
+	return outputs, nil
+}
+
+func redact(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		redacted := map[string]any{}
+		for key, field := range value {
+			if sensitiveOutputKeys.MatchString(key) {
+				redacted[key] = "<redacted>"
+				continue
+			}
+			redacted[key] = redact(field)
+		}
+		return redacted
+	case []any:
+		redacted := []any{}
+		for _, field := range value {
+			redacted = append(redacted, redact(field))
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+// loadBalancerArnFromOutputs is the lenient version of LoadBalancerArnFromState, empty when missing
+func loadBalancerArnFromOutputs(outputs map[string]any, modulePath string) string {
+	arn, _ := outputValue(outputs, modulePath, "ecs.elb.lb.arn").(string)
+	return arn
+}
+
+// outputValue is the value at the path of the outputs of the module, nil when missing
+func outputValue(outputs map[string]any, modulePath, path string) any {
+	var value any = outputs
+	for _, field := range strings.Split(util.Format(".", modulePath, path), ".") {
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = fields[field]
+	}
+	return value
+}
+
+// WriteDiagnosticsE writes each part of the diagnostics in a JSON file, the logs and a summary in text files
+func WriteDiagnosticsE(dir string, diagnostics *Diagnostics) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create diagnostics directory: %w", err)
+	}
+	files := map[string]any{
+		"service.json":         map[string]any{"events": diagnostics.Events, "deployments": diagnostics.Deployments},
+		"stopped_tasks.json":   diagnostics.StoppedTasks,
+		"task_definition.json": diagnostics.TaskDefinition,
+		"load_balancer.json":   map[string]any{"listeners": diagnostics.Listeners, "target_health": diagnostics.TargetHealth},
+		"auto_scaling.json":    diagnostics.ScalingActivities,
+		"outputs.json":         diagnostics.Outputs,
+	}
+	for name, content := range files {
+		data, err := json.MarshalIndent(content, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encode %s: %w", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "logs.txt"), []byte(strings.Join(diagnostics.Logs, "\n")+"\n"), 0o644); err != nil {
+		return fmt.Errorf("write logs.txt: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "summary.txt"), []byte(diagnostics.summary()), 0o644); err != nil {
+		return fmt.Errorf("write summary.txt: %w", err)
+	}
+	return nil
+}
+
+// summary is the short version of the diagnostics to read first
+func (d *Diagnostics) summary() string {
+	lines := []string{"service events:"}
+	for i, event := range d.Events {
+		if i == 10 {
+			break
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s", awsSDK.TimeValue(event.CreatedAt).UTC().Format(time.RFC3339), awsSDK.StringValue(event.Message)))
+	}
+
+	lines = append(lines, "deployments:")
+	for _, deployment := range d.Deployments {
+		lines = append(lines, fmt.Sprintf("  %s %s rollout %s, running %d, pending %d, failed %d, desired %d", awsSDK.StringValue(deployment.Id), awsSDK.StringValue(deployment.Status), awsSDK.StringValue(deployment.RolloutState), awsSDK.Int64Value(deployment.RunningCount), awsSDK.Int64Value(deployment.PendingCount), awsSDK.Int64Value(deployment.FailedTasks), awsSDK.Int64Value(deployment.DesiredCount)))
+	}
+
+	lines = append(lines, "stopped tasks:")
+	for _, task := range d.StoppedTasks {
+		lines = append(lines, fmt.Sprintf("  %s %s: %s", task.TaskArn, task.StopCode, task.StoppedReason))
+		for _, container := range task.Containers {
+			exitCode := "none"
+			if container.ExitCode != nil {
+				exitCode = fmt.Sprint(*container.ExitCode)
+			}
+			lines = append(lines, fmt.Sprintf("    container %s exit code %s: %s", container.Name, exitCode, container.Reason))
+		}
+	}
+
+	lines = append(lines, "targets:")
+	targetGroupArns := []string{}
+	for targetGroupArn := range d.TargetHealth {
+		targetGroupArns = append(targetGroupArns, targetGroupArn)
+	}
+	sort.Strings(targetGroupArns)
+	for _, targetGroupArn := range targetGroupArns {
+		for _, description := range d.TargetHealth[targetGroupArn] {
+			lines = append(lines, fmt.Sprintf("  %s %s:%d %s %s", targetGroupArn, awsSDK.StringValue(description.Target.Id), awsSDK.Int64Value(description.Target.Port), awsSDK.StringValue(description.TargetHealth.State), awsSDK.StringValue(description.TargetHealth.Reason)))
+		}
+	}
+
+	lines = append(lines, "collection errors:")
+	for _, err := range d.Errors {
+		lines = append(lines, "  "+strings.ReplaceAll(err, "\n", "\n  "))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
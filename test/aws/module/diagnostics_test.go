@@ -0,0 +1,168 @@
+package module
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+// a rest service whose task crashed and whose target is unhealthy
+func setupFakeDiagnostics() (*fakeEcs, *fakeElbv2, *fakeAutoScaling, *fakeCloudWatchLogs) {
+	ecsClient, autoScalingClient, _ := setupFakeAutoScaling()
+	logsClient := newFakeCloudWatchLogs()
+	service := ecsClient.services[fakeClusterName+"/"+fakeServiceName]
+	service.Events = []*ecs.ServiceEvent{{CreatedAt: awsSDK.Time(time.Unix(0, 0)), Message: awsSDK.String("(service vi-rest-test-unique) has started 1 tasks")}}
+	service.Deployments = []*ecs.Deployment{{Id: awsSDK.String("ecs-svc/1"), Status: awsSDK.String("PRIMARY"), RolloutState: awsSDK.String(ecs.DeploymentRolloutStateInProgress), DesiredCount: awsSDK.Int64(1), FailedTasks: awsSDK.Int64(1)}}
+	ecsClient.putTask(fakeClusterName, fakeServiceName, &ecs.Task{
+		TaskArn:       awsSDK.String(fakeTaskArn),
+		DesiredStatus: awsSDK.String(ecs.DesiredStatusStopped),
+		StopCode:      awsSDK.String(ecs.TaskStopCodeEssentialContainerExited),
+		StoppedReason: awsSDK.String("Essential container in task exited"),
+		Containers:    []*ecs.Container{{Name: awsSDK.String("unique"), ExitCode: awsSDK.Int64(137), Reason: awsSDK.String("OutOfMemoryError: Container killed due to memory usage")}},
+	})
+	ecsClient.putTask(fakeClusterName, fakeServiceName, &ecs.Task{TaskArn: awsSDK.String(fakeTaskArn + "1"), DesiredStatus: awsSDK.String(ecs.DesiredStatusRunning)})
+
+	autoScalingClient.activities["vi-rest-test-ON-t3-s"] = []*autoscaling.Activity{{StatusCode: awsSDK.String(autoscaling.ScalingActivityStatusCodeSuccessful), Description: awsSDK.String("Launching a new EC2 instance: i-0")}}
+
+	elbClient := setupFakeElbv2()
+	elbClient.setTarget(fakeTgArn, 0, elbv2.TargetHealthStateEnumUnhealthy, elbv2.TargetHealthReasonEnumTargetResponseCodeMismatch)
+
+	logsClient.putEvent(fakeLogGroup, fakeLogStream, time.Now(), "apt update\n")
+	logsClient.putEvent(fakeLogGroup, fakeLogStream, time.Now().Add(-time.Hour), "previous deployment")
+	return ecsClient, elbClient, autoScalingClient, logsClient
+}
+
+func Test_Unit_CollectDiagnostics(t *testing.T) {
+	ecsClient, elbClient, autoScalingClient, logsClient := setupFakeDiagnostics()
+	diagnostics := CollectDiagnosticsE(ecsClient, elbClient, autoScalingClient, logsClient, fakeClusterName, fakeServiceName, fakeLbArn)
+	if len(diagnostics.Errors) > 0 {
+		t.Fatal(diagnostics.Errors)
+	}
+
+	util.Equal(t, 1, len(diagnostics.Events))
+	util.Equal(t, 1, len(diagnostics.Deployments))
+	util.Equal(t, fakeTaskDefinitionArn, awsSDK.StringValue(diagnostics.TaskDefinition.TaskDefinitionArn))
+	expectedTasks := []StoppedTask{{
+		TaskArn:       fakeTaskArn,
+		StopCode:      ecs.TaskStopCodeEssentialContainerExited,
+		StoppedReason: "Essential container in task exited",
+		Containers:    []StoppedContainer{{Name: "unique", ExitCode: awsSDK.Int64(137), Reason: "OutOfMemoryError: Container killed due to memory usage"}},
+	}}
+	if !reflect.DeepEqual(expectedTasks, diagnostics.StoppedTasks) {
+		t.Errorf("expected stopped tasks %+v, got %+v", expectedTasks, diagnostics.StoppedTasks)
+	}
+	util.Equal(t, 2, len(diagnostics.Listeners))
+	// the target group shared by the listeners is described once
+	util.Equal(t, 1, elbClient.healthCalls)
+	util.Equal(t, elbv2.TargetHealthStateEnumUnhealthy, awsSDK.StringValue(diagnostics.TargetHealth[fakeTgArn][0].TargetHealth.State))
+	util.Equal(t, 1, len(diagnostics.ScalingActivities["vi-rest-test-ON-t3-s"]))
+	util.Equal(t, 1, len(diagnostics.Logs))
+	if !strings.HasSuffix(diagnostics.Logs[0], fakeLogGroup+":"+fakeLogStream+" apt update") {
+		t.Errorf("unexpected log line %q", diagnostics.Logs[0])
+	}
+}
+
+func Test_Unit_CollectDiagnostics_Errors(t *testing.T) {
+	ecsClient, elbClient, autoScalingClient, logsClient := setupFakeDiagnostics()
+	delete(ecsClient.taskDefinitions, fakeTaskDefinitionArn)
+	delete(autoScalingClient.groups, "vi-rest-test-ON-t3-s")
+
+	// the other parts are still collected
+	diagnostics := CollectDiagnosticsE(ecsClient, elbClient, autoScalingClient, logsClient, fakeClusterName, fakeServiceName, fakeLbArn+"0")
+	errs := strings.Join(diagnostics.Errors, "\n")
+	for _, errMsg := range []string{
+		"describe task definition of service vi-rest-test-unique",
+		"describe scaling activities of vi-rest-test-ON-t3-s",
+		"describe listeners of " + fakeLbArn + "0",
+	} {
+		if !strings.Contains(errs, errMsg) {
+			t.Errorf("expected error containing %q, got %v", errMsg, errs)
+		}
+	}
+	util.Equal(t, 1, len(diagnostics.Events))
+	util.Equal(t, 1, len(diagnostics.StoppedTasks))
+}
+
+func Test_Unit_WriteDiagnostics(t *testing.T) {
+	ecsClient, elbClient, autoScalingClient, logsClient := setupFakeDiagnostics()
+	diagnostics := CollectDiagnosticsE(ecsClient, elbClient, autoScalingClient, logsClient, fakeClusterName, fakeServiceName, fakeLbArn)
+	diagnostics.Outputs = map[string]any{"ecs": map[string]any{"service": fakeServiceName}}
+	diagnostics.addError(os.ErrNotExist)
+
+	dir := filepath.Join(t.TempDir(), "Test_Unit_Microservice_Rest_ECS_EC2_Httpd_trunk")
+	if err := WriteDiagnosticsE(dir, diagnostics); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"service.json", "stopped_tasks.json", "task_definition.json", "load_balancer.json", "auto_scaling.json", "outputs.json"} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !json.Valid(content) {
+			t.Errorf("%s is not valid JSON", name)
+		}
+	}
+
+	summary, err := os.ReadFile(filepath.Join(dir, "summary.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"1970-01-01T00:00:00Z (service vi-rest-test-unique) has started 1 tasks",
+		"ecs-svc/1 PRIMARY rollout IN_PROGRESS, running 0, pending 0, failed 1, desired 1",
+		"container unique exit code 137: OutOfMemoryError",
+		fakeTgArn + " i-0:80 unhealthy Target.ResponseCodeMismatch",
+		"file does not exist",
+	} {
+		if !strings.Contains(string(summary), line) {
+			t.Errorf("expected summary containing %q, got:\n%s", line, summary)
+		}
+	}
+	logs, err := os.ReadFile(filepath.Join(dir, "logs.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, 1, strings.Count(string(logs), "\n"))
+}
+
+func Test_Unit_SanitizedOutputsFromState(t *testing.T) {
+	dir := t.TempDir()
+	state := `{
+		"outputs": {
+			"microservice": {"value": {"ecs": {"elb": {"lb": {"arn": "` + fakeLbArn + `"}}}, "iam": {"access_key": "AKIA", "users": [{"name": "a", "secret": "b"}]}}},
+			"db_password": {"value": "plain"},
+			"api": {"value": "token", "sensitive": true}
+		}
+	}`
+	if err := os.WriteFile(filepath.Join(dir, "terraform.tfstate"), []byte(state), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outputs, err := SanitizedOutputsFromState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]any{
+		"microservice": map[string]any{
+			"ecs": map[string]any{"elb": map[string]any{"lb": map[string]any{"arn": fakeLbArn}}},
+			"iam": map[string]any{"access_key": "<redacted>", "users": []any{map[string]any{"name": "a", "secret": "<redacted>"}}},
+		},
+		"db_password": "<redacted>",
+		"api":         "<redacted>",
+	}
+	if !reflect.DeepEqual(expected, outputs) {
+		t.Fatalf("expected %+v, got %+v", expected, outputs)
+	}
+	util.Equal(t, fakeLbArn, loadBalancerArnFromOutputs(outputs, "microservice"))
+	util.Equal(t, "", loadBalancerArnFromOutputs(outputs, ""))
+}
@@ -0,0 +1,177 @@
+package module
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+// DynamodbTableTest is a table of the `dynamodb_tables` variable with the name created by terraform
+type DynamodbTableTest struct {
+	TableName           string
+	PrimaryKeyName      string
+	PrimaryKeyType      string
+	SortKeyName         string
+	SortKeyType         string
+	PredictableWorkload bool
+}
+
+// DynamodbTablesFromState matches the `dynamodb_tables` variable with the `dynamodb_tables` output
+func DynamodbTablesFromState(t *testing.T, microservicePath string, dynamodbTables []map[string]any) []DynamodbTableTest {
+	outputs, ok := ExtractFromState(t, microservicePath, "dynamodb_tables").(map[string]any)
+	if !ok {
+		t.Fatalf("no dynamodb_tables in the state")
+	}
+
+	tables := []DynamodbTableTest{}
+	for _, dynamodbTable := range dynamodbTables {
+		name, _ := dynamodbTable["name"].(string)
+		output, ok := outputs[name].(map[string]any)
+		if !ok {
+			t.Fatalf("no table %s in the dynamodb_tables output: %+v", name, outputs)
+		}
+		tableName, _ := output["dynamodb"].(map[string]any)["table_id"].(string)
+		if tableName == "" {
+			t.Fatalf("no table_id for table %s in the dynamodb_tables output", name)
+		}
+
+		table := DynamodbTableTest{TableName: tableName}
+		table.PrimaryKeyName, _ = dynamodbTable["primary_key_name"].(string)
+		table.PrimaryKeyType, _ = dynamodbTable["primary_key_type"].(string)
+		table.SortKeyName, _ = dynamodbTable["sort_key_name"].(string)
+		table.SortKeyType, _ = dynamodbTable["sort_key_type"].(string)
+		table.PredictableWorkload, _ = dynamodbTable["predictable_workload"].(bool)
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+func (c *Client) ValidateDynamodbTables(t *testing.T, tables []DynamodbTableTest) {
+	util.RunTestStage(t, "validate_dynamodb", func() {
+		client := dynamodb.New(c.Session(t))
+		for _, table := range tables {
+			c.options.Logger.Logf(t, "dynamodb table :: %+v", table)
+			util.ReportResource(t, "dynamodb_table", table.TableName)
+			if err := ValidateDynamodbTableE(client, table); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+// ValidateDynamodbTableE checks the key schema, the attribute types and the billing mode, then puts, gets and deletes an item
+func ValidateDynamodbTableE(client dynamodbiface.DynamoDBAPI, table DynamodbTableTest) error {
+	output, err := client.DescribeTable(&dynamodb.DescribeTableInput{TableName: awsSDK.String(table.TableName)})
+	if err != nil {
+		return fmt.Errorf("describe table %s: %w", table.TableName, err)
+	}
+	description := output.Table
+
+	// keys
+	keyNames := map[string]string{}
+	for _, key := range description.KeySchema {
+		keyNames[awsSDK.StringValue(key.KeyType)] = awsSDK.StringValue(key.AttributeName)
+	}
+	expectedKeyNames := map[string]string{
+		dynamodb.KeyTypeHash:  table.PrimaryKeyName,
+		dynamodb.KeyTypeRange: table.SortKeyName,
+	}
+	if !reflect.DeepEqual(expectedKeyNames, keyNames) {
+		return fmt.Errorf("table %s key schema %v, expected %v", table.TableName, keyNames, expectedKeyNames)
+	}
+	attributeTypes := map[string]string{}
+	for _, attribute := range description.AttributeDefinitions {
+		attributeTypes[awsSDK.StringValue(attribute.AttributeName)] = awsSDK.StringValue(attribute.AttributeType)
+	}
+	for name, expectedType := range map[string]string{table.PrimaryKeyName: table.PrimaryKeyType, table.SortKeyName: table.SortKeyType} {
+		if attributeTypes[name] != expectedType {
+			return fmt.Errorf("table %s attribute %s of type %q, expected %q", table.TableName, name, attributeTypes[name], expectedType)
+		}
+	}
+
+	// billing mode, the summary is missing for tables always provisioned
+	billingMode := dynamodb.BillingModeProvisioned
+	if description.BillingModeSummary != nil && description.BillingModeSummary.BillingMode != nil {
+		billingMode = awsSDK.StringValue(description.BillingModeSummary.BillingMode)
+	}
+	expectedBillingMode := dynamodb.BillingModePayPerRequest
+	if table.PredictableWorkload {
+		expectedBillingMode = dynamodb.BillingModeProvisioned
+	}
+	if billingMode != expectedBillingMode {
+		return fmt.Errorf("table %s billing mode %s, expected %s with predictable_workload %t", table.TableName, billingMode, expectedBillingMode, table.PredictableWorkload)
+	}
+
+	return roundTripDynamodbItem(client, table)
+}
+
+func roundTripDynamodbItem(client dynamodbiface.DynamoDBAPI, table DynamodbTableTest) error {
+	id := util.RandomID(8)
+	key := map[string]*dynamodb.AttributeValue{}
+	for name, keyType := range map[string]string{table.PrimaryKeyName: table.PrimaryKeyType, table.SortKeyName: table.SortKeyType} {
+		value, err := dynamodbKeyValue(keyType, id)
+		if err != nil {
+			return fmt.Errorf("table %s key %s: %w", table.TableName, name, err)
+		}
+		key[name] = value
+	}
+	item := map[string]*dynamodb.AttributeValue{"TerratestValue": {S: awsSDK.String(id)}}
+	for name, value := range key {
+		item[name] = value
+	}
+
+	if _, err := client.PutItem(&dynamodb.PutItemInput{TableName: awsSDK.String(table.TableName), Item: item}); err != nil {
+		return fmt.Errorf("put item in table %s: %w", table.TableName, err)
+	}
+	got, err := client.GetItem(&dynamodb.GetItemInput{TableName: awsSDK.String(table.TableName), Key: key, ConsistentRead: awsSDK.Bool(true)})
+	if err != nil {
+		return fmt.Errorf("get item from table %s: %w", table.TableName, err)
+	}
+	if !reflect.DeepEqual(item, got.Item) {
+		return fmt.Errorf("table %s returned item %v, expected %v", table.TableName, got.Item, item)
+	}
+	if _, err := client.DeleteItem(&dynamodb.DeleteItemInput{TableName: awsSDK.String(table.TableName), Key: key}); err != nil {
+		return fmt.Errorf("delete item from table %s: %w", table.TableName, err)
+	}
+	got, err = client.GetItem(&dynamodb.GetItemInput{TableName: awsSDK.String(table.TableName), Key: key, ConsistentRead: awsSDK.Bool(true)})
+	if err != nil {
+		return fmt.Errorf("get deleted item from table %s: %w", table.TableName, err)
+	}
+	if len(got.Item) != 0 {
+		keys := []string{}
+		for name := range got.Item {
+			keys = append(keys, name)
+		}
+		sort.Strings(keys)
+		return fmt.Errorf("table %s still has the deleted item with attributes %v", table.TableName, keys)
+	}
+	return nil
+}
+
+func dynamodbKeyValue(keyType, id string) (*dynamodb.AttributeValue, error) {
+	switch keyType {
+	case dynamodb.ScalarAttributeTypeS:
+		return &dynamodb.AttributeValue{S: awsSDK.String("terratest-" + id)}, nil
+	case dynamodb.ScalarAttributeTypeN:
+		number := int64(0)
+		for _, char := range id {
+			number = number*31 + int64(char)
+		}
+		if number < 0 {
+			number = -number
+		}
+		return &dynamodb.AttributeValue{N: awsSDK.String(strconv.FormatInt(number, 10))}, nil
+	case dynamodb.ScalarAttributeTypeB:
+		return &dynamodb.AttributeValue{B: []byte("terratest-" + id)}, nil
+	default:
+		return nil, fmt.Errorf("unknown key type %q", keyType)
+	}
+}
@@ -0,0 +1,78 @@
+package module
+
+import (
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func Test_Unit_ValidateDynamodbTable(t *testing.T) {
+	client := newFakeDynamodb()
+	tables := []DynamodbTableTest{
+		{TableName: "vi-sp-test-picture-process", PrimaryKeyName: "Origin", PrimaryKeyType: "S", SortKeyName: "ID", SortKeyType: "S"},
+		{TableName: "vi-sp-test-picture-validation", PrimaryKeyName: "Origin", PrimaryKeyType: "S", SortKeyName: "CreationDate", SortKeyType: "N", PredictableWorkload: true},
+		{TableName: "vi-sp-test-picture-blocked", PrimaryKeyName: "Hash", PrimaryKeyType: "B", SortKeyName: "ID", SortKeyType: "N"},
+	}
+	for _, table := range tables {
+		client.createTable(table)
+		if err := ValidateDynamodbTableE(client, table); err != nil {
+			t.Fatal(err)
+		}
+		if items := len(client.items[table.TableName]); items != 0 {
+			t.Errorf("table %s kept %d items after the round trip", table.TableName, items)
+		}
+	}
+}
+
+func Test_Unit_ValidateDynamodbTable_Errors(t *testing.T) {
+	expected := DynamodbTableTest{TableName: "vi-sp-test-picture-process", PrimaryKeyName: "Origin", PrimaryKeyType: "S", SortKeyName: "ID", SortKeyType: "S"}
+	testCases := []struct {
+		name   string
+		setup  func(client *fakeDynamodb)
+		errMsg string
+	}{
+		{
+			name:   "missing table",
+			setup:  func(client *fakeDynamodb) {},
+			errMsg: "describe table",
+		},
+		{
+			name: "key schema",
+			setup: func(client *fakeDynamodb) {
+				table := expected
+				table.SortKeyName = "CreationDate"
+				client.createTable(table)
+			},
+			errMsg: "key schema",
+		},
+		{
+			name: "attribute type",
+			setup: func(client *fakeDynamodb) {
+				table := expected
+				table.SortKeyType = "N"
+				client.createTable(table)
+			},
+			errMsg: `attribute ID of type "N", expected "S"`,
+		},
+		{
+			name: "billing mode",
+			setup: func(client *fakeDynamodb) {
+				client.createTable(expected).BillingModeSummary = &dynamodb.BillingModeSummary{BillingMode: awsSDK.String(dynamodb.BillingModeProvisioned)}
+			},
+			errMsg: "billing mode PROVISIONED, expected PAY_PER_REQUEST",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			client := newFakeDynamodb()
+			testCase.setup(client)
+			err := ValidateDynamodbTableE(client, expected)
+			if err == nil || !strings.Contains(err.Error(), testCase.errMsg) {
+				t.Fatalf("expected error containing %q, got %v", testCase.errMsg, err)
+			}
+		})
+	}
+}
@@ -2,33 +2,27 @@ package module
 
 import (
 	"fmt"
-	"strconv"
-	"strings"
 	"testing"
 
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecr"
 	"github.com/likexian/gokit/assert"
 
-	terratestShell "github.com/gruntwork-io/terratest/modules/shell"
-	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/vistimi/infrastructure-modules/test/util"
 )
 
-func TestEcr(t *testing.T, accountRegion, organization, repository, branch string) {
-	terratestStructure.RunTestStage(t, "validate_ecr", func() {
-
-		bashCode := fmt.Sprintf(`aws ecr list-images --repository-name %s --region %s --output text --query "imageIds[].[imageTag]" | wc -l`,
-			strings.ToLower(fmt.Sprintf("%s-%s-%s", organization, repository, branch)),
-			accountRegion,
-		)
-		command := terratestShell.Command{
-			Command: "bash",
-			Args:    []string{"-c", bashCode},
-		}
-		output := strings.TrimSpace(terratestShell.RunCommandAndGetOutput(t, command))
-		ecrImagesAmount, err := strconv.Atoi(output)
+// TestEcr checks that the workflow pushed an image in the repository of the branch, the extension is the one of the Dockerfile
+func (c *Client) TestEcr(t *testing.T, repository, branch, extension string) {
+	util.RunTestStage(t, "validate_ecr", func() {
+		repositoryName := EcrRepositoryName(repository, branch, extension)
+		output, err := ecr.New(c.Session(t)).ListImages(&ecr.ListImagesInput{
+			RepositoryName: awsSDK.String(repositoryName),
+			Filter:         &ecr.ListImagesFilter{TagStatus: awsSDK.String(ecr.TagStatusTagged)},
+		})
 		if err != nil {
-			t.Fatalf("String to int conversion failed: %s", output)
+			t.Fatalf("list images of %s: %v", repositoryName, err)
 		}
-
+		ecrImagesAmount := len(output.ImageIds)
 		assert.Equal(t, 1, ecrImagesAmount, fmt.Sprintf("No image published to repository: %v", ecrImagesAmount))
 	})
 }
@@ -6,73 +6,97 @@ import (
 	"time"
 
 	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
 	"github.com/likexian/gokit/assert"
 
-	terratestAws "github.com/gruntwork-io/terratest/modules/aws"
-	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
-	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/vistimi/infrastructure-modules/test/util"
 )
 
 // https://github.com/gruntwork-io/terratest/blob/master/test/terraform_aws_ecs_example_test.go
-func ValidateEcs(t *testing.T, accountRegion, clusterName, serviceName string, serviceCount int64, deploymentTest DeploymentTest) {
-	terratestStructure.RunTestStage(t, "validate_ecs", func() {
+func (c *Client) ValidateEcs(t *testing.T, clusterName, serviceName string, serviceCount int64, deploymentTest DeploymentTest) {
+	util.RunTestStage(t, "validate_ecs", func() {
+		client := ecs.New(c.Session(t))
 
 		// cluster
-		cluster := terratestAws.GetEcsCluster(t, accountRegion, clusterName)
-		if cluster == nil {
-			t.Fatalf("no service")
+		cluster, err := describeCluster(client, clusterName)
+		if err != nil {
+			t.Fatal(err)
 		}
 		assert.Equal(t, awsSDK.Int64Value(cluster.ActiveServicesCount), serviceCount, "amount of services do not match")
 
 		// tasks in service
-		service := terratestAws.GetEcsService(t, accountRegion, clusterName, serviceName)
-		if service == nil {
-			t.Fatalf("no service")
+		service, err := describeService(client, clusterName, serviceName)
+		if err != nil {
+			t.Fatal(err)
 		}
 		serviceTaskDesiredCount := awsSDK.Int64Value(service.DesiredCount)
 		assert.NotEqual(t, int64(0), serviceTaskDesiredCount, "amount of tasks in service do not match")
 
-		taskDefinition := terratestAws.GetEcsTaskDefinition(t, accountRegion, awsSDK.StringValue(service.TaskDefinition))
-		if taskDefinition == nil {
-			t.Fatalf("no task definition")
+		taskDefinition, err := client.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{TaskDefinition: service.TaskDefinition})
+		if err != nil {
+			t.Fatalf("describe task definition of service %s: %v", serviceName, err)
 		}
-		latestTaskDefinitionArn := taskDefinition.TaskDefinitionArn
+		latestTaskDefinitionArn := taskDefinition.TaskDefinition.TaskDefinitionArn
 		if latestTaskDefinitionArn == nil {
 			t.Fatalf("no task definition arn")
 		}
-		fmt.Printf("\n\nlatestTaskDefinitionArn = %s\n\n", *latestTaskDefinitionArn)
+		c.options.Logger.Logf(t, "latestTaskDefinitionArn = %s", *latestTaskDefinitionArn)
+		util.ReportResource(t, "ecs_cluster", clusterName)
+		util.ReportResource(t, "ecs_service", serviceName)
+		util.ReportResource(t, "ecs_task_definition", *latestTaskDefinitionArn)
 
-		if len(service.Deployments) == 0 {
-			t.Fatalf("no service deployment")
-		}
 		deployment := service.Deployments[0] // one deployment because no other service update, take the last one otherwise
 		assert.Equal(t, awsSDK.Int64Value(deployment.DesiredCount), serviceTaskDesiredCount, "amount of desired tasks in service do not match")
 
-		maxRetries := 5
-		if deploymentTest.MaxRetries != nil {
-			maxRetries = *deploymentTest.MaxRetries
-		}
-		sleepBetweenRetries := 30 * time.Second
-		if deploymentTest.SleepBetweenRetries != nil {
-			sleepBetweenRetries = *deploymentTest.SleepBetweenRetries
-		}
-		for i := 0; i <= maxRetries; i++ {
-			deployment := terratestAws.GetEcsService(t, accountRegion, clusterName, serviceName).Deployments[0]
-			terratestLogger.Log(t, fmt.Sprintf(`
+		poll := c.poll(deploymentTest.MaxRetries, deploymentTest.SleepBetweenRetries)
+		for i := 0; i <= poll.MaxRetries; i++ {
+			service, err := describeService(client, clusterName, serviceName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			deployment := service.Deployments[0]
+			c.options.Logger.Logf(t, `
 		tasks FAILURE:: %d
 		tasks RUNNING:: %d
 		tasks PENDING:: %d
 		tasks DESIRED:: %d
-		`, awsSDK.Int64Value(deployment.FailedTasks), awsSDK.Int64Value(deployment.RunningCount), awsSDK.Int64Value(deployment.PendingCount), serviceTaskDesiredCount))
+		`, awsSDK.Int64Value(deployment.FailedTasks), awsSDK.Int64Value(deployment.RunningCount), awsSDK.Int64Value(deployment.PendingCount), serviceTaskDesiredCount)
 			if awsSDK.Int64Value(deployment.RunningCount) == serviceTaskDesiredCount {
-				terratestLogger.Log(t, `'Task deployment successful`)
+				c.options.Logger.Logf(t, `'Task deployment successful`)
 				break
 			}
-			if i == maxRetries {
-				t.Fatalf(`Task deployment unsuccessful after %d retries`, maxRetries)
+			if i == poll.MaxRetries {
+				t.Fatalf(`Task deployment unsuccessful after %d retries`, poll.MaxRetries)
 			}
-			terratestLogger.Log(t, fmt.Sprintf("Sleeping %s...", sleepBetweenRetries))
-			time.Sleep(sleepBetweenRetries)
+			c.options.Logger.Logf(t, "Sleeping %s...", poll.SleepBetweenRetries)
+			util.ReportRetry(t)
+			time.Sleep(poll.SleepBetweenRetries)
 		}
 	})
 }
+
+func describeCluster(client ecsiface.ECSAPI, clusterName string) (*ecs.Cluster, error) {
+	clusters, err := client.DescribeClusters(&ecs.DescribeClustersInput{Clusters: []*string{awsSDK.String(clusterName)}})
+	if err != nil {
+		return nil, fmt.Errorf("describe cluster %s: %w", clusterName, err)
+	}
+	if len(clusters.Clusters) != 1 {
+		return nil, fmt.Errorf("cluster %s not found", clusterName)
+	}
+	return clusters.Clusters[0], nil
+}
+
+func describeService(client ecsiface.ECSAPI, clusterName, serviceName string) (*ecs.Service, error) {
+	services, err := client.DescribeServices(&ecs.DescribeServicesInput{Cluster: awsSDK.String(clusterName), Services: []*string{awsSDK.String(serviceName)}})
+	if err != nil {
+		return nil, fmt.Errorf("describe service %s: %w", serviceName, err)
+	}
+	if len(services.Services) != 1 {
+		return nil, fmt.Errorf("service %s not found in cluster %s", serviceName, clusterName)
+	}
+	if len(services.Services[0].Deployments) == 0 {
+		return nil, fmt.Errorf("no deployment for service %s", serviceName)
+	}
+	return services.Services[0], nil
+}
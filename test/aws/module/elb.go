@@ -0,0 +1,306 @@
+package module
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	terratestTesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+var (
+	// defaults of `local.traffics` in modules/aws/container/ecs
+	defaultListenerPorts = map[string]int{
+		"http":  80,
+		"https": 443,
+		"grpc":  443,
+	}
+	defaultProtocolVersions = map[string]string{
+		"http":  "http1",
+		"https": "http1",
+		"grpc":  "http2",
+	}
+
+	// unhealthyReasonsFailFast are the reasons of unhealthy targets that waiting does not fix, e.g. a wrong health check path, port or matcher
+	unhealthyReasonsFailFast = map[string]bool{
+		elbv2.TargetHealthReasonEnumTargetResponseCodeMismatch: true,
+		elbv2.TargetHealthReasonEnumTargetTimeout:              true,
+	}
+)
+
+func LoadBalancerArnFromState(t *testing.T, microservicePath, modulePath string) string {
+	elb, ok := ExtractFromState(t, microservicePath, util.Format(".", modulePath, "ecs.elb")).(map[string]any)
+	if !ok {
+		t.Fatalf("no elb in the state")
+	}
+	lb, _ := elb["lb"].(map[string]any)
+	arn, _ := lb["arn"].(string)
+	if arn == "" {
+		t.Fatalf("no arn for the elb in the state: %+v", lb)
+	}
+	return arn
+}
+
+func (c *Client) ValidateLoadBalancer(t *testing.T, loadBalancerArn string, traffics []Traffic, healthCheckPath string) {
+	util.RunTestStage(t, "validate_load_balancer", func() {
+		c.options.Logger.Logf(t, "load balancer :: %s", loadBalancerArn)
+		util.ReportResource(t, "load_balancer", loadBalancerArn)
+		if err := ValidateLoadBalancerE(elbv2.New(c.Session(t)), loadBalancerArn, traffics, healthCheckPath); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// ValidateLoadBalancerE checks for each traffic the listener, the target group it forwards to and the health of its targets
+func ValidateLoadBalancerE(client elbv2iface.ELBV2API, loadBalancerArn string, traffics []Traffic, healthCheckPath string) error {
+	listeners, err := describeListeners(client, loadBalancerArn)
+	if err != nil {
+		return err
+	}
+
+	problems := []string{}
+	healthChecked := map[string]bool{}
+	for i, traffic := range traffics {
+		prefix := fmt.Sprintf("traffic[%d] %s:%d -> %s:%d", i, traffic.Listener.Protocol, listenerPort(traffic), traffic.Target.Protocol, util.Value(traffic.Target.Port))
+		targetGroupArn, err := validateListener(listeners, traffic)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", prefix, err))
+			continue
+		}
+		if err := validateTargetGroup(client, targetGroupArn, traffic, healthCheckPath); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", prefix, err))
+			continue
+		}
+		if healthChecked[targetGroupArn] {
+			continue
+		}
+		healthChecked[targetGroupArn] = true
+		if err := validateTargetsHealthy(client, targetGroupArn); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", prefix, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("load balancer %s validation failed:\n%s", loadBalancerArn, strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+func describeListeners(client elbv2iface.ELBV2API, loadBalancerArn string) ([]*elbv2.Listener, error) {
+	listeners := []*elbv2.Listener{}
+	err := client.DescribeListenersPages(&elbv2.DescribeListenersInput{LoadBalancerArn: awsSDK.String(loadBalancerArn)}, func(page *elbv2.DescribeListenersOutput, lastPage bool) bool {
+		listeners = append(listeners, page.Listeners...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describe listeners of %s: %w", loadBalancerArn, err)
+	}
+	return listeners, nil
+}
+
+func listenerPort(traffic Traffic) int {
+	return util.Value(traffic.Listener.Port, defaultListenerPorts[traffic.Listener.Protocol])
+}
+
+// validateListener returns the target group of the forward action
+func forwardTargetGroupArn(listener *elbv2.Listener) string {
+	for _, action := range listener.DefaultActions {
+		if awsSDK.StringValue(action.Type) != elbv2.ActionTypeEnumForward {
+			continue
+		}
+		if action.TargetGroupArn != nil {
+			return awsSDK.StringValue(action.TargetGroupArn)
+		}
+		if action.ForwardConfig != nil && len(action.ForwardConfig.TargetGroups) > 0 {
+			return awsSDK.StringValue(action.ForwardConfig.TargetGroups[0].TargetGroupArn)
+		}
+	}
+	return ""
+}
+
+func validateListener(listeners []*elbv2.Listener, traffic Traffic) (string, error) {
+	port := listenerPort(traffic)
+	for _, listener := range listeners {
+		if awsSDK.Int64Value(listener.Port) != int64(port) {
+			continue
+		}
+		if protocol := awsSDK.StringValue(listener.Protocol); protocol != strings.ToUpper(traffic.Listener.Protocol) {
+			return "", fmt.Errorf("listener port %d protocol %s, expected %s", port, protocol, strings.ToUpper(traffic.Listener.Protocol))
+		}
+		if targetGroupArn := forwardTargetGroupArn(listener); targetGroupArn != "" {
+			return targetGroupArn, nil
+		}
+		return "", fmt.Errorf("listener port %d does not forward to a target group", port)
+	}
+	return "", fmt.Errorf("no listener on port %d", port)
+}
+
+func validateTargetGroup(client elbv2iface.ELBV2API, targetGroupArn string, traffic Traffic, healthCheckPath string) error {
+	output, err := client.DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{TargetGroupArns: []*string{awsSDK.String(targetGroupArn)}})
+	if err != nil {
+		return fmt.Errorf("describe target group %s: %w", targetGroupArn, err)
+	}
+	if len(output.TargetGroups) != 1 {
+		return fmt.Errorf("target group %s not found", targetGroupArn)
+	}
+	targetGroup := output.TargetGroups[0]
+	name := awsSDK.StringValue(targetGroup.TargetGroupName)
+
+	mismatches := []string{}
+	mismatch := func(field string, actual, expected any) {
+		if fmt.Sprint(actual) != fmt.Sprint(expected) {
+			mismatches = append(mismatches, fmt.Sprintf("%s %v, expected %v", field, actual, expected))
+		}
+	}
+	mismatch("port", awsSDK.Int64Value(targetGroup.Port), util.Value(traffic.Target.Port))
+	mismatch("protocol", awsSDK.StringValue(targetGroup.Protocol), strings.ToUpper(traffic.Target.Protocol))
+	protocolVersion := util.Value(traffic.Target.ProtocolVersion, defaultProtocolVersions[traffic.Target.Protocol])
+	if protocolVersion != "" {
+		mismatch("protocol_version", awsSDK.StringValue(targetGroup.ProtocolVersion), strings.ToUpper(protocolVersion))
+	}
+	if healthCheckPath == "" {
+		healthCheckPath = "/"
+	}
+	mismatch("health check path", awsSDK.StringValue(targetGroup.HealthCheckPath), healthCheckPath)
+
+	// defaults of the elb module, then of AWS for http1
+	expectedMatcher := ""
+	switch {
+	case traffic.Target.StatusCode != nil:
+		expectedMatcher = *traffic.Target.StatusCode
+	case protocolVersion == "grpc":
+		expectedMatcher = "0"
+	case protocolVersion == "http2":
+		expectedMatcher = "200-299"
+	case protocolVersion == "http1":
+		expectedMatcher = "200"
+	}
+	if expectedMatcher != "" {
+		matcher := ""
+		if targetGroup.Matcher != nil {
+			if protocolVersion == "grpc" {
+				matcher = awsSDK.StringValue(targetGroup.Matcher.GrpcCode)
+			} else {
+				matcher = awsSDK.StringValue(targetGroup.Matcher.HttpCode)
+			}
+		}
+		mismatch("matcher", matcher, expectedMatcher)
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("target group %s: %s", name, strings.Join(mismatches, ", "))
+	}
+	return nil
+}
+
+func validateTargetsHealthy(client elbv2iface.ELBV2API, targetGroupArn string) error {
+	output, err := client.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{TargetGroupArn: awsSDK.String(targetGroupArn)})
+	if err != nil {
+		return fmt.Errorf("describe target health of %s: %w", targetGroupArn, err)
+	}
+	if len(output.TargetHealthDescriptions) == 0 {
+		return fmt.Errorf("no target registered in %s", targetGroupArn)
+	}
+	unhealthy := []string{}
+	for _, description := range output.TargetHealthDescriptions {
+		if state := awsSDK.StringValue(description.TargetHealth.State); state != elbv2.TargetHealthStateEnumHealthy {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s:%d %s %s", awsSDK.StringValue(description.Target.Id), awsSDK.Int64Value(description.Target.Port), state, awsSDK.StringValue(description.TargetHealth.Reason)))
+		}
+	}
+	if len(unhealthy) > 0 {
+		return fmt.Errorf("unhealthy targets in %s: %s", targetGroupArn, strings.Join(unhealthy, ", "))
+	}
+	return nil
+}
+
+// WaitForHealthyTargets polls the targets behind the listeners of the load balancer until they are all healthy,
+// the retries of the deployment override the poll policy of the options like the ecs validation
+func (c *Client) WaitForHealthyTargets(t *testing.T, loadBalancerArn string, deployment DeploymentTest) {
+	util.RunTestStage(t, "wait_healthy_targets", func() {
+		util.ReportResource(t, "load_balancer", loadBalancerArn)
+		poll := c.poll(deployment.MaxRetries, deployment.SleepBetweenRetries)
+		if err := WaitForHealthyTargetsE(t, c.options.Logger, elbv2.New(c.Session(t)), loadBalancerArn, poll); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// WaitForHealthyTargetsE returns as soon as a target is unhealthy for a reason that waiting does not fix
+func WaitForHealthyTargetsE(t terratestTesting.TestingT, logger *terratestLogger.Logger, client elbv2iface.ELBV2API, loadBalancerArn string, poll PollPolicy) error {
+	listeners, err := describeListeners(client, loadBalancerArn)
+	if err != nil {
+		return err
+	}
+	targetGroupArns := []string{}
+	seen := map[string]bool{}
+	for _, listener := range listeners {
+		if targetGroupArn := forwardTargetGroupArn(listener); targetGroupArn != "" && !seen[targetGroupArn] {
+			seen[targetGroupArn] = true
+			targetGroupArns = append(targetGroupArns, targetGroupArn)
+		}
+	}
+	if len(targetGroupArns) == 0 {
+		return fmt.Errorf("no target group behind the listeners of %s", loadBalancerArn)
+	}
+	sort.Strings(targetGroupArns)
+
+	for i := 0; i <= poll.MaxRetries; i++ {
+		pending := []string{}
+		for _, targetGroupArn := range targetGroupArns {
+			targetGroupPending, err := pendingTargets(client, targetGroupArn)
+			if err != nil {
+				return err
+			}
+			pending = append(pending, targetGroupPending...)
+		}
+		if len(pending) == 0 {
+			logger.Logf(t, "Targets healthy")
+			return nil
+		}
+		logger.Logf(t, "targets not healthy yet:: %s", strings.Join(pending, ", "))
+		if i == poll.MaxRetries {
+			return fmt.Errorf("targets of %s not healthy after %d retries: %s", loadBalancerArn, poll.MaxRetries, strings.Join(pending, ", "))
+		}
+		logger.Logf(t, "Sleeping %s...", poll.SleepBetweenRetries)
+		util.ReportRetry(t)
+		time.Sleep(poll.SleepBetweenRetries)
+	}
+	return nil
+}
+
+// pendingTargets describes the targets not healthy yet, the error is for unhealthy targets that will not recover
+func pendingTargets(client elbv2iface.ELBV2API, targetGroupArn string) ([]string, error) {
+	output, err := client.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{TargetGroupArn: awsSDK.String(targetGroupArn)})
+	if err != nil {
+		return nil, fmt.Errorf("describe target health of %s: %w", targetGroupArn, err)
+	}
+	if len(output.TargetHealthDescriptions) == 0 {
+		return []string{fmt.Sprintf("no target registered in %s", targetGroupArn)}, nil
+	}
+	pending, failed := []string{}, []string{}
+	for _, description := range output.TargetHealthDescriptions {
+		state := awsSDK.StringValue(description.TargetHealth.State)
+		if state == elbv2.TargetHealthStateEnumHealthy {
+			continue
+		}
+		reason := awsSDK.StringValue(description.TargetHealth.Reason)
+		target := fmt.Sprintf("%s:%d %s %s", awsSDK.StringValue(description.Target.Id), awsSDK.Int64Value(description.Target.Port), state, reason)
+		if state == elbv2.TargetHealthStateEnumUnhealthy && unhealthyReasonsFailFast[reason] {
+			failed = append(failed, fmt.Sprintf("%s (%s)", target, awsSDK.StringValue(description.TargetHealth.Description)))
+			continue
+		}
+		pending = append(pending, target)
+	}
+	if len(failed) > 0 {
+		return nil, fmt.Errorf("unhealthy targets in %s: %s", targetGroupArn, strings.Join(failed, ", "))
+	}
+	return pending, nil
+}
@@ -0,0 +1,202 @@
+package module
+
+import (
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+const (
+	fakeLbArn = "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/vi-rest-test/1"
+	fakeTgArn = "arn:aws:elasticloadbalancing:us-east-1:123456789012:targetgroup/vi-rest-test/1"
+)
+
+// same as the rest scenario, two listeners forward to the base target group
+var fakeRestTraffics = []Traffic{
+	{
+		Listener: TrafficPoint{Port: util.Ptr(80), Protocol: "http"},
+		Target:   TrafficPoint{Port: util.Ptr(80), Protocol: "http"},
+		Base:     util.Ptr(true),
+	},
+	{
+		Listener: TrafficPoint{Port: util.Ptr(81), Protocol: "http"},
+		Target:   TrafficPoint{Port: util.Ptr(80), Protocol: "http"},
+	},
+}
+
+func setupFakeElbv2() *fakeElbv2 {
+	client := newFakeElbv2()
+	client.putTargetGroup(&elbv2.TargetGroup{
+		TargetGroupArn:  awsSDK.String(fakeTgArn),
+		TargetGroupName: awsSDK.String("vi-rest-test"),
+		Port:            awsSDK.Int64(80),
+		Protocol:        awsSDK.String("HTTP"),
+		ProtocolVersion: awsSDK.String("HTTP1"),
+		HealthCheckPath: awsSDK.String("/"),
+		Matcher:         &elbv2.Matcher{HttpCode: awsSDK.String("200")},
+	}, elbv2.TargetHealthStateEnumHealthy)
+	client.putListener(fakeLbArn, 80, "HTTP", fakeTgArn)
+	client.putListener(fakeLbArn, 81, "HTTP", fakeTgArn)
+	return client
+}
+
+func Test_Unit_ValidateLoadBalancer(t *testing.T) {
+	client := setupFakeElbv2()
+	if err := ValidateLoadBalancerE(client, fakeLbArn, fakeRestTraffics, "/"); err != nil {
+		t.Fatal(err)
+	}
+	// the health of a target group shared by the traffics is checked once
+	util.Equal(t, 1, client.healthCalls)
+}
+
+func Test_Unit_ValidateLoadBalancer_Grpc(t *testing.T) {
+	client := newFakeElbv2()
+	client.putTargetGroup(&elbv2.TargetGroup{
+		TargetGroupArn:  awsSDK.String(fakeTgArn),
+		TargetGroupName: awsSDK.String("vi-grpc-test"),
+		Port:            awsSDK.Int64(50051),
+		Protocol:        awsSDK.String("HTTP"),
+		ProtocolVersion: awsSDK.String("GRPC"),
+		HealthCheckPath: awsSDK.String("/helloworld.Greeter/SayHello"),
+		Matcher:         &elbv2.Matcher{GrpcCode: awsSDK.String("0")},
+	}, elbv2.TargetHealthStateEnumHealthy)
+	client.putListener(fakeLbArn, 443, "HTTPS", fakeTgArn)
+
+	traffics := []Traffic{{
+		Listener: TrafficPoint{Protocol: "https"},
+		Target:   TrafficPoint{Port: util.Ptr(50051), Protocol: "http", ProtocolVersion: util.Ptr("grpc")},
+	}}
+	if err := ValidateLoadBalancerE(client, fakeLbArn, traffics, "/helloworld.Greeter/SayHello"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Unit_ValidateLoadBalancer_Errors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		setup    func(client *fakeElbv2) []Traffic
+		errMsgs  []string
+		noErrMsg string
+	}{
+		{
+			name: "missing listener",
+			setup: func(client *fakeElbv2) []Traffic {
+				client.listeners[fakeLbArn] = client.listeners[fakeLbArn][:1]
+				return fakeRestTraffics
+			},
+			errMsgs:  []string{"traffic[1] http:81 -> http:80: no listener on port 81"},
+			noErrMsg: "traffic[0]",
+		},
+		{
+			name: "listener protocol",
+			setup: func(client *fakeElbv2) []Traffic {
+				client.listeners[fakeLbArn][0].Protocol = awsSDK.String("HTTPS")
+				return fakeRestTraffics
+			},
+			errMsgs: []string{"traffic[0] http:80 -> http:80: listener port 80 protocol HTTPS, expected HTTP"},
+		},
+		{
+			name: "target group",
+			setup: func(client *fakeElbv2) []Traffic {
+				traffics := append([]Traffic{}, fakeRestTraffics...)
+				traffics[1].Target = TrafficPoint{Port: util.Ptr(8080), Protocol: "http", ProtocolVersion: util.Ptr("http2"), StatusCode: util.Ptr("200-399")}
+				return traffics
+			},
+			errMsgs: []string{
+				"traffic[1] http:81 -> http:8080: target group vi-rest-test: port 80, expected 8080, protocol_version HTTP1, expected HTTP2, matcher 200, expected 200-399",
+			},
+			noErrMsg: "traffic[0]",
+		},
+		{
+			name: "unhealthy",
+			setup: func(client *fakeElbv2) []Traffic {
+				client.setTarget(fakeTgArn, 1, elbv2.TargetHealthStateEnumUnhealthy, elbv2.TargetHealthReasonEnumTargetResponseCodeMismatch)
+				return fakeRestTraffics
+			},
+			errMsgs: []string{"unhealthy targets", "i-1:80 unhealthy Target.ResponseCodeMismatch"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			client := setupFakeElbv2()
+			traffics := testCase.setup(client)
+			err := ValidateLoadBalancerE(client, fakeLbArn, traffics, "/")
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, errMsg := range testCase.errMsgs {
+				if !strings.Contains(err.Error(), errMsg) {
+					t.Errorf("expected error containing %q, got %v", errMsg, err)
+				}
+			}
+			if testCase.noErrMsg != "" && strings.Contains(err.Error(), testCase.noErrMsg) {
+				t.Errorf("expected no error for %q, got %v", testCase.noErrMsg, err)
+			}
+		})
+	}
+}
+
+func Test_Unit_WaitForHealthyTargets(t *testing.T) {
+	client := setupFakeElbv2()
+	client.setTarget(fakeTgArn, 0, elbv2.TargetHealthStateEnumInitial, elbv2.TargetHealthReasonEnumElbInitialHealthChecking)
+	client.onHealth = func(f *fakeElbv2, call int) {
+		if call == 3 {
+			f.setTarget(fakeTgArn, 0, elbv2.TargetHealthStateEnumHealthy, "")
+		}
+	}
+	if err := WaitForHealthyTargetsE(t, terratestLogger.Discard, client, fakeLbArn, PollPolicy{MaxRetries: 5}); err != nil {
+		t.Fatal(err)
+	}
+	// the two listeners forward to the same target group, polled once per retry
+	util.Equal(t, 3, client.healthCalls)
+}
+
+func Test_Unit_WaitForHealthyTargets_Errors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		state       string
+		reason      string
+		healthCalls int
+		errMsg      string
+	}{
+		{
+			name:        "response code mismatch",
+			state:       elbv2.TargetHealthStateEnumUnhealthy,
+			reason:      elbv2.TargetHealthReasonEnumTargetResponseCodeMismatch,
+			healthCalls: 1,
+			errMsg:      "i-0:80 unhealthy Target.ResponseCodeMismatch",
+		},
+		{
+			name:        "timeout",
+			state:       elbv2.TargetHealthStateEnumUnhealthy,
+			reason:      elbv2.TargetHealthReasonEnumTargetTimeout,
+			healthCalls: 1,
+			errMsg:      "i-0:80 unhealthy Target.Timeout",
+		},
+		{
+			name:        "still initial",
+			state:       elbv2.TargetHealthStateEnumInitial,
+			reason:      elbv2.TargetHealthReasonEnumElbInitialHealthChecking,
+			healthCalls: 3,
+			errMsg:      "not healthy after 2 retries: i-0:80 initial Elb.InitialHealthChecking",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			client := setupFakeElbv2()
+			client.setTarget(fakeTgArn, 0, testCase.state, testCase.reason)
+			err := WaitForHealthyTargetsE(t, terratestLogger.Discard, client, fakeLbArn, PollPolicy{MaxRetries: 2})
+			if err == nil || !strings.Contains(err.Error(), testCase.errMsg) {
+				t.Fatalf("expected error containing %q, got %v", testCase.errMsg, err)
+			}
+			util.Equal(t, testCase.healthCalls, client.healthCalls)
+		})
+	}
+}
@@ -0,0 +1,767 @@
+package module
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi/resourcegroupstaggingapiiface"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// fakeS3 serves objects and the versioning of buckets, the other calls panic
+type fakeS3 struct {
+	s3iface.S3API
+	objects    map[string]map[string]string // bucket -> key -> content
+	versioning map[string]string            // bucket -> status
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string]map[string]string{}, versioning: map[string]string{}}
+}
+
+func (f *fakeS3) putObject(bucket, key, content string) {
+	if f.objects[bucket] == nil {
+		f.objects[bucket] = map[string]string{}
+	}
+	f.objects[bucket][key] = content
+}
+
+func (f *fakeS3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	bucket, ok := f.objects[awsSDK.StringValue(input.Bucket)]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "The specified bucket does not exist", nil)
+	}
+	content, ok := bucket[awsSDK.StringValue(input.Key)]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "The specified key does not exist.", nil)
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewBufferString(content))}, nil
+}
+
+func (f *fakeS3) GetBucketVersioning(input *s3.GetBucketVersioningInput) (*s3.GetBucketVersioningOutput, error) {
+	if _, ok := f.objects[awsSDK.StringValue(input.Bucket)]; !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchBucket, "The specified bucket does not exist", nil)
+	}
+	output := &s3.GetBucketVersioningOutput{}
+	if status, ok := f.versioning[awsSDK.StringValue(input.Bucket)]; ok {
+		output.Status = awsSDK.String(status)
+	}
+	return output, nil
+}
+
+// fakeEcs serves clusters, capacity providers, services, tasks, container instances and task definitions, the other calls panic
+type fakeEcs struct {
+	ecsiface.ECSAPI
+	clusters          map[string]*ecs.Cluster           // name
+	capacityProviders map[string]*ecs.CapacityProvider  // name
+	services          map[string]*ecs.Service           // cluster/service
+	tasks             map[string][]*ecs.Task            // cluster/service
+	taskDefinitions   map[string]*ecs.TaskDefinition    // arn
+	instances         map[string]*ecs.ContainerInstance // arn
+}
+
+func newFakeEcs() *fakeEcs {
+	return &fakeEcs{clusters: map[string]*ecs.Cluster{}, capacityProviders: map[string]*ecs.CapacityProvider{}, services: map[string]*ecs.Service{}, tasks: map[string][]*ecs.Task{}, taskDefinitions: map[string]*ecs.TaskDefinition{}, instances: map[string]*ecs.ContainerInstance{}}
+}
+
+// putCapacityProvider adds the capacity provider to the cluster and to its default strategy
+func (f *fakeEcs) putCapacityProvider(clusterName, name, autoScalingGroupArn string, base, weight int64) {
+	cluster, ok := f.clusters[clusterName]
+	if !ok {
+		cluster = &ecs.Cluster{ClusterName: awsSDK.String(clusterName)}
+		f.clusters[clusterName] = cluster
+	}
+	cluster.CapacityProviders = append(cluster.CapacityProviders, awsSDK.String(name))
+	cluster.DefaultCapacityProviderStrategy = append(cluster.DefaultCapacityProviderStrategy, &ecs.CapacityProviderStrategyItem{
+		CapacityProvider: awsSDK.String(name),
+		Base:             awsSDK.Int64(base),
+		Weight:           awsSDK.Int64(weight),
+	})
+	f.capacityProviders[name] = &ecs.CapacityProvider{
+		Name:                     awsSDK.String(name),
+		AutoScalingGroupProvider: &ecs.AutoScalingGroupProvider{AutoScalingGroupArn: awsSDK.String(autoScalingGroupArn)},
+	}
+}
+
+func (f *fakeEcs) DescribeClusters(input *ecs.DescribeClustersInput) (*ecs.DescribeClustersOutput, error) {
+	output := &ecs.DescribeClustersOutput{}
+	for _, name := range input.Clusters {
+		cluster, ok := f.clusters[awsSDK.StringValue(name)]
+		if !ok {
+			output.Failures = append(output.Failures, &ecs.Failure{Arn: name, Reason: awsSDK.String("MISSING")})
+			continue
+		}
+		output.Clusters = append(output.Clusters, cluster)
+	}
+	return output, nil
+}
+
+func (f *fakeEcs) DescribeCapacityProviders(input *ecs.DescribeCapacityProvidersInput) (*ecs.DescribeCapacityProvidersOutput, error) {
+	output := &ecs.DescribeCapacityProvidersOutput{}
+	for _, name := range input.CapacityProviders {
+		provider, ok := f.capacityProviders[awsSDK.StringValue(name)]
+		if !ok {
+			output.Failures = append(output.Failures, &ecs.Failure{Arn: name, Reason: awsSDK.String("MISSING")})
+			continue
+		}
+		output.CapacityProviders = append(output.CapacityProviders, provider)
+	}
+	return output, nil
+}
+
+func (f *fakeEcs) putService(clusterName string, service *ecs.Service, taskDefinition *ecs.TaskDefinition) {
+	service.ClusterArn = awsSDK.String(clusterName)
+	service.TaskDefinition = taskDefinition.TaskDefinitionArn
+	f.services[clusterName+"/"+awsSDK.StringValue(service.ServiceName)] = service
+	f.taskDefinitions[awsSDK.StringValue(taskDefinition.TaskDefinitionArn)] = taskDefinition
+}
+
+func (f *fakeEcs) DescribeServices(input *ecs.DescribeServicesInput) (*ecs.DescribeServicesOutput, error) {
+	output := &ecs.DescribeServicesOutput{}
+	for _, name := range input.Services {
+		service, ok := f.services[awsSDK.StringValue(input.Cluster)+"/"+awsSDK.StringValue(name)]
+		if !ok {
+			output.Failures = append(output.Failures, &ecs.Failure{Arn: name, Reason: awsSDK.String("MISSING")})
+			continue
+		}
+		output.Services = append(output.Services, service)
+	}
+	return output, nil
+}
+
+func (f *fakeEcs) putTask(clusterName, serviceName string, task *ecs.Task) {
+	f.tasks[clusterName+"/"+serviceName] = append(f.tasks[clusterName+"/"+serviceName], task)
+}
+
+func (f *fakeEcs) ListTasks(input *ecs.ListTasksInput) (*ecs.ListTasksOutput, error) {
+	output := &ecs.ListTasksOutput{TaskArns: []*string{}}
+	for _, task := range f.tasks[awsSDK.StringValue(input.Cluster)+"/"+awsSDK.StringValue(input.ServiceName)] {
+		if input.DesiredStatus == nil || awsSDK.StringValue(task.DesiredStatus) == awsSDK.StringValue(input.DesiredStatus) {
+			output.TaskArns = append(output.TaskArns, task.TaskArn)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeEcs) DescribeTasks(input *ecs.DescribeTasksInput) (*ecs.DescribeTasksOutput, error) {
+	output := &ecs.DescribeTasksOutput{}
+	for _, arn := range input.Tasks {
+		found := false
+		for key, tasks := range f.tasks {
+			if !strings.HasPrefix(key, awsSDK.StringValue(input.Cluster)+"/") {
+				continue
+			}
+			for _, task := range tasks {
+				if awsSDK.StringValue(task.TaskArn) == awsSDK.StringValue(arn) {
+					output.Tasks = append(output.Tasks, task)
+					found = true
+				}
+			}
+		}
+		if !found {
+			output.Failures = append(output.Failures, &ecs.Failure{Arn: arn, Reason: awsSDK.String("MISSING")})
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeEcs) DescribeContainerInstances(input *ecs.DescribeContainerInstancesInput) (*ecs.DescribeContainerInstancesOutput, error) {
+	output := &ecs.DescribeContainerInstancesOutput{}
+	for _, arn := range input.ContainerInstances {
+		instance, ok := f.instances[awsSDK.StringValue(arn)]
+		if !ok {
+			output.Failures = append(output.Failures, &ecs.Failure{Arn: arn, Reason: awsSDK.String("MISSING")})
+			continue
+		}
+		output.ContainerInstances = append(output.ContainerInstances, instance)
+	}
+	return output, nil
+}
+
+func (f *fakeEcs) DescribeTaskDefinition(input *ecs.DescribeTaskDefinitionInput) (*ecs.DescribeTaskDefinitionOutput, error) {
+	taskDefinition, ok := f.taskDefinitions[awsSDK.StringValue(input.TaskDefinition)]
+	if !ok {
+		return nil, awserr.New(ecs.ErrCodeClientException, fmt.Sprintf("Unable to describe task definition %s", awsSDK.StringValue(input.TaskDefinition)), nil)
+	}
+	return &ecs.DescribeTaskDefinitionOutput{TaskDefinition: taskDefinition}, nil
+}
+
+// the service of the rest scenario shared by the validator tests
+const (
+	fakeClusterName       = "vi-rest-test"
+	fakeServiceName       = "vi-rest-test-unique"
+	fakeContainerName     = "unique"
+	fakeTaskDefinitionArn = "arn:aws:ecs:us-east-1:123456789012:task-definition/vi-rest-test-unique:1"
+	fakeTaskArn           = "arn:aws:ecs:us-east-1:123456789012:task/vi-rest-test/0123456789abcdef"
+	fakeLogGroup          = "/aws/ecs/vi-rest-test-unique/vi-rest-test-unique"
+	fakeLogStream         = "ecs/unique/0123456789abcdef"
+)
+
+// newFakeEcsService returns the cluster with the service and its task definition as configured by the ecs module,
+// one container with awslogs, the tests add what their validator needs
+func newFakeEcsService() (*fakeEcs, *ecs.Service, *ecs.TaskDefinition) {
+	client := newFakeEcs()
+	client.clusters[fakeClusterName] = &ecs.Cluster{ClusterName: awsSDK.String(fakeClusterName)}
+	service := &ecs.Service{ServiceName: awsSDK.String(fakeServiceName)}
+	taskDefinition := &ecs.TaskDefinition{
+		TaskDefinitionArn: awsSDK.String(fakeTaskDefinitionArn),
+		ContainerDefinitions: []*ecs.ContainerDefinition{{
+			Name: awsSDK.String(fakeContainerName),
+			LogConfiguration: &ecs.LogConfiguration{
+				LogDriver: awsSDK.String(ecs.LogDriverAwslogs),
+				Options: awsSDK.StringMap(map[string]string{
+					"awslogs-group":         fakeLogGroup,
+					"awslogs-region":        "us-east-1",
+					"awslogs-stream-prefix": "ecs",
+				}),
+			},
+		}},
+	}
+	client.putService(fakeClusterName, service, taskDefinition)
+	return client, service, taskDefinition
+}
+
+// fakeDynamodb is a local stand-in of DynamoDB with the tables and the items kept in memory, the other calls panic
+type fakeDynamodb struct {
+	dynamodbiface.DynamoDBAPI
+	tables map[string]*dynamodb.TableDescription
+	items  map[string]map[string]map[string]*dynamodb.AttributeValue // table -> key -> item
+}
+
+func newFakeDynamodb() *fakeDynamodb {
+	return &fakeDynamodb{tables: map[string]*dynamodb.TableDescription{}, items: map[string]map[string]map[string]*dynamodb.AttributeValue{}}
+}
+
+func (f *fakeDynamodb) createTable(table DynamodbTableTest) *dynamodb.TableDescription {
+	description := &dynamodb.TableDescription{
+		TableName:   awsSDK.String(table.TableName),
+		TableStatus: awsSDK.String(dynamodb.TableStatusActive),
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: awsSDK.String(table.PrimaryKeyName), KeyType: awsSDK.String(dynamodb.KeyTypeHash)},
+			{AttributeName: awsSDK.String(table.SortKeyName), KeyType: awsSDK.String(dynamodb.KeyTypeRange)},
+		},
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: awsSDK.String(table.PrimaryKeyName), AttributeType: awsSDK.String(table.PrimaryKeyType)},
+			{AttributeName: awsSDK.String(table.SortKeyName), AttributeType: awsSDK.String(table.SortKeyType)},
+		},
+	}
+	if !table.PredictableWorkload {
+		description.BillingModeSummary = &dynamodb.BillingModeSummary{BillingMode: awsSDK.String(dynamodb.BillingModePayPerRequest)}
+	}
+	f.tables[table.TableName] = description
+	f.items[table.TableName] = map[string]map[string]*dynamodb.AttributeValue{}
+	return description
+}
+
+func (f *fakeDynamodb) table(name *string) (*dynamodb.TableDescription, error) {
+	description, ok := f.tables[awsSDK.StringValue(name)]
+	if !ok {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "Requested resource not found", nil)
+	}
+	return description, nil
+}
+
+// itemKey checks the attributes of the key like DynamoDB does
+func (f *fakeDynamodb) itemKey(description *dynamodb.TableDescription, attributes map[string]*dynamodb.AttributeValue) (string, error) {
+	types := map[string]string{}
+	for _, attribute := range description.AttributeDefinitions {
+		types[awsSDK.StringValue(attribute.AttributeName)] = awsSDK.StringValue(attribute.AttributeType)
+	}
+	parts := []string{}
+	for _, key := range description.KeySchema {
+		name := awsSDK.StringValue(key.AttributeName)
+		value, ok := attributes[name]
+		if !ok {
+			return "", awserr.New("ValidationException", fmt.Sprintf("One of the required keys was not given a value: %s", name), nil)
+		}
+		switch {
+		case types[name] == dynamodb.ScalarAttributeTypeS && value.S != nil:
+			parts = append(parts, "S:"+*value.S)
+		case types[name] == dynamodb.ScalarAttributeTypeN && value.N != nil:
+			parts = append(parts, "N:"+*value.N)
+		case types[name] == dynamodb.ScalarAttributeTypeB && value.B != nil:
+			parts = append(parts, "B:"+string(value.B))
+		default:
+			return "", awserr.New("ValidationException", fmt.Sprintf("Type mismatch for key %s expected: %s", name, types[name]), nil)
+		}
+	}
+	sort.Strings(parts)
+	return fmt.Sprint(parts), nil
+}
+
+func (f *fakeDynamodb) DescribeTable(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	description, err := f.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.DescribeTableOutput{Table: description}, nil
+}
+
+func (f *fakeDynamodb) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	description, err := f.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := f.itemKey(description, input.Item)
+	if err != nil {
+		return nil, err
+	}
+	f.items[awsSDK.StringValue(input.TableName)][key] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamodb) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	description, err := f.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := f.itemKey(description, input.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemOutput{Item: f.items[awsSDK.StringValue(input.TableName)][key]}, nil
+}
+
+func (f *fakeDynamodb) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	description, err := f.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := f.itemKey(description, input.Key)
+	if err != nil {
+		return nil, err
+	}
+	delete(f.items[awsSDK.StringValue(input.TableName)], key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// fakeRoute53 serves hosted zones and their record sets, the other calls panic
+type fakeRoute53 struct {
+	route53iface.Route53API
+	zones   []*route53.HostedZone
+	servers map[string][]string                     // zone id -> name servers
+	records map[string][]*route53.ResourceRecordSet // zone id -> record sets
+}
+
+func newFakeRoute53() *fakeRoute53 {
+	return &fakeRoute53{servers: map[string][]string{}, records: map[string][]*route53.ResourceRecordSet{}}
+}
+
+func (f *fakeRoute53) createZone(id, name string, private bool, nameServers ...string) {
+	f.zones = append(f.zones, &route53.HostedZone{
+		Id:     awsSDK.String("/hostedzone/" + id),
+		Name:   awsSDK.String(name + "."),
+		Config: &route53.HostedZoneConfig{PrivateZone: awsSDK.Bool(private)},
+	})
+	f.servers["/hostedzone/"+id] = nameServers
+}
+
+func (f *fakeRoute53) putAlias(zoneId, name, recordType, dnsName, aliasZoneId string) {
+	f.records["/hostedzone/"+zoneId] = append(f.records["/hostedzone/"+zoneId], &route53.ResourceRecordSet{
+		Name:        awsSDK.String(name + "."),
+		Type:        awsSDK.String(recordType),
+		AliasTarget: &route53.AliasTarget{DNSName: awsSDK.String(dnsName + "."), HostedZoneId: awsSDK.String(aliasZoneId)},
+	})
+}
+
+func (f *fakeRoute53) ListHostedZonesByName(input *route53.ListHostedZonesByNameInput) (*route53.ListHostedZonesByNameOutput, error) {
+	zones := append([]*route53.HostedZone{}, f.zones...)
+	sort.Slice(zones, func(i, j int) bool { return *zones[i].Name < *zones[j].Name })
+	output := &route53.ListHostedZonesByNameOutput{}
+	for _, zone := range zones {
+		if *zone.Name >= strings.TrimSuffix(awsSDK.StringValue(input.DNSName), ".")+"." {
+			output.HostedZones = append(output.HostedZones, zone)
+		}
+	}
+	return output, nil
+}
+
+func (f *fakeRoute53) GetHostedZone(input *route53.GetHostedZoneInput) (*route53.GetHostedZoneOutput, error) {
+	for _, zone := range f.zones {
+		if *zone.Id == awsSDK.StringValue(input.Id) {
+			return &route53.GetHostedZoneOutput{HostedZone: zone, DelegationSet: &route53.DelegationSet{NameServers: awsSDK.StringSlice(f.servers[*zone.Id])}}, nil
+		}
+	}
+	return nil, awserr.New(route53.ErrCodeNoSuchHostedZone, "No hosted zone found", nil)
+}
+
+func (f *fakeRoute53) ListResourceRecordSets(input *route53.ListResourceRecordSetsInput) (*route53.ListResourceRecordSetsOutput, error) {
+	records := append([]*route53.ResourceRecordSet{}, f.records[awsSDK.StringValue(input.HostedZoneId)]...)
+	sort.Slice(records, func(i, j int) bool {
+		if *records[i].Name != *records[j].Name {
+			return *records[i].Name < *records[j].Name
+		}
+		return *records[i].Type < *records[j].Type
+	})
+	start := strings.TrimSuffix(awsSDK.StringValue(input.StartRecordName), ".") + "."
+	output := &route53.ListResourceRecordSetsOutput{}
+	for _, record := range records {
+		if *record.Name < start || (*record.Name == start && *record.Type < awsSDK.StringValue(input.StartRecordType)) {
+			continue
+		}
+		output.ResourceRecordSets = append(output.ResourceRecordSets, record)
+	}
+	return output, nil
+}
+
+// fakeDnsServer answers the A queries of its names over UDP, it refuses the others
+type fakeDnsServer struct {
+	address string
+
+	mu    sync.Mutex
+	names map[string]net.IP
+}
+
+func (s *fakeDnsServer) deleteName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.names, name)
+}
+
+func newFakeDnsServer(t *testing.T, names map[string]net.IP) *fakeDnsServer {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	server := &fakeDnsServer{address: conn.LocalAddr().String(), names: names}
+	go server.serve(conn)
+	return server
+}
+
+func (s *fakeDnsServer) serve(conn net.PacketConn) {
+	buffer := make([]byte, 512)
+	for {
+		n, address, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		var request dnsmessage.Message
+		if err := request.Unpack(buffer[:n]); err != nil || len(request.Questions) != 1 {
+			continue
+		}
+		question := request.Questions[0]
+		response := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: request.ID, Response: true, Authoritative: true},
+			Questions: request.Questions,
+		}
+		s.mu.Lock()
+		ip, ok := s.names[strings.TrimSuffix(strings.ToLower(question.Name.String()), ".")]
+		s.mu.Unlock()
+		switch {
+		case !ok:
+			response.RCode = dnsmessage.RCodeNameError
+		case question.Type == dnsmessage.TypeA:
+			a := dnsmessage.AResource{}
+			copy(a.A[:], ip.To4())
+			response.Answers = append(response.Answers, dnsmessage.Resource{
+				Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+				Body:   &a,
+			})
+		}
+		packed, err := response.Pack()
+		if err != nil {
+			continue
+		}
+		conn.WriteTo(packed, address)
+	}
+}
+
+// fakeElbv2 serves listeners, target groups and the health of their targets, the other calls panic
+type fakeElbv2 struct {
+	elbv2iface.ELBV2API
+
+	mu           sync.Mutex
+	listeners    map[string][]*elbv2.Listener                // load balancer arn
+	targetGroups map[string]*elbv2.TargetGroup               // arn
+	targets      map[string][]*elbv2.TargetHealthDescription // target group arn
+	// healthCalls counts the DescribeTargetHealth calls
+	healthCalls int
+	// onHealth is called before answering DescribeTargetHealth, e.g. to change the state of the targets
+	onHealth func(f *fakeElbv2, call int)
+}
+
+func newFakeElbv2() *fakeElbv2 {
+	return &fakeElbv2{listeners: map[string][]*elbv2.Listener{}, targetGroups: map[string]*elbv2.TargetGroup{}, targets: map[string][]*elbv2.TargetHealthDescription{}}
+}
+
+func (f *fakeElbv2) putListener(loadBalancerArn string, port int64, protocol, targetGroupArn string) {
+	f.listeners[loadBalancerArn] = append(f.listeners[loadBalancerArn], &elbv2.Listener{
+		ListenerArn:     awsSDK.String(fmt.Sprintf("%s/listener/%d", loadBalancerArn, port)),
+		LoadBalancerArn: awsSDK.String(loadBalancerArn),
+		Port:            awsSDK.Int64(port),
+		Protocol:        awsSDK.String(protocol),
+		DefaultActions:  []*elbv2.Action{{Type: awsSDK.String(elbv2.ActionTypeEnumForward), TargetGroupArn: awsSDK.String(targetGroupArn)}},
+	})
+}
+
+func (f *fakeElbv2) putTargetGroup(targetGroup *elbv2.TargetGroup, states ...string) {
+	arn := awsSDK.StringValue(targetGroup.TargetGroupArn)
+	f.targetGroups[arn] = targetGroup
+	f.targets[arn] = nil
+	for i, state := range states {
+		f.setTarget(arn, i, state, "")
+	}
+}
+
+func (f *fakeElbv2) setTarget(targetGroupArn string, index int, state, reason string) {
+	description := &elbv2.TargetHealthDescription{
+		Target:       &elbv2.TargetDescription{Id: awsSDK.String(fmt.Sprintf("i-%d", index)), Port: f.targetGroups[targetGroupArn].Port},
+		TargetHealth: &elbv2.TargetHealth{State: awsSDK.String(state)},
+	}
+	if reason != "" {
+		description.TargetHealth.Reason = awsSDK.String(reason)
+	}
+	if index < len(f.targets[targetGroupArn]) {
+		f.targets[targetGroupArn][index] = description
+		return
+	}
+	f.targets[targetGroupArn] = append(f.targets[targetGroupArn], description)
+}
+
+func (f *fakeElbv2) DescribeListenersPages(input *elbv2.DescribeListenersInput, fn func(*elbv2.DescribeListenersOutput, bool) bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	listeners, ok := f.listeners[awsSDK.StringValue(input.LoadBalancerArn)]
+	if !ok {
+		return awserr.New(elbv2.ErrCodeLoadBalancerNotFoundException, "One or more load balancers not found", nil)
+	}
+	fn(&elbv2.DescribeListenersOutput{Listeners: listeners}, true)
+	return nil
+}
+
+func (f *fakeElbv2) DescribeTargetGroups(input *elbv2.DescribeTargetGroupsInput) (*elbv2.DescribeTargetGroupsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	output := &elbv2.DescribeTargetGroupsOutput{}
+	for _, arn := range input.TargetGroupArns {
+		targetGroup, ok := f.targetGroups[awsSDK.StringValue(arn)]
+		if !ok {
+			return nil, awserr.New(elbv2.ErrCodeTargetGroupNotFoundException, "One or more target groups not found", nil)
+		}
+		output.TargetGroups = append(output.TargetGroups, targetGroup)
+	}
+	return output, nil
+}
+
+func (f *fakeElbv2) DescribeTargetHealth(input *elbv2.DescribeTargetHealthInput) (*elbv2.DescribeTargetHealthOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.healthCalls++
+	if f.onHealth != nil {
+		f.onHealth(f, f.healthCalls)
+	}
+	targets, ok := f.targets[awsSDK.StringValue(input.TargetGroupArn)]
+	if !ok {
+		return nil, awserr.New(elbv2.ErrCodeTargetGroupNotFoundException, "One or more target groups not found", nil)
+	}
+	return &elbv2.DescribeTargetHealthOutput{TargetHealthDescriptions: targets}, nil
+}
+
+// fakeAutoScaling serves auto scaling groups and their activities, the other calls panic
+type fakeAutoScaling struct {
+	autoscalingiface.AutoScalingAPI
+	groups     map[string]*autoscaling.Group      // name
+	activities map[string][]*autoscaling.Activity // group name
+}
+
+func newFakeAutoScaling() *fakeAutoScaling {
+	return &fakeAutoScaling{groups: map[string]*autoscaling.Group{}, activities: map[string][]*autoscaling.Activity{}}
+}
+
+func (f *fakeAutoScaling) putGroup(group *autoscaling.Group) string {
+	name := awsSDK.StringValue(group.AutoScalingGroupName)
+	arn := fmt.Sprintf("arn:aws:autoscaling:us-east-1:123456789012:autoScalingGroup:%s:autoScalingGroupName/%s", strings.Repeat("0", 8), name)
+	group.AutoScalingGroupARN = awsSDK.String(arn)
+	f.groups[name] = group
+	return arn
+}
+
+func (f *fakeAutoScaling) DescribeAutoScalingGroupsPages(input *autoscaling.DescribeAutoScalingGroupsInput, fn func(*autoscaling.DescribeAutoScalingGroupsOutput, bool) bool) error {
+	output := &autoscaling.DescribeAutoScalingGroupsOutput{}
+	for _, name := range input.AutoScalingGroupNames {
+		if group, ok := f.groups[awsSDK.StringValue(name)]; ok {
+			output.AutoScalingGroups = append(output.AutoScalingGroups, group)
+		}
+	}
+	fn(output, true)
+	return nil
+}
+
+func (f *fakeAutoScaling) DescribeScalingActivities(input *autoscaling.DescribeScalingActivitiesInput) (*autoscaling.DescribeScalingActivitiesOutput, error) {
+	name := awsSDK.StringValue(input.AutoScalingGroupName)
+	if _, ok := f.groups[name]; !ok {
+		return nil, awserr.New("ValidationError", fmt.Sprintf("AutoScalingGroup name not found - %s", name), nil)
+	}
+	return &autoscaling.DescribeScalingActivitiesOutput{Activities: f.activities[name]}, nil
+}
+
+// fakeEc2 serves the default version of launch templates and the subnets, the other calls panic
+type fakeEc2 struct {
+	ec2iface.EC2API
+	launchTemplates map[string]*ec2.ResponseLaunchTemplateData // id
+	subnets         []*ec2.Subnet
+}
+
+func newFakeEc2() *fakeEc2 {
+	return &fakeEc2{launchTemplates: map[string]*ec2.ResponseLaunchTemplateData{}}
+}
+
+func (f *fakeEc2) putSubnet(vpcId, id, tier string) {
+	f.subnets = append(f.subnets, &ec2.Subnet{
+		VpcId:    awsSDK.String(vpcId),
+		SubnetId: awsSDK.String(id),
+		Tags:     []*ec2.Tag{{Key: awsSDK.String("Tier"), Value: awsSDK.String(tier)}},
+	})
+}
+
+// DescribeSubnetsPages only filters by vpc-id and tags
+func (f *fakeEc2) DescribeSubnetsPages(input *ec2.DescribeSubnetsInput, fn func(*ec2.DescribeSubnetsOutput, bool) bool) error {
+	output := &ec2.DescribeSubnetsOutput{}
+	for _, subnet := range f.subnets {
+		match := true
+		for _, filter := range input.Filters {
+			values := awsSDK.StringValueSlice(filter.Values)
+			name := awsSDK.StringValue(filter.Name)
+			switch {
+			case name == "vpc-id":
+				match = match && contains(values, awsSDK.StringValue(subnet.VpcId))
+			case strings.HasPrefix(name, "tag:"):
+				tagged := false
+				for _, tag := range subnet.Tags {
+					tagged = tagged || (awsSDK.StringValue(tag.Key) == strings.TrimPrefix(name, "tag:") && contains(values, awsSDK.StringValue(tag.Value)))
+				}
+				match = match && tagged
+			default:
+				panic("unsupported filter " + name)
+			}
+		}
+		if match {
+			output.Subnets = append(output.Subnets, subnet)
+		}
+	}
+	fn(output, true)
+	return nil
+}
+
+func (f *fakeEc2) DescribeLaunchTemplateVersions(input *ec2.DescribeLaunchTemplateVersionsInput) (*ec2.DescribeLaunchTemplateVersionsOutput, error) {
+	data, ok := f.launchTemplates[awsSDK.StringValue(input.LaunchTemplateId)]
+	if !ok {
+		return nil, awserr.New("InvalidLaunchTemplateId.NotFound", fmt.Sprintf("The specified launch template, with template ID %s, does not exist.", awsSDK.StringValue(input.LaunchTemplateId)), nil)
+	}
+	return &ec2.DescribeLaunchTemplateVersionsOutput{LaunchTemplateVersions: []*ec2.LaunchTemplateVersion{{
+		LaunchTemplateId:   input.LaunchTemplateId,
+		DefaultVersion:     awsSDK.Bool(true),
+		LaunchTemplateData: data,
+	}}}, nil
+}
+
+// fakeCloudWatchLogs serves the streams and events of log groups, the other calls panic
+type fakeCloudWatchLogs struct {
+	cloudwatchlogsiface.CloudWatchLogsAPI
+	events map[string]map[string][]*cloudwatchlogs.FilteredLogEvent // group, stream
+	// filterCalls counts the FilterLogEvents calls
+	filterCalls int
+	// onDescribe is called before answering DescribeLogStreams, e.g. to add events
+	onDescribe    func(f *fakeCloudWatchLogs, call int)
+	describeCalls int
+}
+
+func newFakeCloudWatchLogs() *fakeCloudWatchLogs {
+	return &fakeCloudWatchLogs{events: map[string]map[string][]*cloudwatchlogs.FilteredLogEvent{}}
+}
+
+func (f *fakeCloudWatchLogs) putEvent(group, stream string, timestamp time.Time, message string) {
+	if _, ok := f.events[group]; !ok {
+		f.events[group] = map[string][]*cloudwatchlogs.FilteredLogEvent{}
+	}
+	f.events[group][stream] = append(f.events[group][stream], &cloudwatchlogs.FilteredLogEvent{
+		LogStreamName: awsSDK.String(stream),
+		Timestamp:     awsSDK.Int64(timestamp.UnixMilli()),
+		Message:       awsSDK.String(message),
+	})
+}
+
+func (f *fakeCloudWatchLogs) DescribeLogStreamsPages(input *cloudwatchlogs.DescribeLogStreamsInput, fn func(*cloudwatchlogs.DescribeLogStreamsOutput, bool) bool) error {
+	f.describeCalls++
+	if f.onDescribe != nil {
+		f.onDescribe(f, f.describeCalls)
+	}
+	output := &cloudwatchlogs.DescribeLogStreamsOutput{}
+	for stream := range f.events[awsSDK.StringValue(input.LogGroupName)] {
+		if strings.HasPrefix(stream, awsSDK.StringValue(input.LogStreamNamePrefix)) {
+			output.LogStreams = append(output.LogStreams, &cloudwatchlogs.LogStream{LogStreamName: awsSDK.String(stream)})
+		}
+	}
+	fn(output, true)
+	return nil
+}
+
+func (f *fakeCloudWatchLogs) FilterLogEventsPages(input *cloudwatchlogs.FilterLogEventsInput, fn func(*cloudwatchlogs.FilterLogEventsOutput, bool) bool) error {
+	f.filterCalls++
+	streams := []string{}
+	for stream := range f.events[awsSDK.StringValue(input.LogGroupName)] {
+		if strings.HasPrefix(stream, awsSDK.StringValue(input.LogStreamNamePrefix)) {
+			streams = append(streams, stream)
+		}
+	}
+	sort.Strings(streams)
+	output := &cloudwatchlogs.FilterLogEventsOutput{}
+	for _, stream := range streams {
+		for _, event := range f.events[awsSDK.StringValue(input.LogGroupName)][stream] {
+			if awsSDK.Int64Value(event.Timestamp) >= awsSDK.Int64Value(input.StartTime) {
+				output.Events = append(output.Events, event)
+			}
+		}
+	}
+	fn(output, true)
+	return nil
+}
+
+// fakeTagging returns the resources tagged with the TestID of the filter
+type fakeTagging struct {
+	resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI
+	resources map[string][]string // TestID -> arns
+}
+
+func (f *fakeTagging) GetResources(input *resourcegroupstaggingapi.GetResourcesInput) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+	output := &resourcegroupstaggingapi.GetResourcesOutput{}
+	for _, filter := range input.TagFilters {
+		if awsSDK.StringValue(filter.Key) != "TestID" {
+			continue
+		}
+		for _, value := range filter.Values {
+			for _, arn := range f.resources[awsSDK.StringValue(value)] {
+				output.ResourceTagMappingList = append(output.ResourceTagMappingList, &resourcegroupstaggingapi.ResourceTagMapping{ResourceARN: awsSDK.String(arn)})
+			}
+		}
+	}
+	return output, nil
+}
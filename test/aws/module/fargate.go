@@ -0,0 +1,252 @@
+package module
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+var (
+	// capacity providers of the `capacities` types in modules/aws/container/ecs
+	fargateCapacityProviders = map[string]string{
+		CapacityTypeOnDemand: "FARGATE",
+		CapacityTypeSpot:     "FARGATE_SPOT",
+	}
+
+	// memory in MiB allowed for each cpu unit
+	// https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-cpu-memory-error.html
+	fargateMemories = map[int]struct{ min, max, step int }{
+		256:   {512, 2048, 0},
+		512:   {1024, 4096, 1024},
+		1024:  {2048, 8192, 1024},
+		2048:  {4096, 16384, 1024},
+		4096:  {8192, 30720, 1024},
+		8192:  {16384, 61440, 4096},
+		16384: {32768, 122880, 8192},
+	}
+)
+
+// RuntimePlatform is the `runtimePlatform` of a task definition, not yet in the ecs api of the aws sdk
+type RuntimePlatform struct {
+	OperatingSystemFamily *string `locationName:"operatingSystemFamily"`
+	CpuArchitecture       *string `locationName:"cpuArchitecture"`
+}
+
+// RuntimePlatformDescriber returns the runtime platform of the task definition, nil when it has none
+type RuntimePlatformDescriber func(taskDefinitionArn string) (*RuntimePlatform, error)
+
+// DescribeRuntimePlatform decodes the response of DescribeTaskDefinition with the runtime platform
+func DescribeRuntimePlatform(client ecsiface.ECSAPI) RuntimePlatformDescriber {
+	return func(taskDefinitionArn string) (*RuntimePlatform, error) {
+		request, _ := client.DescribeTaskDefinitionRequest(&ecs.DescribeTaskDefinitionInput{TaskDefinition: awsSDK.String(taskDefinitionArn)})
+		output := &struct {
+			TaskDefinition *struct {
+				RuntimePlatform *RuntimePlatform `locationName:"runtimePlatform"`
+			} `locationName:"taskDefinition"`
+		}{}
+		request.Data = output
+		if err := request.Send(); err != nil {
+			return nil, err
+		}
+		if output.TaskDefinition == nil {
+			return nil, fmt.Errorf("task definition %s not found", taskDefinitionArn)
+		}
+		return output.TaskDefinition.RuntimePlatform, nil
+	}
+}
+
+// FargateTest is the `fargate` object of the group and the `vpc` variable
+type FargateTest struct {
+	Os           string
+	Architecture string
+	Capacities   []CapacityTest
+	VpcId        string
+	Tier         string
+}
+
+// FargateFromVars reads the first `group` with a `fargate` object and the `vpc` of the terraform variables,
+// os and architecture default to the only ones mapped by the ecs module
+func FargateFromVars(t *testing.T, vars map[string]any) FargateTest {
+	group := findGroup(vars, "fargate")
+	if group == nil {
+		t.Fatalf("no group with fargate in the variables")
+	}
+	fargateGroup, _ := group["fargate"].(map[string]any)
+
+	fargate := FargateTest{Os: "linux", Architecture: "x86_64"}
+	if os, ok := fargateGroup["os"].(string); ok {
+		fargate.Os = os
+	}
+	if architecture, ok := fargateGroup["architecture"].(string); ok {
+		fargate.Architecture = architecture
+	}
+	capacities, _ := fargateGroup["capacities"].([]map[string]any)
+	fargate.Capacities = capacitiesFromVars(capacities)
+
+	vpc, _ := vars["vpc"].(map[string]any)
+	fargate.VpcId, _ = vpc["id"].(string)
+	fargate.Tier, _ = vpc["tier"].(string)
+	if fargate.VpcId == "" || fargate.Tier == "" {
+		t.Fatalf("no id or tier in the vpc variable: %+v", vpc)
+	}
+	return fargate
+}
+
+// ValidFargateCpuMemory tells if the task cpu units and memory in MiB are a combination supported by fargate
+func ValidFargateCpuMemory(cpu, memory int) bool {
+	memories, ok := fargateMemories[cpu]
+	if !ok || memory < memories.min || memory > memories.max {
+		return false
+	}
+	if memories.step == 0 {
+		return memory == 512 || memory%1024 == 0
+	}
+	return (memory-memories.min)%memories.step == 0
+}
+
+func (c *Client) ValidateFargate(t *testing.T, clusterName, serviceName string, fargate FargateTest) {
+	util.RunTestStage(t, "validate_fargate", func() {
+		c.options.Logger.Logf(t, "fargate :: %+v", fargate)
+		util.ReportResource(t, "ecs_cluster", clusterName)
+		util.ReportResource(t, "ecs_service", serviceName)
+		sess := c.Session(t)
+		ecsClient := ecs.New(sess)
+		if err := ValidateFargateE(ecsClient, ec2.New(sess), DescribeRuntimePlatform(ecsClient), clusterName, serviceName, fargate); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// ValidateFargateE checks the launch type or the fargate capacity providers, the runtime platform, the cpu and memory of the task
+// and the awsvpc networking of the service in the subnets of the tier
+func ValidateFargateE(ecsClient ecsiface.ECSAPI, ec2Client ec2iface.EC2API, describeRuntimePlatform RuntimePlatformDescriber, clusterName, serviceName string, fargate FargateTest) error {
+	clusters, err := ecsClient.DescribeClusters(&ecs.DescribeClustersInput{Clusters: []*string{awsSDK.String(clusterName)}})
+	if err != nil {
+		return fmt.Errorf("describe cluster %s: %w", clusterName, err)
+	}
+	if len(clusters.Clusters) != 1 {
+		return fmt.Errorf("cluster %s not found", clusterName)
+	}
+	services, err := ecsClient.DescribeServices(&ecs.DescribeServicesInput{Cluster: awsSDK.String(clusterName), Services: []*string{awsSDK.String(serviceName)}})
+	if err != nil {
+		return fmt.Errorf("describe service %s: %w", serviceName, err)
+	}
+	if len(services.Services) != 1 {
+		return fmt.Errorf("service %s not found in cluster %s", serviceName, clusterName)
+	}
+	service := services.Services[0]
+	taskDefinitionOutput, err := ecsClient.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{TaskDefinition: service.TaskDefinition})
+	if err != nil {
+		return fmt.Errorf("describe task definition of service %s: %w", serviceName, err)
+	}
+	taskDefinition := taskDefinitionOutput.TaskDefinition
+
+	problems := []string{}
+
+	// launch type or capacity providers
+	strategyItems := capacityProviderStrategy(clusters.Clusters[0], service)
+	if launchType := awsSDK.StringValue(service.LaunchType); launchType != "" && launchType != ecs.LaunchTypeFargate {
+		problems = append(problems, fmt.Sprintf("launch type %s, expected %s", launchType, ecs.LaunchTypeFargate))
+	} else if launchType == "" {
+		for name := range strategyItems {
+			if name != fargateCapacityProviders[CapacityTypeOnDemand] && name != fargateCapacityProviders[CapacityTypeSpot] {
+				problems = append(problems, fmt.Sprintf("capacity provider %s, expected FARGATE or FARGATE_SPOT", name))
+			}
+		}
+	}
+	for _, capacity := range fargate.Capacities {
+		name := fargateCapacityProviders[capacity.Type]
+		item, ok := strategyItems[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("capacity provider %s: not in the strategy of the service", name))
+			continue
+		}
+		if base := awsSDK.Int64Value(item.Base); base != int64(capacity.Base) {
+			problems = append(problems, fmt.Sprintf("capacity provider %s: base %d, expected %d", name, base, capacity.Base))
+		}
+		if weight := awsSDK.Int64Value(item.Weight); weight != int64(capacity.Weight) {
+			problems = append(problems, fmt.Sprintf("capacity provider %s: weight %d, expected %d", name, weight, capacity.Weight))
+		}
+	}
+
+	// task definition, fargate defaults to linux on x86_64 without runtime platform
+	if !contains(awsSDK.StringValueSlice(taskDefinition.RequiresCompatibilities), ecs.CompatibilityFargate) {
+		problems = append(problems, fmt.Sprintf("task definition requires compatibilities %v, expected %s", awsSDK.StringValueSlice(taskDefinition.RequiresCompatibilities), ecs.CompatibilityFargate))
+	}
+	platform, err := describeRuntimePlatform(awsSDK.StringValue(taskDefinition.TaskDefinitionArn))
+	if err != nil {
+		return fmt.Errorf("describe runtime platform of task definition %s: %w", awsSDK.StringValue(taskDefinition.TaskDefinitionArn), err)
+	}
+	operatingSystemFamily, cpuArchitecture := "LINUX", "X86_64"
+	if platform != nil {
+		operatingSystemFamily = awsSDK.StringValue(platform.OperatingSystemFamily)
+		cpuArchitecture = awsSDK.StringValue(platform.CpuArchitecture)
+	}
+	if expected := strings.ToUpper(fargate.Os); operatingSystemFamily != expected {
+		problems = append(problems, fmt.Sprintf("operating system family %s, expected %s", operatingSystemFamily, expected))
+	}
+	if expected := strings.ToUpper(fargate.Architecture); cpuArchitecture != expected {
+		problems = append(problems, fmt.Sprintf("cpu architecture %s, expected %s", cpuArchitecture, expected))
+	}
+	cpu, cpuErr := strconv.Atoi(awsSDK.StringValue(taskDefinition.Cpu))
+	memory, memoryErr := strconv.Atoi(awsSDK.StringValue(taskDefinition.Memory))
+	if cpuErr != nil || memoryErr != nil || !ValidFargateCpuMemory(cpu, memory) {
+		problems = append(problems, fmt.Sprintf("task cpu %q and memory %q is not a fargate combination", awsSDK.StringValue(taskDefinition.Cpu), awsSDK.StringValue(taskDefinition.Memory)))
+	}
+
+	// networking
+	if networkMode := awsSDK.StringValue(taskDefinition.NetworkMode); networkMode != ecs.NetworkModeAwsvpc {
+		problems = append(problems, fmt.Sprintf("network mode %s, expected %s", networkMode, ecs.NetworkModeAwsvpc))
+	}
+	if service.NetworkConfiguration == nil || service.NetworkConfiguration.AwsvpcConfiguration == nil {
+		problems = append(problems, "no awsvpc configuration in the service")
+	} else {
+		expectedSubnets, err := tierSubnets(ec2Client, fargate.VpcId, fargate.Tier)
+		if err != nil {
+			return err
+		}
+		subnets := awsSDK.StringValueSlice(service.NetworkConfiguration.AwsvpcConfiguration.Subnets)
+		sort.Strings(subnets)
+		if strings.Join(subnets, ",") != strings.Join(expectedSubnets, ",") {
+			problems = append(problems, fmt.Sprintf("subnets %v, expected %v of tier %s", subnets, expectedSubnets, fargate.Tier))
+		}
+		// without public ip the tasks of a public tier cannot pull images
+		if assignPublicIp := awsSDK.StringValue(service.NetworkConfiguration.AwsvpcConfiguration.AssignPublicIp); fargate.Tier == "public" && assignPublicIp != ecs.AssignPublicIpEnabled {
+			problems = append(problems, fmt.Sprintf("assign public ip %s, expected %s in tier %s", assignPublicIp, ecs.AssignPublicIpEnabled, fargate.Tier))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("fargate validation of service %s failed:\n%s", serviceName, strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// tierSubnets returns the sorted subnets of the vpc with the `Tier` tag
+func tierSubnets(client ec2iface.EC2API, vpcId, tier string) ([]string, error) {
+	subnets := []string{}
+	err := client.DescribeSubnetsPages(&ec2.DescribeSubnetsInput{Filters: []*ec2.Filter{
+		{Name: awsSDK.String("vpc-id"), Values: []*string{awsSDK.String(vpcId)}},
+		{Name: awsSDK.String("tag:Tier"), Values: []*string{awsSDK.String(tier)}},
+	}}, func(page *ec2.DescribeSubnetsOutput, lastPage bool) bool {
+		for _, subnet := range page.Subnets {
+			subnets = append(subnets, awsSDK.StringValue(subnet.SubnetId))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describe subnets of vpc %s in tier %s: %w", vpcId, tier, err)
+	}
+	sort.Strings(subnets)
+	return subnets, nil
+}
@@ -0,0 +1,203 @@
+package module
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// same as the scraper fargate scenarios
+var fakeScraperFargate = FargateTest{
+	Os:           "linux",
+	Architecture: "x86_64",
+	VpcId:        "vpc-0",
+	Tier:         "public",
+}
+
+func setupFakeFargate() (*fakeEcs, *fakeEc2, *RuntimePlatform) {
+	ecsClient, service, taskDefinition := newFakeEcsService()
+	service.LaunchType = awsSDK.String(ecs.LaunchTypeFargate)
+	service.NetworkConfiguration = &ecs.NetworkConfiguration{AwsvpcConfiguration: &ecs.AwsVpcConfiguration{
+		Subnets:        awsSDK.StringSlice([]string{"subnet-b", "subnet-a"}),
+		AssignPublicIp: awsSDK.String(ecs.AssignPublicIpEnabled),
+	}}
+	taskDefinition.RequiresCompatibilities = awsSDK.StringSlice([]string{ecs.CompatibilityFargate})
+	taskDefinition.NetworkMode = awsSDK.String(ecs.NetworkModeAwsvpc)
+	taskDefinition.Cpu = awsSDK.String("512")
+	taskDefinition.Memory = awsSDK.String("1024")
+
+	ec2Client := newFakeEc2()
+	ec2Client.putSubnet("vpc-0", "subnet-a", "public")
+	ec2Client.putSubnet("vpc-0", "subnet-b", "public")
+	ec2Client.putSubnet("vpc-0", "subnet-c", "private")
+	ec2Client.putSubnet("vpc-1", "subnet-d", "public")
+
+	return ecsClient, ec2Client, &RuntimePlatform{OperatingSystemFamily: awsSDK.String("LINUX"), CpuArchitecture: awsSDK.String("X86_64")}
+}
+
+func describeFakeRuntimePlatform(platform *RuntimePlatform) RuntimePlatformDescriber {
+	return func(taskDefinitionArn string) (*RuntimePlatform, error) {
+		if taskDefinitionArn != fakeTaskDefinitionArn {
+			return nil, fmt.Errorf("unexpected task definition %s", taskDefinitionArn)
+		}
+		return platform, nil
+	}
+}
+
+func Test_Unit_FargateFromVars(t *testing.T) {
+	vars := map[string]any{
+		"vpc": map[string]any{"id": "vpc-0", "tier": "public"},
+		"microservice": map[string]any{"container": map[string]any{"group": map[string]any{
+			"fargate": map[string]any{"architecture": "arm64", "capacities": []map[string]any{{"type": "SPOT", "base": nil}}},
+		}}},
+	}
+	expected := FargateTest{Os: "linux", Architecture: "arm64", Capacities: []CapacityTest{{Type: CapacityTypeSpot, Weight: 1}}, VpcId: "vpc-0", Tier: "public"}
+	if fargate := FargateFromVars(t, vars); !reflect.DeepEqual(expected, fargate) {
+		t.Fatalf("expected %+v, got %+v", expected, fargate)
+	}
+}
+
+func Test_Unit_ValidFargateCpuMemory(t *testing.T) {
+	for _, combination := range []struct {
+		cpu, memory int
+		valid       bool
+	}{
+		{256, 512, true},
+		{256, 2048, true},
+		{256, 1536, false},
+		{512, 4096, true},
+		{512, 512, false},
+		{4096, 30720, true},
+		{8192, 20480, true},
+		{8192, 18432, false},
+		{16384, 122880, true},
+		{300, 1024, false},
+	} {
+		if valid := ValidFargateCpuMemory(combination.cpu, combination.memory); valid != combination.valid {
+			t.Errorf("cpu %d memory %d: expected valid %t", combination.cpu, combination.memory, combination.valid)
+		}
+	}
+}
+
+func Test_Unit_DescribeRuntimePlatform(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if target := r.Header.Get("X-Amz-Target"); !strings.HasSuffix(target, ".DescribeTaskDefinition") {
+			t.Errorf("unexpected target %s", target)
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		fmt.Fprintf(w, `{"taskDefinition":{"taskDefinitionArn":%q,"runtimePlatform":{"operatingSystemFamily":"LINUX","cpuArchitecture":"ARM64"}}}`, fakeTaskDefinitionArn)
+	}))
+	defer server.Close()
+
+	session, err := session.NewSession(&awsSDK.Config{
+		Endpoint:    awsSDK.String(server.URL),
+		Region:      awsSDK.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	platform, err := DescribeRuntimePlatform(ecs.New(session))(fakeTaskDefinitionArn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &RuntimePlatform{OperatingSystemFamily: awsSDK.String("LINUX"), CpuArchitecture: awsSDK.String("ARM64")}
+	if !reflect.DeepEqual(expected, platform) {
+		t.Fatalf("expected %+v, got %+v", expected, platform)
+	}
+}
+
+func Test_Unit_ValidateFargate(t *testing.T) {
+	ecsClient, ec2Client, platform := setupFakeFargate()
+	if err := ValidateFargateE(ecsClient, ec2Client, describeFakeRuntimePlatform(platform), fakeClusterName, fakeServiceName, fakeScraperFargate); err != nil {
+		t.Fatal(err)
+	}
+
+	// capacity providers instead of the launch type, without runtime platform
+	service := ecsClient.services[fakeClusterName+"/"+fakeServiceName]
+	service.LaunchType = nil
+	service.CapacityProviderStrategy = []*ecs.CapacityProviderStrategyItem{{CapacityProvider: awsSDK.String("FARGATE_SPOT"), Base: awsSDK.Int64(1), Weight: awsSDK.Int64(50)}}
+	fargate := fakeScraperFargate
+	fargate.Capacities = []CapacityTest{{Type: CapacityTypeSpot, Base: 1, Weight: 50}}
+	if err := ValidateFargateE(ecsClient, ec2Client, describeFakeRuntimePlatform(nil), fakeClusterName, fakeServiceName, fargate); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Unit_ValidateFargate_Errors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		setup   func(service *ecs.Service, taskDefinition *ecs.TaskDefinition, platform *RuntimePlatform, fargate *FargateTest)
+		errMsgs []string
+	}{
+		{
+			name: "launch type",
+			setup: func(service *ecs.Service, taskDefinition *ecs.TaskDefinition, platform *RuntimePlatform, fargate *FargateTest) {
+				service.LaunchType = awsSDK.String(ecs.LaunchTypeEc2)
+				taskDefinition.RequiresCompatibilities = awsSDK.StringSlice([]string{ecs.CompatibilityEc2})
+			},
+			errMsgs: []string{"launch type EC2, expected FARGATE", "requires compatibilities [EC2], expected FARGATE"},
+		},
+		{
+			name: "capacity provider",
+			setup: func(service *ecs.Service, taskDefinition *ecs.TaskDefinition, platform *RuntimePlatform, fargate *FargateTest) {
+				service.LaunchType = nil
+				service.CapacityProviderStrategy = []*ecs.CapacityProviderStrategyItem{{CapacityProvider: awsSDK.String("vi-rest-test-ON_DEMAND"), Weight: awsSDK.Int64(1)}}
+				fargate.Capacities = []CapacityTest{{Type: CapacityTypeOnDemand, Weight: 1}}
+			},
+			errMsgs: []string{"capacity provider vi-rest-test-ON_DEMAND, expected FARGATE or FARGATE_SPOT", "capacity provider FARGATE: not in the strategy"},
+		},
+		{
+			name: "runtime platform",
+			setup: func(service *ecs.Service, taskDefinition *ecs.TaskDefinition, platform *RuntimePlatform, fargate *FargateTest) {
+				platform.CpuArchitecture = awsSDK.String("ARM64")
+			},
+			errMsgs: []string{"cpu architecture ARM64, expected X86_64"},
+		},
+		{
+			name: "cpu memory",
+			setup: func(service *ecs.Service, taskDefinition *ecs.TaskDefinition, platform *RuntimePlatform, fargate *FargateTest) {
+				taskDefinition.Memory = awsSDK.String("512")
+			},
+			errMsgs: []string{`task cpu "512" and memory "512" is not a fargate combination`},
+		},
+		{
+			name: "networking",
+			setup: func(service *ecs.Service, taskDefinition *ecs.TaskDefinition, platform *RuntimePlatform, fargate *FargateTest) {
+				taskDefinition.NetworkMode = awsSDK.String(ecs.NetworkModeBridge)
+				service.NetworkConfiguration.AwsvpcConfiguration.Subnets = awsSDK.StringSlice([]string{"subnet-a", "subnet-c"})
+				service.NetworkConfiguration.AwsvpcConfiguration.AssignPublicIp = awsSDK.String(ecs.AssignPublicIpDisabled)
+			},
+			errMsgs: []string{
+				"network mode bridge, expected awsvpc",
+				"subnets [subnet-a subnet-c], expected [subnet-a subnet-b] of tier public",
+				"assign public ip DISABLED, expected ENABLED in tier public",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ecsClient, ec2Client, platform := setupFakeFargate()
+			fargate := fakeScraperFargate
+			testCase.setup(ecsClient.services[fakeClusterName+"/"+fakeServiceName], ecsClient.taskDefinitions[fakeTaskDefinitionArn], platform, &fargate)
+			err := ValidateFargateE(ecsClient, ec2Client, describeFakeRuntimePlatform(platform), fakeClusterName, fakeServiceName, fargate)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, errMsg := range testCase.errMsgs {
+				if !strings.Contains(err.Error(), errMsg) {
+					t.Errorf("expected error containing %q, got %v", errMsg, err)
+				}
+			}
+		})
+	}
+}
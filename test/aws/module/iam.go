@@ -7,9 +7,6 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/iam"
-	terratestAws "github.com/gruntwork-io/terratest/modules/aws"
-	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
-	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
 )
 
 type GroupInfo struct {
@@ -18,39 +15,39 @@ type GroupInfo struct {
 	ExternalAssumeRoles []string
 }
 
-func ValidateLevel(t *testing.T, accountRegion, prefixName string, groups ...GroupInfo) {
-	terratestStructure.RunTestStage(t, "validate_level", func() {
+func (c *Client) ValidateLevel(t *testing.T, prefixName string, groups ...GroupInfo) {
+	util.RunTestStage(t, "validate_level", func() {
 		for _, group := range groups {
-			ValidateGroup(t, accountRegion, prefixName, group)
+			c.ValidateGroup(t, prefixName, group)
 		}
 	})
 }
 
-func ValidateGroup(t *testing.T, accountRegion, prefixName string, group GroupInfo) {
-	terratestStructure.RunTestStage(t, "validate_group", func() {
-		terratestStructure.RunTestStage(t, "validate_group_role", func() {
+func (c *Client) ValidateGroup(t *testing.T, prefixName string, group GroupInfo) {
+	util.RunTestStage(t, "validate_group", func() {
+		util.RunTestStage(t, "validate_group_role", func() {
 			accessRoleNames := group.ExternalAssumeRoles
 
 			for _, accessRoleName := range accessRoleNames {
 				groupName := util.Format("-", prefixName, group.Name, accessRoleName)
-				groupRoleArn := TestRole(t, accountRegion, groupName)
+				groupRoleArn := c.TestRole(t, groupName)
 				if groupRoleArn == nil {
 					t.Fatalf("no groupRoleArn for groupName: %s", groupName)
 				}
 			}
 		})
 
-		terratestStructure.RunTestStage(t, "validate_group_permissions", func() {
+		util.RunTestStage(t, "validate_group_permissions", func() {
 			userNames := util.Reduce(group.Users, func(resource map[string]any) string { return resource["name"].(string) })
 			groupName := util.Format("-", prefixName, group.Name)
-			groupArn := TestGroup(t, accountRegion, groupName, userNames)
+			groupArn := c.TestGroup(t, groupName, userNames)
 			if groupArn == nil {
 				t.Fatalf("no groupArn for groupName: %s", groupName)
 			}
 
 			for _, userName := range userNames {
 				// userName := util.Format("-",groupName, userName)
-				userArn := TestUser(t, accountRegion, userName)
+				userArn := c.TestUser(t, userName)
 				if userArn == nil {
 					t.Fatalf("no userArn for userName: %s", userName)
 				}
@@ -61,13 +58,9 @@ func ValidateGroup(t *testing.T, accountRegion, prefixName string, group GroupIn
 	})
 }
 
-func TestUser(t *testing.T, accountRegion, userName string) *string {
-	iamClient, err := terratestAws.NewIamClientE(t, accountRegion)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	terratestLogger.Log(t, "user:: "+userName)
+func (c *Client) TestUser(t *testing.T, userName string) *string {
+	iamClient := iam.New(c.Session(t))
+	c.options.Logger.Logf(t, "user:: %s", userName)
 	user, err := iamClient.GetUser(&iam.GetUserInput{UserName: aws.String(userName)})
 	if err != nil {
 		t.Fatal(err)
@@ -75,13 +68,9 @@ func TestUser(t *testing.T, accountRegion, userName string) *string {
 	return user.User.Arn
 }
 
-func TestGroup(t *testing.T, accountRegion, groupName string, userNames []string) *string {
-	iamClient, err := terratestAws.NewIamClientE(t, accountRegion)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	terratestLogger.Log(t, "group users:: "+groupName)
+func (c *Client) TestGroup(t *testing.T, groupName string, userNames []string) *string {
+	iamClient := iam.New(c.Session(t))
+	c.options.Logger.Logf(t, "group users:: %s", groupName)
 	group, err := iamClient.GetGroup(&iam.GetGroupInput{GroupName: aws.String(groupName)})
 	if err != nil {
 		t.Fatal(err)
@@ -92,7 +81,7 @@ func TestGroup(t *testing.T, accountRegion, groupName string, userNames []string
 	}
 
 	// FIXME: not found
-	// terratestLogger.Log(t, "group policy:: "+groupName)
+	// c.options.Logger.Logf(t, "group policy:: %s", groupName)
 	// groupPolicy, err := iamClient.GetGroupPolicy(&iam.GetGroupPolicyInput{GroupName: aws.String(groupName), PolicyName: aws.String(groupName)})
 	// if err != nil {
 	// 	t.Fatal(err)
@@ -105,13 +94,9 @@ func TestGroup(t *testing.T, accountRegion, groupName string, userNames []string
 	return group.Group.Arn
 }
 
-func TestRole(t *testing.T, accountRegion, roleName string) *string {
-	iamClient, err := terratestAws.NewIamClientE(t, accountRegion)
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	terratestLogger.Log(t, "role:: "+roleName)
+func (c *Client) TestRole(t *testing.T, roleName string) *string {
+	iamClient := iam.New(c.Session(t))
+	c.options.Logger.Logf(t, "role:: %s", roleName)
 	role, err := iamClient.GetRole(&iam.GetRoleInput{RoleName: aws.String(util.Format("-", roleName))})
 	if err != nil {
 		t.Fatal(err)
@@ -0,0 +1,33 @@
+package module
+
+import (
+	"fmt"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi/resourcegroupstaggingapiiface"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+// TaggedIDInUse returns true when resources of the region are tagged with the id in `TestID`
+func (c *Client) TaggedIDInUse() util.IDInUse {
+	return func(id string) (bool, error) {
+		sess, err := c.SessionE()
+		if err != nil {
+			return false, err
+		}
+		return TaggedIDInUseE(resourcegroupstaggingapi.New(sess), id)
+	}
+}
+
+func TaggedIDInUseE(client resourcegroupstaggingapiiface.ResourceGroupsTaggingAPIAPI, id string) (bool, error) {
+	output, err := client.GetResources(&resourcegroupstaggingapi.GetResourcesInput{
+		TagFilters:       []*resourcegroupstaggingapi.TagFilter{{Key: awsSDK.String("TestID"), Values: []*string{awsSDK.String(id)}}},
+		ResourcesPerPage: awsSDK.Int64(1),
+	})
+	if err != nil {
+		return false, fmt.Errorf("get resources tagged with TestID %s: %w", id, err)
+	}
+	return len(output.ResourceTagMappingList) > 0, nil
+}
@@ -0,0 +1,26 @@
+package module
+
+import (
+	"testing"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+func Test_Unit_TaggedIDInUse(t *testing.T) {
+	client := &fakeTagging{resources: map[string][]string{"abcd": {"arn:aws:ecs:us-east-1:1:cluster/vi-ms-rest-dev-abcd"}}}
+	for id, expected := range map[string]bool{"abcd": true, "efgh": false} {
+		used, err := TaggedIDInUseE(client, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used != expected {
+			t.Errorf("id %s in use %t, expected %t", id, used, expected)
+		}
+	}
+
+	id, err := util.TestIDE(7, t.Name(), 4, func(id string) (bool, error) { return TaggedIDInUseE(client, id) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, 4, len(id))
+}
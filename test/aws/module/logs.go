@@ -0,0 +1,212 @@
+package module
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	terratestTesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+// DefaultForbiddenLogPatterns are the crashes of the usual runtimes, go, python and the kernel or the jvm out of memory
+var DefaultForbiddenLogPatterns = []string{
+	`panic: `,
+	`Traceback \(most recent call last\)`,
+	`(?i)out of memory|OutOfMemoryError|OOMKilled`,
+}
+
+// LogPatternsTest are the regular expressions expected in the logs of the containers of a service
+type LogPatternsTest struct {
+	// each one must appear in the logs of at least one container
+	Required []string
+	// none can appear, DefaultForbiddenLogPatterns when nil
+	Forbidden []string
+	// only the events more recent than the window are searched, 30 minutes by default
+	Window *time.Duration
+}
+
+// LogsFromTaskDefinitionE returns the log group and stream prefix of each container of the service with the awslogs driver,
+// the streams are named `<prefix>/<container>/<task id>`
+func LogsFromTaskDefinitionE(client ecsiface.ECSAPI, clusterName, serviceName string) ([]LogTest, error) {
+	services, err := client.DescribeServices(&ecs.DescribeServicesInput{Cluster: awsSDK.String(clusterName), Services: []*string{awsSDK.String(serviceName)}})
+	if err != nil {
+		return nil, fmt.Errorf("describe service %s: %w", serviceName, err)
+	}
+	if len(services.Services) != 1 {
+		return nil, fmt.Errorf("service %s not found in cluster %s", serviceName, clusterName)
+	}
+	taskDefinition, err := client.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{TaskDefinition: services.Services[0].TaskDefinition})
+	if err != nil {
+		return nil, fmt.Errorf("describe task definition of service %s: %w", serviceName, err)
+	}
+
+	logs := []LogTest{}
+	for _, container := range taskDefinition.TaskDefinition.ContainerDefinitions {
+		configuration := container.LogConfiguration
+		if configuration == nil || awsSDK.StringValue(configuration.LogDriver) != ecs.LogDriverAwslogs {
+			continue
+		}
+		group := awsSDK.StringValue(configuration.Options["awslogs-group"])
+		if group == "" {
+			return nil, fmt.Errorf("container %s: no awslogs-group", awsSDK.StringValue(container.Name))
+		}
+		logs = append(logs, LogTest{
+			Group:  group,
+			Stream: util.Format("/", awsSDK.StringValue(configuration.Options["awslogs-stream-prefix"]), awsSDK.StringValue(container.Name)),
+		})
+	}
+	if len(logs) == 0 {
+		return nil, fmt.Errorf("no container with the awslogs driver in the task definition of service %s", serviceName)
+	}
+	return logs, nil
+}
+
+func (c *Client) ValidateLogs(t *testing.T, clusterName, serviceName string, patterns LogPatternsTest, deployment DeploymentTest) {
+	util.RunTestStage(t, "validate_logs", func() {
+		c.options.Logger.Logf(t, "log patterns :: %+v", patterns)
+		util.ReportResource(t, "ecs_cluster", clusterName)
+		util.ReportResource(t, "ecs_service", serviceName)
+		sess := c.Session(t)
+		poll := c.poll(deployment.MaxRetries, deployment.SleepBetweenRetries)
+		if err := ValidateLogsE(t, c.options.Logger, ecs.New(sess), cloudwatchlogs.New(sess), clusterName, serviceName, patterns, poll); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// ValidateLogsE waits for the streams of the containers, then for the required patterns,
+// it returns as soon as a forbidden pattern appears
+func ValidateLogsE(t terratestTesting.TestingT, logger *terratestLogger.Logger, ecsClient ecsiface.ECSAPI, logsClient cloudwatchlogsiface.CloudWatchLogsAPI, clusterName, serviceName string, patterns LogPatternsTest, poll PollPolicy) error {
+	logs, err := LogsFromTaskDefinitionE(ecsClient, clusterName, serviceName)
+	if err != nil {
+		return err
+	}
+	required, err := compilePatterns(patterns.Required)
+	if err != nil {
+		return err
+	}
+	forbiddenPatterns := patterns.Forbidden
+	if forbiddenPatterns == nil {
+		forbiddenPatterns = DefaultForbiddenLogPatterns
+	}
+	forbidden, err := compilePatterns(forbiddenPatterns)
+	if err != nil {
+		return err
+	}
+	window := 30 * time.Minute
+	if patterns.Window != nil {
+		window = *patterns.Window
+	}
+
+	for _, log := range logs {
+		util.ReportResource(t, "log_group", log.Group)
+	}
+
+	found := map[string]bool{}
+	for i := 0; i <= poll.MaxRetries; i++ {
+		startTime := time.Now().Add(-window).UnixMilli()
+		missingStreams := []string{}
+		for _, log := range logs {
+			streams, err := logStreams(logsClient, log)
+			if err != nil {
+				return err
+			}
+			if len(streams) == 0 {
+				missingStreams = append(missingStreams, log.Group+":"+log.Stream)
+				continue
+			}
+
+			var forbiddenErr error
+			err = logsClient.FilterLogEventsPages(&cloudwatchlogs.FilterLogEventsInput{
+				LogGroupName:        awsSDK.String(log.Group),
+				LogStreamNamePrefix: awsSDK.String(log.Stream + "/"),
+				StartTime:           awsSDK.Int64(startTime),
+			}, func(page *cloudwatchlogs.FilterLogEventsOutput, lastPage bool) bool {
+				for _, event := range page.Events {
+					message := awsSDK.StringValue(event.Message)
+					for _, pattern := range forbidden {
+						if pattern.MatchString(message) {
+							forbiddenErr = fmt.Errorf("forbidden pattern %q in %s:%s: %s", pattern, log.Group, awsSDK.StringValue(event.LogStreamName), strings.TrimSpace(message))
+							return false
+						}
+					}
+					for _, pattern := range required {
+						if pattern.MatchString(message) {
+							found[pattern.String()] = true
+						}
+					}
+				}
+				return true
+			})
+			if err != nil {
+				return fmt.Errorf("filter log events %s:%s: %w", log.Group, log.Stream, err)
+			}
+			if forbiddenErr != nil {
+				return forbiddenErr
+			}
+		}
+
+		missingPatterns := []string{}
+		for _, pattern := range required {
+			if !found[pattern.String()] {
+				missingPatterns = append(missingPatterns, fmt.Sprintf("%q", pattern))
+			}
+		}
+		if len(missingStreams) == 0 && len(missingPatterns) == 0 {
+			logger.Logf(t, "Logs successful")
+			return nil
+		}
+		logger.Logf(t, `
+		missing streams:: %v
+		missing patterns:: %v
+		`, missingStreams, missingPatterns)
+		if i == poll.MaxRetries {
+			return fmt.Errorf("logs of service %s unsuccessful after %d retries: missing streams %v, missing patterns %v", serviceName, poll.MaxRetries, missingStreams, missingPatterns)
+		}
+		logger.Logf(t, "Sleeping %s...", poll.SleepBetweenRetries)
+		util.ReportRetry(t)
+		time.Sleep(poll.SleepBetweenRetries)
+	}
+	return nil
+}
+
+func logStreams(client cloudwatchlogsiface.CloudWatchLogsAPI, log LogTest) ([]string, error) {
+	streams := []string{}
+	err := client.DescribeLogStreamsPages(&cloudwatchlogs.DescribeLogStreamsInput{
+		LogGroupName:        awsSDK.String(log.Group),
+		LogStreamNamePrefix: awsSDK.String(log.Stream + "/"),
+	}, func(page *cloudwatchlogs.DescribeLogStreamsOutput, lastPage bool) bool {
+		for _, stream := range page.LogStreams {
+			streams = append(streams, awsSDK.StringValue(stream.LogStreamName))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describe log streams %s:%s: %w", log.Group, log.Stream, err)
+	}
+	sort.Strings(streams)
+	return streams, nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("log pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,103 @@
+package module
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+func Test_Unit_LogsFromTaskDefinition(t *testing.T) {
+	ecsClient, _, _ := newFakeEcsService()
+	logs, err := LogsFromTaskDefinitionE(ecsClient, fakeClusterName, fakeServiceName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []LogTest{{Group: fakeLogGroup, Stream: "ecs/unique"}}; !reflect.DeepEqual(expected, logs) {
+		t.Fatalf("expected %+v, got %+v", expected, logs)
+	}
+}
+
+func Test_Unit_ValidateLogs(t *testing.T) {
+	ecsClient, _, _ := newFakeEcsService()
+	logsClient := newFakeCloudWatchLogs()
+	// the stream appears on the second retry, the startup line on the third
+	logsClient.onDescribe = func(f *fakeCloudWatchLogs, call int) {
+		switch call {
+		case 2:
+			f.putEvent(fakeLogGroup, fakeLogStream, time.Now(), "apt update")
+			// out of the window
+			f.putEvent(fakeLogGroup, fakeLogStream, time.Now().Add(-time.Hour), "panic: previous deployment")
+		case 3:
+			f.putEvent(fakeLogGroup, fakeLogStream, time.Now(), "<title>Apache2 Ubuntu Default Page: It works</title>")
+		}
+	}
+	patterns := LogPatternsTest{Required: []string{`Apache2 (Ubuntu )?Default Page`}, Window: util.Ptr(10 * time.Minute)}
+	if err := ValidateLogsE(t, terratestLogger.Discard, ecsClient, logsClient, fakeClusterName, fakeServiceName, patterns, PollPolicy{MaxRetries: 5}); err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, 3, logsClient.describeCalls)
+	util.Equal(t, 2, logsClient.filterCalls)
+}
+
+func Test_Unit_ValidateLogs_Errors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		messages    []string
+		patterns    LogPatternsTest
+		filterCalls int
+		errMsg      string
+	}{
+		{
+			name:        "no stream",
+			patterns:    LogPatternsTest{},
+			filterCalls: 0,
+			errMsg:      "missing streams [" + fakeLogGroup + ":ecs/unique], missing patterns []",
+		},
+		{
+			name:        "missing pattern",
+			messages:    []string{"Torchserve is starting"},
+			patterns:    LogPatternsTest{Required: []string{`Torchserve version`}},
+			filterCalls: 3,
+			errMsg:      `missing patterns ["Torchserve version"]`,
+		},
+		{
+			name:        "traceback",
+			messages:    []string{"Torchserve version: 0.8.0", "Traceback (most recent call last):"},
+			patterns:    LogPatternsTest{Required: []string{`Torchserve version`}},
+			filterCalls: 1,
+			errMsg:      `forbidden pattern "Traceback \\(most recent call last\\)" in ` + fakeLogGroup + ":" + fakeLogStream,
+		},
+		{
+			name:        "custom forbidden",
+			messages:    []string{"java.lang.OutOfMemoryError: Java heap space", "AH00558: apache2: Could not reliably determine"},
+			patterns:    LogPatternsTest{Forbidden: []string{`AH\d+`}},
+			filterCalls: 1,
+			errMsg:      `forbidden pattern "AH\\d+"`,
+		},
+		{
+			name:     "invalid pattern",
+			patterns: LogPatternsTest{Required: []string{`(`}},
+			errMsg:   `log pattern "("`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ecsClient, _, _ := newFakeEcsService()
+			logsClient := newFakeCloudWatchLogs()
+			for _, message := range testCase.messages {
+				logsClient.putEvent(fakeLogGroup, fakeLogStream, time.Now(), message)
+			}
+			err := ValidateLogsE(t, terratestLogger.Discard, ecsClient, logsClient, fakeClusterName, fakeServiceName, testCase.patterns, PollPolicy{MaxRetries: 2})
+			if err == nil || !strings.Contains(err.Error(), testCase.errMsg) {
+				t.Fatalf("expected error containing %q, got %v", testCase.errMsg, err)
+			}
+			util.Equal(t, testCase.filterCalls, logsClient.filterCalls)
+		})
+	}
+}
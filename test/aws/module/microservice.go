@@ -1,27 +1,18 @@
 package module
 
 import (
-	"crypto/tls"
 	"fmt"
+	"io"
+	"net/http"
 	"regexp"
 	"strings"
 	"testing"
 	"time"
 
-	terratest_http_helper "github.com/gruntwork-io/terratest/modules/http-helper"
-	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
 	terratestShell "github.com/gruntwork-io/terratest/modules/shell"
-	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
 	"github.com/vistimi/infrastructure-modules/test/util"
 )
 
-var (
-	AccountName   = util.GetEnvVariable("AWS_PROFILE_NAME")
-	AccountId     = util.GetEnvVariable("AWS_ACCOUNT_ID")
-	AccountRegion = util.GetEnvVariable("AWS_REGION_NAME")
-	DomainName    = fmt.Sprintf("%s.%s", util.GetEnvVariable("DOMAIN_NAME"), util.GetEnvVariable("DOMAIN_SUFFIX"))
-)
-
 const (
 	// https://docs.aws.amazon.com/AmazonECS/latest/developerguide/memory-management.html#ecs-reserved-memory
 	ECSReservedMemory = 100
@@ -44,6 +35,9 @@ type MicroserviceInformation struct {
 	Branch          string
 	HealthCheckPath string
 	Docker          Docker
+	// env files merged in order, then the variables, into the env file of the test uploaded in the env bucket
+	EnvFiles []string
+	Env      map[string]string
 }
 type Docker struct {
 	Registry   *Registry
@@ -101,28 +95,31 @@ type Traffic struct {
 	Base     *bool
 }
 
-func ValidateMicroservice(t *testing.T, name string, deployment DeploymentTest, serviceName string) {
-	terratestStructure.RunTestStage(t, "validate_microservice", func() {
+func (c *Client) ValidateMicroservice(t *testing.T, name string, deployment DeploymentTest, serviceName string) {
+	util.RunTestStage(t, "validate_microservice", func() {
 		serviceCount := int64(1)
-		ValidateEcs(t, AccountRegion, name, serviceName, serviceCount, deployment)
+		c.ValidateEcs(t, name, serviceName, serviceCount, deployment)
 	})
 }
 
-func ValidateRestEndpoints(t *testing.T, microservicePath string, deployment DeploymentTest, traffics []Traffic, name, modulePath string) {
-	terratestLogger.Log(t, "Validate Rest endpoints")
+func (c *Client) ValidateRestEndpoints(t *testing.T, microservicePath string, deployment DeploymentTest, traffics []Traffic, name, modulePath string) {
+	c.options.Logger.Logf(t, "Validate Rest endpoints")
+	if ExtractFromState(t, microservicePath, util.Format(".", modulePath, "ecs.elb")) != nil {
+		c.WaitForHealthyTargets(t, LoadBalancerArnFromState(t, microservicePath, modulePath), deployment)
+	}
 	for _, traffic := range traffics {
 		if traffic.Listener.Protocol == "http" {
 			port := util.Value(traffic.Listener.Port, 80)
 			// test Load Balancer HTTP
 			elb := ExtractFromState(t, microservicePath, util.Format(".", modulePath, "ecs.elb"))
-			terratestLogger.Log(t, fmt.Sprintf("elb :: %+v", elb))
+			c.options.Logger.Logf(t, "elb :: %+v", elb)
 			if elb != nil {
 				elbDnsUrl := elb.(map[string]any)["lb"].(map[string]any)["dns_name"].(string)
 				if elbDnsUrl == "" || elbDnsUrl == "null" {
 					t.Fatalf("ECS ELB DNS is null: %s", elbDnsUrl)
 				}
 				elbDnsUrl = fmt.Sprintf("http://%s:%d", elbDnsUrl, port)
-				fmt.Printf("\n\nLoad Balancer DNS = %s\n\n", elbDnsUrl)
+				c.options.Logger.Logf(t, "Load Balancer DNS = %s", elbDnsUrl)
 
 				// add dns to endpoints
 				endpointsLoadBalancer := []EndpointTest{}
@@ -143,21 +140,18 @@ func ValidateRestEndpoints(t *testing.T, microservicePath string, deployment Dep
 					endpointsLoadBalancer = append(endpointsLoadBalancer, newEndpoint)
 				}
 
-				terratestStructure.RunTestStage(t, "validate_rest_endpoints_load_balancer", func() {
-					TestRestEndpoints(t, endpointsLoadBalancer)
+				util.RunTestStage(t, "validate_rest_endpoints_load_balancer", func() {
+					c.TestRestEndpoints(t, endpointsLoadBalancer)
 				})
 			}
 
 			// test Route53
-			route53 := ExtractFromState(t, microservicePath, "ecs.route53")
-			terratestLogger.Log(t, fmt.Sprintf("route53 :: %+v", route53))
-			if route53 != nil {
-				records := route53.(map[string]any)["records"].(map[string]any)
-				terratestLogger.Log(t, fmt.Sprintf("records :: %+v", records))
-				if len(records) != 0 {
-					recordName := records[fmt.Sprintf("%s.%s", util.GetEnvVariable("DOMAIN_NAME"), util.GetEnvVariable("DOMAIN_SUFFIX"))].(map[string]any)["name"].(map[string]any)[name+" A"].(string)
+			records := Route53RecordsFromState(t, microservicePath, modulePath)
+			c.options.Logger.Logf(t, "records :: %+v", records)
+			for _, recordNames := range records {
+				for _, recordName := range recordNames {
 					route53DnsUrl := fmt.Sprintf("http://%s:%d", recordName, port)
-					fmt.Printf("\n\nRoute53 DNS = %s\n\n", route53DnsUrl)
+					c.options.Logger.Logf(t, "Route53 DNS = %s", route53DnsUrl)
 
 					// add dns to endpoints
 					endpointsRoute53 := []EndpointTest{}
@@ -173,8 +167,8 @@ func ValidateRestEndpoints(t *testing.T, microservicePath string, deployment Dep
 						endpointsRoute53 = append(endpointsRoute53, newEndpoint)
 					}
 
-					terratestStructure.RunTestStage(t, "validate_rest_endpoints_route53", func() {
-						TestRestEndpoints(t, endpointsRoute53)
+					util.RunTestStage(t, "validate_rest_endpoints_route53", func() {
+						c.TestRestEndpoints(t, endpointsRoute53)
 					})
 				}
 			}
@@ -186,141 +180,186 @@ func ValidateRestEndpoints(t *testing.T, microservicePath string, deployment Dep
 	}
 }
 
-func TestRestEndpoints(t *testing.T, endpoints []EndpointTest) {
-	tlsConfig := tls.Config{}
+func (c *Client) TestRestEndpoints(t *testing.T, endpoints []EndpointTest) {
+endpoints:
 	for _, endpoint := range endpoints {
 		path := endpoint.Path
-		options := terratest_http_helper.HttpGetOptions{Url: path, TlsConfig: &tlsConfig, Timeout: 10}
 		expectedBody := ""
 		if endpoint.ExpectedBody != nil {
 			expectedBody = *endpoint.ExpectedBody
 		}
-		maxRetries := 5
-		if endpoint.MaxRetries != nil {
-			maxRetries = *endpoint.MaxRetries
-		}
-		sleepBetweenRetries := 30 * time.Second
-		if endpoint.SleepBetweenRetries != nil {
-			sleepBetweenRetries = *endpoint.SleepBetweenRetries
+		poll := c.poll(endpoint.MaxRetries, endpoint.SleepBetweenRetries)
+		maxRetries, sleepBetweenRetries := poll.MaxRetries, poll.SleepBetweenRetries
+		report := util.EndpointReport{Target: path, ExpectedStatus: endpoint.ExpectedStatus}
+		if endpoint.Command != nil {
+			report.Target = util.Value(endpoint.Command)
 		}
 		for i := 0; i <= maxRetries; i++ {
+			report.Attempts = i + 1
 			if endpoint.Command != nil {
 				command := terratestShell.Command{
 					Command: "bash",
 					Args:    []string{"-c", util.Value(endpoint.Command)},
 				}
 				output := strings.TrimSpace(terratestShell.RunCommandAndGetOutput(t, command))
-				terratestLogger.Log(t, output)
+				c.options.Logger.Logf(t, "%s", output)
 				if err := util.FindE(fmt.Sprintf("%d", endpoint.ExpectedStatus), output); err == nil {
-					terratestLogger.Log(t, `Command successful`)
-					return
+					c.options.Logger.Logf(t, `Command successful`)
+					report.Passed = true
+					util.ReportEndpoint(t, report)
+					continue endpoints
 				}
 				if i == maxRetries {
+					report.Message = output
+					util.ReportEndpoint(t, report)
 					t.Fatalf(`'Command' unsuccessful after %d retries`, maxRetries)
 				}
 			} else {
-				gotStatus, gotBody := terratest_http_helper.HttpGetWithOptions(t, options)
-				terratestLogger.Log(t, fmt.Sprintf(`
+				gotStatus, gotBody, err := httpGetE(c.options.HttpClient, path)
+				if err != nil {
+					c.options.Logger.Logf(t, "HTTP GET to URL %s: %v", path, err)
+				}
+				report.Status = gotStatus
+				c.options.Logger.Logf(t, `
 					got status:: %d
 					expected status:: %d
-					`, gotStatus, endpoint.ExpectedStatus))
+					`, gotStatus, endpoint.ExpectedStatus)
 				if endpoint.ExpectedBody != nil {
-					terratestLogger.Log(t, fmt.Sprintf(`
+					c.options.Logger.Logf(t, `
 					got body:: %s
 					expected body:: %s
-					`, gotBody, expectedBody))
+					`, gotBody, expectedBody)
 				}
 				if gotStatus == endpoint.ExpectedStatus && (endpoint.ExpectedBody == nil || (endpoint.ExpectedBody != nil && gotBody == expectedBody)) {
-					terratestLogger.Log(t, `'HTTP GET to URL %s' successful`, path)
-					return
+					c.options.Logger.Logf(t, `'HTTP GET to URL %s' successful`, path)
+					report.Passed = true
+					util.ReportEndpoint(t, report)
+					continue endpoints
 				}
 				if i == maxRetries {
+					if endpoint.ExpectedBody != nil && gotBody != expectedBody {
+						report.Message = "unexpected body"
+					}
+					util.ReportEndpoint(t, report)
 					t.Fatalf(`'HTTP GET to URL %s' unsuccessful after %d retries`, path, maxRetries)
 				}
 			}
 
-			terratestLogger.Log(t, fmt.Sprintf("Sleeping %s...", sleepBetweenRetries))
+			c.options.Logger.Logf(t, "Sleeping %s...", sleepBetweenRetries)
+			util.ReportRetry(t)
 			time.Sleep(sleepBetweenRetries)
 		}
 	}
 }
 
-func ValidateGrpcEndpoints(t *testing.T, microservicePath string, deployment DeploymentTest, traffics []Traffic, name, modulePath string) {
-	terratestLogger.Log(t, "Validate gRPC endpoints")
+func (c *Client) ValidateGrpcEndpoints(t *testing.T, microservicePath string, deployment DeploymentTest, traffics []Traffic, name, modulePath string) {
+	c.options.Logger.Logf(t, "Validate gRPC endpoints")
+	if ExtractFromState(t, microservicePath, util.Format(".", modulePath, "ecs.elb")) != nil {
+		c.WaitForHealthyTargets(t, LoadBalancerArnFromState(t, microservicePath, modulePath), deployment)
+	}
 	for _, traffic := range traffics {
-		terratestLogger.Log(t, "protocol", traffic.Listener.Protocol)
+		c.options.Logger.Logf(t, "protocol %s", traffic.Listener.Protocol)
 
 		port := util.Value(traffic.Listener.Port, 443)
 
-		route53 := ExtractFromState(t, microservicePath, "ecs.route53")
-		terratestLogger.Log(t, fmt.Sprintf("route53 :: %+v", route53))
-		if route53 != nil {
-			recordName := route53.(map[string]any)["records"].(map[string]any)[fmt.Sprintf("%s.%s", util.GetEnvVariable("DOMAIN_NAME"), util.GetEnvVariable("DOMAIN_SUFFIX"))].(map[string]any)["name"].(map[string]any)[name+" A"].(string)
-			route53DnsUrl := fmt.Sprintf("%s:%d", recordName, port)
-			fmt.Printf("\n\nRoute53 DNS = %s\n\n", route53DnsUrl)
+		records := Route53RecordsFromState(t, microservicePath, modulePath)
+		c.options.Logger.Logf(t, "records :: %+v", records)
+		for _, recordNames := range records {
+			for _, recordName := range recordNames {
+				route53DnsUrl := fmt.Sprintf("%s:%d", recordName, port)
+				c.options.Logger.Logf(t, "Route53 DNS = %s", route53DnsUrl)
 
-			endpointsLoadBalancer := []EndpointTest{}
-			for _, endpoint := range deployment.Endpoints {
-				newEndpoint := endpoint
+				endpointsLoadBalancer := []EndpointTest{}
+				for _, endpoint := range deployment.Endpoints {
+					newEndpoint := endpoint
 
-				if endpoint.Command != nil {
-					re := regexp.MustCompile(`<URL>`)
-					newEndpoint.Command = util.Ptr(re.ReplaceAllString(util.Value(endpoint.Command), route53DnsUrl))
-				}
+					if endpoint.Command != nil {
+						re := regexp.MustCompile(`<URL>`)
+						newEndpoint.Command = util.Ptr(re.ReplaceAllString(util.Value(endpoint.Command), route53DnsUrl))
+					}
 
-				endpointsLoadBalancer = append(endpointsLoadBalancer, newEndpoint)
+					endpointsLoadBalancer = append(endpointsLoadBalancer, newEndpoint)
+				}
+				util.RunTestStage(t, "validate_grpc_endpoints_load_balancer", func() {
+					c.TestGrpcEndpoints(t, endpointsLoadBalancer, route53DnsUrl)
+				})
 			}
-			terratestStructure.RunTestStage(t, "validate_grpc_endpoints_load_balancer", func() {
-				TestGrpcEndpoints(t, endpointsLoadBalancer, route53DnsUrl)
-			})
 		}
 	}
 }
 
-func TestGrpcEndpoints(t *testing.T, endpoints []EndpointTest, address string) {
+func (c *Client) TestGrpcEndpoints(t *testing.T, endpoints []EndpointTest, address string) {
+endpoints:
 	for _, endpoint := range endpoints {
-		maxRetries := 5
-		if endpoint.MaxRetries != nil {
-			maxRetries = *endpoint.MaxRetries
-		}
-		sleepBetweenRetries := 30 * time.Second
-		if endpoint.SleepBetweenRetries != nil {
-			sleepBetweenRetries = *endpoint.SleepBetweenRetries
-		}
-		for i := 0; i <= maxRetries; i++ {
-			if endpoint.Command != nil {
-				command := terratestShell.Command{
-					Command: "bash",
-					Args:    []string{"-c", util.Value(endpoint.Command)},
-				}
-				output := strings.TrimSpace(terratestShell.RunCommandAndGetOutput(t, command))
-				terratestLogger.Log(t, output)
-				if i == maxRetries {
-					t.Fatalf(`'Command' unsuccessful after %d retries`, maxRetries)
-				}
-			} else {
-				paths := strings.Split(strings.TrimPrefix(endpoint.Path, "/"), "/")
-				service := paths[0]
-				method := paths[1]
+		poll := c.poll(endpoint.MaxRetries, endpoint.SleepBetweenRetries)
+		maxRetries, sleepBetweenRetries := poll.MaxRetries, poll.SleepBetweenRetries
+		report := util.EndpointReport{Target: util.Format("/", address, strings.TrimPrefix(endpoint.Path, "/"))}
+		cmd := util.Value(endpoint.Command)
+		if endpoint.Command != nil {
+			report.Target = cmd
+		} else {
+			service, method, err := grpcServiceMethod(endpoint.Path)
+			if err != nil {
+				report.Message = err.Error()
+				util.ReportEndpoint(t, report)
+				t.Fatal(err)
+			}
 
-				// cmd := fmt.Sprintf("wget https://github.com/fullstorydev/grpcurl/releases/download/v1.8.7/grpcurl_1.8.7_linux_%s.tar.gz -q; tar -xzvf grpcurl_1.8.7_linux_%s.tar.gz grpcurl; ./grpcurl -plaintext %s %s/%s", util.GetEnvVariable("ARCH"), util.GetEnvVariable("ARCH"), address, service, method)
+			arch := c.options.Arch
+			// cmd := fmt.Sprintf("wget https://github.com/fullstorydev/grpcurl/releases/download/v1.8.7/grpcurl_1.8.7_linux_%s.tar.gz -q; tar -xzvf grpcurl_1.8.7_linux_%s.tar.gz grpcurl; ./grpcurl -plaintext %s %s/%s", arch, arch, address, service, method)
 
-				request := util.Value(endpoint.Request, "{}")
-				cmd := fmt.Sprintf("curl -L https://github.com/vadimi/grpc-client-cli/releases/download/v1.18.0/grpc-client-cli_linux_%s.tar.gz | tar -xz; echo '%s' | ./grpc-client-cli -service %s -method %s %s", util.GetEnvVariable("ARCH"), request, service, method, address)
+			request := util.Value(endpoint.Request, "{}")
+			cmd = fmt.Sprintf("curl -L https://github.com/vadimi/grpc-client-cli/releases/download/v1.18.0/grpc-client-cli_linux_%s.tar.gz | tar -xz; echo '%s' | ./grpc-client-cli -service %s -method %s %s", arch, request, service, method, address)
+		}
 
-				command := terratestShell.Command{
-					Command: "bash",
-					Args:    []string{"-c", cmd},
-				}
-				output := strings.TrimSpace(terratestShell.RunCommandAndGetOutput(t, command))
-				terratestLogger.Log(t, output)
-				if i == maxRetries {
-					t.Fatalf(`gRPC unsuccessful after %d retries`, maxRetries)
-				}
+		for i := 0; i <= maxRetries; i++ {
+			report.Attempts = i + 1
+			command := terratestShell.Command{
+				Command: "bash",
+				Args:    []string{"-c", cmd},
+			}
+			// the command succeeds with its exit code, and with the expected body in its output when there is one
+			output, err := terratestShell.RunCommandAndGetOutputE(t, command)
+			output = strings.TrimSpace(output)
+			c.options.Logger.Logf(t, "%s", output)
+			if err == nil && (endpoint.ExpectedBody == nil || strings.Contains(output, *endpoint.ExpectedBody)) {
+				c.options.Logger.Logf(t, `gRPC to %s successful`, report.Target)
+				report.Passed = true
+				util.ReportEndpoint(t, report)
+				continue endpoints
+			}
+			if i == maxRetries {
+				report.Message = output
+				util.ReportEndpoint(t, report)
+				t.Fatalf(`gRPC to %s unsuccessful after %d retries`, report.Target, maxRetries)
 			}
 
-			terratestLogger.Log(t, fmt.Sprintf("Sleeping %s...", sleepBetweenRetries))
+			c.options.Logger.Logf(t, "Sleeping %s...", sleepBetweenRetries)
+			util.ReportRetry(t)
 			time.Sleep(sleepBetweenRetries)
 		}
 	}
 }
+
+// grpcServiceMethod splits the path `/<service>/<method>` of a gRPC endpoint
+func grpcServiceMethod(path string) (service, method string, err error) {
+	paths := strings.Split(strings.Trim(path, "/"), "/")
+	if len(paths) != 2 || paths[0] == "" || paths[1] == "" {
+		return "", "", fmt.Errorf("gRPC path %q: expected /<service>/<method>", path)
+	}
+	return paths[0], paths[1], nil
+}
+
+// httpGetE returns the status and the body of the response, the body is trimmed like terratest
+func httpGetE(client *http.Client, url string) (int, string, error) {
+	response, err := client.Get(url)
+	if err != nil {
+		return 0, "", err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return response.StatusCode, "", err
+	}
+	return response.StatusCode, strings.TrimSpace(string(body)), nil
+}
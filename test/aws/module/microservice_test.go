@@ -0,0 +1,99 @@
+package module
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+func newFakeEndpointsClient() *Client {
+	return NewClient(Options{Logger: terratestLogger.Discard, Poll: PollPolicy{MaxRetries: 1, SleepBetweenRetries: time.Millisecond}})
+}
+
+func Test_Unit_TestRestEndpoints(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[r.URL.Path]++
+		// the second endpoint is ready on the second call
+		if r.URL.Path == "/tags/wanted" && calls[r.URL.Path] == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `"ok"`)
+	}))
+	defer server.Close()
+
+	newFakeEndpointsClient().TestRestEndpoints(t, []EndpointTest{
+		{Path: server.URL + "/healthz", ExpectedStatus: http.StatusOK, ExpectedBody: util.Ptr(`"ok"`)},
+		{Path: server.URL + "/tags/wanted", ExpectedStatus: http.StatusOK},
+		{Command: util.Ptr(fmt.Sprintf("curl -s -o /dev/null -w '%%{http_code}' %s/command", server.URL)), ExpectedStatus: http.StatusOK},
+	})
+
+	expected := map[string]int{"/healthz": 1, "/tags/wanted": 2, "/command": 1}
+	if !reflect.DeepEqual(expected, calls) {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+}
+
+func Test_Unit_TestGrpcEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+
+	newFakeEndpointsClient().TestGrpcEndpoints(t, []EndpointTest{
+		{Command: util.Ptr(fmt.Sprintf("echo first >> %s", calls))},
+		// the second endpoint fails on the first call
+		{Command: util.Ptr(fmt.Sprintf(`echo second >> %[1]s; test -f %[2]s/ready || { touch %[2]s/ready; exit 1; }; echo '{"message": "Hello World"}'`, calls, dir)), ExpectedBody: util.Ptr("Hello World")},
+	}, "localhost:443")
+
+	content, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, "first\nsecond\nsecond", strings.TrimSpace(string(content)))
+}
+
+func Test_Unit_GrpcServiceMethod(t *testing.T) {
+	testCases := []struct {
+		path    string
+		service string
+		method  string
+		errMsg  string
+	}{
+		{path: "/helloworld.Greeter/SayHello", service: "helloworld.Greeter", method: "SayHello"},
+		{path: "helloworld.Greeter/SayHello/", service: "helloworld.Greeter", method: "SayHello"},
+		{path: "/helloworld.Greeter", errMsg: `gRPC path "/helloworld.Greeter": expected /<service>/<method>`},
+		{path: "/helloworld.Greeter/SayHello/World", errMsg: "expected /<service>/<method>"},
+		{path: "", errMsg: "expected /<service>/<method>"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.path, func(t *testing.T) {
+			service, method, err := grpcServiceMethod(testCase.path)
+			if testCase.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", testCase.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			util.Equal(t, testCase.service, service)
+			util.Equal(t, testCase.method, method)
+		})
+	}
+}
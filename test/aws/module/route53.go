@@ -0,0 +1,305 @@
+package module
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+// Route53Test is the `route53` variable of the ecs module
+type Route53Test struct {
+	Zones         []string
+	SubdomainName string
+	Prefixes      []string
+}
+
+// Records returns the names of the records expected in each zone
+func (r Route53Test) Records() map[string][]string {
+	records := map[string][]string{}
+	for _, zone := range r.Zones {
+		zone = normalizeDnsName(zone)
+		names := []string{normalizeDnsName(r.SubdomainName + "." + zone)}
+		for _, prefix := range r.Prefixes {
+			if prefix == "" {
+				continue
+			}
+			names = append(names, normalizeDnsName(prefix+"."+r.SubdomainName+"."+zone))
+		}
+		records[zone] = names
+	}
+	return records
+}
+
+// Route53FromVars returns the records of the first `route53` variable with a record
+func Route53FromVars(t *testing.T, vars map[string]any) Route53Test {
+	route53Vars := findRoute53(vars)
+	if route53Vars == nil {
+		t.Fatalf("no route53 with a record in the variables")
+	}
+	record, _ := route53Vars["record"].(map[string]any)
+
+	route53Test := Route53Test{}
+	route53Test.SubdomainName, _ = record["subdomain_name"].(string)
+	route53Test.Prefixes, _ = record["prefixes"].([]string)
+	zones, _ := route53Vars["zones"].([]map[string]any)
+	for _, zone := range zones {
+		if name, ok := zone["name"].(string); ok {
+			route53Test.Zones = append(route53Test.Zones, name)
+		}
+	}
+	if route53Test.SubdomainName == "" {
+		t.Fatalf("no subdomain_name in the route53 record: %+v", record)
+	}
+	return route53Test
+}
+
+// findRoute53 returns the first `route53` variable with a record
+func findRoute53(vars map[string]any) map[string]any {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, ok := vars[key].(map[string]any)
+		if !ok {
+			continue
+		}
+		if _, ok := value["record"].(map[string]any); ok && key == "route53" {
+			return value
+		}
+		if route53Vars := findRoute53(value); route53Vars != nil {
+			return route53Vars
+		}
+	}
+	return nil
+}
+
+// LoadBalancerAlias is the target of the alias records
+type LoadBalancerAlias struct {
+	DnsName string
+	ZoneId  string
+}
+
+func LoadBalancerAliasFromState(t *testing.T, microservicePath, modulePath string) LoadBalancerAlias {
+	elb, ok := ExtractFromState(t, microservicePath, util.Format(".", modulePath, "ecs.elb")).(map[string]any)
+	if !ok {
+		t.Fatalf("no elb in the state")
+	}
+	lb, _ := elb["lb"].(map[string]any)
+	alias := LoadBalancerAlias{}
+	alias.DnsName, _ = lb["dns_name"].(string)
+	alias.ZoneId, _ = lb["zone_id"].(string)
+	if alias.DnsName == "" || alias.ZoneId == "" {
+		t.Fatalf("no dns_name or zone_id for the elb in the state: %+v", lb)
+	}
+	return alias
+}
+
+// Route53RecordsFromState returns the names of the A records of the `ecs.route53` output for each zone
+func Route53RecordsFromState(t *testing.T, microservicePath, modulePath string) map[string][]string {
+	route53Output, ok := ExtractFromState(t, microservicePath, util.Format(".", modulePath, "ecs.route53")).(map[string]any)
+	if !ok {
+		return nil
+	}
+	records, err := Route53RecordsE(route53Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+// Route53RecordsE returns the names of the A records of the `route53` output of the ecs module for each zone
+func Route53RecordsE(route53Output map[string]any) (map[string][]string, error) {
+	records := map[string][]string{}
+	if route53Output["records"] == nil {
+		return records, nil
+	}
+	zones, ok := route53Output["records"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("route53 output: expected the records by zone, got %T", route53Output["records"])
+	}
+	for zone, zoneRecords := range zones {
+		zoneMap, ok := zoneRecords.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("route53 output: zone %s: expected a map of the records, got %T", zone, zoneRecords)
+		}
+		// the names are keyed by `<name> <type>`
+		names, ok := zoneMap["fqdn"].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("route53 output: zone %s: expected the fqdn of the records, got %T", zone, zoneMap["fqdn"])
+		}
+		for key, name := range names {
+			if strings.HasSuffix(key, " A") {
+				records[zone] = append(records[zone], normalizeDnsName(fmt.Sprint(name)))
+			}
+		}
+		sort.Strings(records[zone])
+	}
+	return records, nil
+}
+
+// NameServerResolver resolves a name only with the name server, `host[:port]`
+type NameServerResolver func(ctx context.Context, nameServer, name string) ([]net.IPAddr, error)
+
+// ResolveWithNameServer queries the name server directly, without the cache of the local resolver
+func ResolveWithNameServer(ctx context.Context, nameServer, name string) ([]net.IPAddr, error) {
+	if _, _, err := net.SplitHostPort(nameServer); err != nil {
+		nameServer = net.JoinHostPort(nameServer, "53")
+	}
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialer := net.Dialer{Timeout: 5 * time.Second}
+			return dialer.DialContext(ctx, network, nameServer)
+		},
+	}
+	return resolver.LookupIPAddr(ctx, name)
+}
+
+// ValidateRoute53 checks the records in the zones of the test, the domain of the options without zones
+func (c *Client) ValidateRoute53(t *testing.T, route53Test Route53Test, alias LoadBalancerAlias) {
+	util.RunTestStage(t, "validate_route53", func() {
+		if len(route53Test.Zones) == 0 && c.options.Domain != "" {
+			route53Test.Zones = []string{c.options.Domain}
+		}
+		c.options.Logger.Logf(t, "route53 :: %+v, alias :: %+v", route53Test, alias)
+		if err := ValidateRoute53E(route53.New(c.Session(t)), ResolveWithNameServer, route53Test, alias); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// ValidateRoute53E checks in every zone that the A and AAAA alias records of the subdomain and its prefixes target the load balancer,
+// then resolves them with the name servers of the zone
+func ValidateRoute53E(client route53iface.Route53API, resolve NameServerResolver, route53Test Route53Test, alias LoadBalancerAlias) error {
+	problems := []string{}
+	records := route53Test.Records()
+	zones := make([]string, 0, len(records))
+	for zone := range records {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+
+	for _, zone := range zones {
+		hostedZone, nameServers, err := findPublicHostedZone(client, zone)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		for _, name := range records[zone] {
+			if err := validateAliasRecords(client, hostedZone, name, alias); err != nil {
+				problems = append(problems, err.Error())
+				continue
+			}
+			if err := resolveWithAnyNameServer(resolve, nameServers, name); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("route53 validation failed:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+func findPublicHostedZone(client route53iface.Route53API, zone string) (hostedZoneId string, nameServers []string, err error) {
+	zones, err := client.ListHostedZonesByName(&route53.ListHostedZonesByNameInput{DNSName: awsSDK.String(zone)})
+	if err != nil {
+		return "", nil, fmt.Errorf("zone %s: list hosted zones: %w", zone, err)
+	}
+	for _, hostedZone := range zones.HostedZones {
+		if normalizeDnsName(awsSDK.StringValue(hostedZone.Name)) != zone {
+			continue
+		}
+		if hostedZone.Config != nil && awsSDK.BoolValue(hostedZone.Config.PrivateZone) {
+			continue
+		}
+		hostedZoneId = awsSDK.StringValue(hostedZone.Id)
+		break
+	}
+	if hostedZoneId == "" {
+		return "", nil, fmt.Errorf("zone %s: no public hosted zone", zone)
+	}
+
+	output, err := client.GetHostedZone(&route53.GetHostedZoneInput{Id: awsSDK.String(hostedZoneId)})
+	if err != nil {
+		return "", nil, fmt.Errorf("zone %s: get hosted zone %s: %w", zone, hostedZoneId, err)
+	}
+	if output.DelegationSet != nil {
+		nameServers = awsSDK.StringValueSlice(output.DelegationSet.NameServers)
+	}
+	if len(nameServers) == 0 {
+		return "", nil, fmt.Errorf("zone %s: no name servers for hosted zone %s", zone, hostedZoneId)
+	}
+	return hostedZoneId, nameServers, nil
+}
+
+// validateAliasRecords requires the A record, the AAAA record is optional
+func validateAliasRecords(client route53iface.Route53API, hostedZoneId, name string, alias LoadBalancerAlias) error {
+	output, err := client.ListResourceRecordSets(&route53.ListResourceRecordSetsInput{
+		HostedZoneId:    awsSDK.String(hostedZoneId),
+		StartRecordName: awsSDK.String(name),
+		StartRecordType: awsSDK.String(route53.RRTypeA),
+		MaxItems:        awsSDK.String("2"),
+	})
+	if err != nil {
+		return fmt.Errorf("record %s: list record sets: %w", name, err)
+	}
+
+	found := false
+	for _, recordSet := range output.ResourceRecordSets {
+		recordType := awsSDK.StringValue(recordSet.Type)
+		if normalizeDnsName(awsSDK.StringValue(recordSet.Name)) != name || (recordType != route53.RRTypeA && recordType != route53.RRTypeAaaa) {
+			continue
+		}
+		if recordType == route53.RRTypeA {
+			found = true
+		}
+		if recordSet.AliasTarget == nil {
+			return fmt.Errorf("record %s %s: not an alias", name, recordType)
+		}
+		if dnsName := normalizeDnsName(awsSDK.StringValue(recordSet.AliasTarget.DNSName)); strings.TrimPrefix(dnsName, "dualstack.") != normalizeDnsName(alias.DnsName) {
+			return fmt.Errorf("record %s %s: alias to %s, expected the load balancer %s", name, recordType, dnsName, alias.DnsName)
+		}
+		if zoneId := awsSDK.StringValue(recordSet.AliasTarget.HostedZoneId); zoneId != alias.ZoneId {
+			return fmt.Errorf("record %s %s: alias hosted zone %s, expected the load balancer zone %s", name, recordType, zoneId, alias.ZoneId)
+		}
+	}
+	if !found {
+		return fmt.Errorf("record %s A: not found", name)
+	}
+	return nil
+}
+
+func resolveWithAnyNameServer(resolve NameServerResolver, nameServers []string, name string) error {
+	errs := []string{}
+	for _, nameServer := range nameServers {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		addresses, err := resolve(ctx, nameServer, name)
+		cancel()
+		if err == nil && len(addresses) > 0 {
+			return nil
+		}
+		if err == nil {
+			err = fmt.Errorf("no address")
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", nameServer, err))
+	}
+	return fmt.Errorf("record %s: not resolved by the name servers of the zone: %s", name, strings.Join(errs, ", "))
+}
+
+func normalizeDnsName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
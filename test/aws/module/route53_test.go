@@ -0,0 +1,209 @@
+package module
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	fakeLbDnsName = "vi-sp-be-test-123456.us-east-1.elb.amazonaws.com"
+	fakeLbZoneId  = "Z35SXDOTRQ7X7K"
+)
+
+func setupFakeRoute53(t *testing.T) (*fakeRoute53, *fakeDnsServer, Route53Test) {
+	route53Test := Route53Test{
+		Zones:         []string{"example.com", "example.org."},
+		SubdomainName: "scraper",
+		Prefixes:      []string{"www", ""},
+	}
+
+	server := newFakeDnsServer(t, map[string]net.IP{
+		"scraper.example.com":     net.ParseIP("10.0.0.1"),
+		"www.scraper.example.com": net.ParseIP("10.0.0.1"),
+		"scraper.example.org":     net.ParseIP("10.0.0.1"),
+		"www.scraper.example.org": net.ParseIP("10.0.0.1"),
+	})
+
+	client := newFakeRoute53()
+	// a private zone with the same name is ignored
+	client.createZone("PRIVATE", "example.com", true, server.address)
+	client.createZone("COM", "example.com", false, server.address)
+	client.createZone("ORG", "example.org", false, server.address)
+	for zoneId, zone := range map[string]string{"COM": "example.com", "ORG": "example.org"} {
+		for _, name := range []string{"scraper." + zone, "www.scraper." + zone} {
+			client.putAlias(zoneId, name, "A", "dualstack."+fakeLbDnsName, fakeLbZoneId)
+			client.putAlias(zoneId, name, "AAAA", "dualstack."+fakeLbDnsName, fakeLbZoneId)
+		}
+	}
+	return client, server, route53Test
+}
+
+func Test_Unit_Route53Test_Records(t *testing.T) {
+	_, _, route53Test := setupFakeRoute53(t)
+	expected := map[string][]string{
+		"example.com": {"scraper.example.com", "www.scraper.example.com"},
+		"example.org": {"scraper.example.org", "www.scraper.example.org"},
+	}
+	if records := route53Test.Records(); !reflect.DeepEqual(expected, records) {
+		t.Errorf("expected %v, got %v", expected, records)
+	}
+}
+
+func Test_Unit_Route53FromVars(t *testing.T) {
+	route53Vars := map[string]any{
+		"zones":  []map[string]any{{"name": "example.com"}, {"name": "example.org"}},
+		"record": map[string]any{"subdomain_name": "scraper", "prefixes": []string{"www"}},
+	}
+	expected := Route53Test{Zones: []string{"example.com", "example.org"}, SubdomainName: "scraper", Prefixes: []string{"www"}}
+
+	for name, vars := range map[string]map[string]any{
+		"microservice": {"microservice": map[string]any{"route53": route53Vars, "container": map[string]any{}}},
+		"module":       {"route53": route53Vars},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if route53Test := Route53FromVars(t, vars); !reflect.DeepEqual(expected, route53Test) {
+				t.Fatalf("expected %+v, got %+v", expected, route53Test)
+			}
+		})
+	}
+}
+
+func Test_Unit_Route53Records(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   map[string]any
+		expected map[string][]string
+		errMsg   string
+	}{
+		{
+			name: "records",
+			output: map[string]any{"records": map[string]any{
+				"example.com": map[string]any{"fqdn": map[string]any{
+					"www.scraper.example.com A":    "www.scraper.example.com",
+					"scraper.example.com A":        "scraper.example.com.",
+					"scraper.example.com AAAA":     "scraper.example.com",
+					"www.scraper.example.com AAAA": "www.scraper.example.com",
+				}},
+			}},
+			expected: map[string][]string{"example.com": {"scraper.example.com", "www.scraper.example.com"}},
+		},
+		{
+			name:     "no records",
+			output:   map[string]any{"records": nil},
+			expected: map[string][]string{},
+		},
+		{
+			name:   "records not by zone",
+			output: map[string]any{"records": []any{"scraper.example.com"}},
+			errMsg: "expected the records by zone, got []interface {}",
+		},
+		{
+			name:   "zone not a map",
+			output: map[string]any{"records": map[string]any{"example.com": "scraper.example.com"}},
+			errMsg: "zone example.com: expected a map of the records, got string",
+		},
+		{
+			name:   "no fqdn",
+			output: map[string]any{"records": map[string]any{"example.com": map[string]any{"name": map[string]any{}}}},
+			errMsg: "zone example.com: expected the fqdn of the records, got <nil>",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			records, err := Route53RecordsE(testCase.output)
+			if testCase.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", testCase.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(testCase.expected, records) {
+				t.Errorf("expected %v, got %v", testCase.expected, records)
+			}
+		})
+	}
+}
+
+func Test_Unit_ValidateRoute53(t *testing.T) {
+	client, _, route53Test := setupFakeRoute53(t)
+	if err := ValidateRoute53E(client, ResolveWithNameServer, route53Test, LoadBalancerAlias{DnsName: fakeLbDnsName, ZoneId: fakeLbZoneId}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Unit_ResolveWithNameServer(t *testing.T) {
+	_, server, _ := setupFakeRoute53(t)
+	addresses, err := ResolveWithNameServer(context.Background(), server.address, "scraper.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 1 || !addresses[0].IP.Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("unexpected addresses %v", addresses)
+	}
+	if _, err := ResolveWithNameServer(context.Background(), server.address, "missing.example.com"); err == nil {
+		t.Error("expected missing name to fail")
+	}
+}
+
+func Test_Unit_ValidateRoute53_Errors(t *testing.T) {
+	alias := LoadBalancerAlias{DnsName: fakeLbDnsName, ZoneId: fakeLbZoneId}
+	testCases := []struct {
+		name   string
+		setup  func(client *fakeRoute53, server *fakeDnsServer, route53Test *Route53Test)
+		errMsg string
+	}{
+		{
+			name: "missing zone",
+			setup: func(client *fakeRoute53, server *fakeDnsServer, route53Test *Route53Test) {
+				route53Test.Zones = append(route53Test.Zones, "example.net")
+			},
+			errMsg: "zone example.net: no public hosted zone",
+		},
+		{
+			name: "missing prefix",
+			setup: func(client *fakeRoute53, server *fakeDnsServer, route53Test *Route53Test) {
+				route53Test.Prefixes = append(route53Test.Prefixes, "api")
+			},
+			errMsg: "record api.scraper.example.com A: not found",
+		},
+		{
+			name: "wrong alias",
+			setup: func(client *fakeRoute53, server *fakeDnsServer, route53Test *Route53Test) {
+				client.records["/hostedzone/ORG"][0].AliasTarget.DNSName = &[]string{"dualstack.other.elb.amazonaws.com."}[0]
+			},
+			errMsg: "expected the load balancer",
+		},
+		{
+			name: "wrong alias zone",
+			setup: func(client *fakeRoute53, server *fakeDnsServer, route53Test *Route53Test) {
+				client.records["/hostedzone/COM"][1].AliasTarget.HostedZoneId = &[]string{"Z000000"}[0]
+			},
+			errMsg: "alias hosted zone Z000000",
+		},
+		{
+			name: "not resolved",
+			setup: func(client *fakeRoute53, server *fakeDnsServer, route53Test *Route53Test) {
+				server.deleteName("www.scraper.example.org")
+			},
+			errMsg: "record www.scraper.example.org: not resolved",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			client, server, route53Test := setupFakeRoute53(t)
+			testCase.setup(client, server, &route53Test)
+			err := ValidateRoute53E(client, ResolveWithNameServer, route53Test, alias)
+			if err == nil || !strings.Contains(err.Error(), testCase.errMsg) {
+				t.Fatalf("expected error containing %q, got %v", testCase.errMsg, err)
+			}
+		})
+	}
+}
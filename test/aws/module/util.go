@@ -22,10 +22,19 @@ func ExtractFromState(t *testing.T, microservicePath, statePath string) any {
 	byteValue, _ := io.ReadAll(jsonFile)
 	var result map[string]any
 	json.Unmarshal([]byte(byteValue), &result)
-	result = result["outputs"].(map[string]any)[stateFields[0]].(map[string]any)["value"].(map[string]any)
+	outputs, _ := result["outputs"].(map[string]any)
+	output, _ := outputs[stateFields[0]].(map[string]any)
+	result, ok := output["value"].(map[string]any)
+	if !ok {
+		return nil
+	}
 
+	// nil when a field is missing or null, e.g. the elb of a service without load balancer
 	for _, stateField := range stateFields[1:] {
-		result = result[stateField].(map[string]any)
+		result, ok = result[stateField].(map[string]any)
+		if !ok {
+			return nil
+		}
 	}
 	return result
 }
@@ -0,0 +1,34 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Unit_ExtractFromState(t *testing.T) {
+	microservicePath := t.TempDir()
+	state := `{"outputs": {"ecs": {"value": {"elb": {"lb": {"arn": "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/vi-rest-test/0"}}, "route53": null}}}}`
+	if err := os.WriteFile(filepath.Join(microservicePath, "terraform.tfstate"), []byte(state), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		statePath string
+		found     bool
+	}{
+		{statePath: "ecs.elb", found: true},
+		{statePath: "ecs.elb.lb", found: true},
+		{statePath: "ecs.route53", found: false},
+		{statePath: "ecs.autoscaling", found: false},
+		{statePath: "microservice.ecs.elb", found: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.statePath, func(t *testing.T) {
+			if found := ExtractFromState(t, microservicePath, testCase.statePath) != nil; found != testCase.found {
+				t.Errorf("expected found %v, got %v", testCase.found, found)
+			}
+		})
+	}
+}
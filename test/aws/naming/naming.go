@@ -0,0 +1,92 @@
+// Package naming builds the names of the AWS resources of the tests within the limits of each resource type.
+//
+// The invalid characters are replaced by hyphens and the names too long are truncated with a hash of the full name,
+// the same parts always give the same name so the validators find the resources created by the modules.
+package naming
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+const (
+	separator = "-"
+	// length of the hash appended to the truncated names
+	hashLength = 8
+)
+
+// Kind holds the limits of the names of a resource type
+type Kind struct {
+	Name      string
+	MaxLength int
+	Lowercase bool
+	// Invalid matches the characters not allowed in the name
+	Invalid *regexp.Regexp
+}
+
+// https://docs.aws.amazon.com/elasticloadbalancing/latest/APIReference/API_CreateLoadBalancer.html
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/bucketnamingrules.html
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_iam-quotas.html
+// https://docs.aws.amazon.com/AmazonECR/latest/APIReference/API_CreateRepository.html
+var (
+	LoadBalancer  = Kind{Name: "load_balancer", MaxLength: 32, Invalid: regexp.MustCompile(`[^a-zA-Z0-9-]+`)}
+	TargetGroup   = Kind{Name: "target_group", MaxLength: 32, Invalid: regexp.MustCompile(`[^a-zA-Z0-9-]+`)}
+	S3Bucket      = Kind{Name: "s3_bucket", MaxLength: 63, Lowercase: true, Invalid: regexp.MustCompile(`[^a-z0-9.-]+`)}
+	IamUser       = Kind{Name: "iam_user", MaxLength: 64, Invalid: regexp.MustCompile(`[^a-zA-Z0-9+=,.@_-]+`)}
+	IamRole       = Kind{Name: "iam_role", MaxLength: 64, Invalid: regexp.MustCompile(`[^a-zA-Z0-9+=,.@_-]+`)}
+	IamGroup      = Kind{Name: "iam_group", MaxLength: 128, Invalid: regexp.MustCompile(`[^a-zA-Z0-9+=,.@_-]+`)}
+	EcrRepository = Kind{Name: "ecr_repository", MaxLength: 256, Lowercase: true, Invalid: regexp.MustCompile(`[^a-z0-9._/-]+`)}
+	EcsCluster    = Kind{Name: "ecs_cluster", MaxLength: 255, Invalid: regexp.MustCompile(`[^a-zA-Z0-9_-]+`)}
+	EcsService    = Kind{Name: "ecs_service", MaxLength: 255, Invalid: regexp.MustCompile(`[^a-zA-Z0-9_-]+`)}
+	DynamodbTable = Kind{Name: "dynamodb_table", MaxLength: 255, Invalid: regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)}
+
+	// Microservice is the common name given to the microservice module, it is the name of the cluster, the load balancer and the target groups,
+	// the other resources append to it, e.g. the env bucket `<name>-env`, so it follows the strictest limits of all
+	Microservice = Kind{Name: "microservice", MaxLength: 32, Lowercase: true, Invalid: regexp.MustCompile(`[^a-z0-9-]+`)}
+)
+
+// Build joins the non empty parts with hyphens, replaces the invalid characters
+// and truncates the name with a hash of the full name when it is too long
+func (k Kind) Build(parts ...string) string {
+	name := util.Format(separator, parts...)
+	if k.Lowercase {
+		name = strings.ToLower(name)
+	}
+	name = strings.Trim(k.Invalid.ReplaceAllString(name, separator), separator)
+	if len(name) <= k.MaxLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:hashLength]
+	return strings.TrimRight(name[:k.MaxLength-hashLength-len(separator)], separator) + separator + hash
+}
+
+// Validate returns an error when the name is not within the limits, e.g. a name built by a module
+func (k Kind) Validate(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("%s name is empty", k.Name)
+	case len(name) > k.MaxLength:
+		return fmt.Errorf("%s name %s has %d characters, maximum %d", k.Name, name, len(name), k.MaxLength)
+	case k.Lowercase && strings.ToLower(name) != name:
+		return fmt.Errorf("%s name %s must be lowercase", k.Name, name)
+	case k.Invalid.MatchString(name):
+		return fmt.Errorf("%s name %s has invalid characters %q", k.Name, name, k.Invalid.FindAllString(name, -1))
+	}
+	return nil
+}
+
+// Service is the name given by the ecs module to the service of a group, `<name>-<group>`
+func Service(name, group string) string {
+	return EcsService.Build(name, group)
+}
+
+// EnvBucket is the name given by the microservice module to the env bucket, `<name>-env`
+func EnvBucket(name string) string {
+	return S3Bucket.Build(name, "env")
+}
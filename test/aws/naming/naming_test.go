@@ -0,0 +1,69 @@
+package naming
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+func Test_Unit_Build(t *testing.T) {
+	for _, testCase := range []struct {
+		kind     Kind
+		parts    []string
+		expected string
+	}{
+		{Microservice, []string{"vi", "ms", "rest", "dev-ab12"}, "vi-ms-rest-dev-ab12"},
+		{Microservice, []string{"vi", "", "Rest", "Dev_Account-AB12"}, "vi-rest-dev-account-ab12"},
+		{EcrRepository, []string{"viton-hd", "Feature/Try_On-rest"}, "viton-hd-feature/try_on-rest"},
+		{S3Bucket, []string{"-vi", "ms", "env"}, "vi-ms-env"},
+		{LoadBalancer, []string{"vi", "ms", "rest", "dev-ab12"}, "vi-ms-rest-dev-ab12"},
+		{LoadBalancer, []string{"vi", "scraper-backend", "rest", "development-ab12"}, "vi-scraper-backend-rest-3365250b"},
+	} {
+		name := testCase.kind.Build(testCase.parts...)
+		util.Equal(t, testCase.expected, name)
+		if err := testCase.kind.Validate(name); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func Test_Unit_Build_Truncate(t *testing.T) {
+	parts := []string{"vi", "scraper-backend", "rest", "development-ab12"}
+	name := Microservice.Build(parts...)
+	util.Equal(t, Microservice.MaxLength, len(name))
+	// deterministic
+	util.Equal(t, name, Microservice.Build(parts...))
+	// the hash keeps the names of different tests apart
+	other := Microservice.Build("vi", "scraper-backend", "rest", "development-cd34")
+	if name == other {
+		t.Errorf("same name %s for different parts", name)
+	}
+	if !strings.HasPrefix(name, "vi-scraper-backend-rest-") {
+		t.Errorf("name %s does not keep the start of the parts", name)
+	}
+}
+
+func Test_Unit_Validate(t *testing.T) {
+	for _, testCase := range []struct {
+		kind   Kind
+		name   string
+		errMsg string
+	}{
+		{LoadBalancer, "vi-scraper-backend-rest-development-ab12", "has 40 characters, maximum 32"},
+		{S3Bucket, "Vi-ms-env", "must be lowercase"},
+		{EcsCluster, "vi.ms", "invalid characters"},
+		{IamRole, "", "is empty"},
+	} {
+		err := testCase.kind.Validate(testCase.name)
+		if err == nil || !strings.Contains(err.Error(), testCase.errMsg) {
+			t.Errorf("expected error %q for %s, got %v", testCase.errMsg, testCase.name, err)
+		}
+	}
+}
+
+func Test_Unit_Derived(t *testing.T) {
+	name := Microservice.Build("vi", "ms", "rest", "dev-ab12")
+	util.Equal(t, "vi-ms-rest-dev-ab12-unique", Service(name, "unique"))
+	util.Equal(t, "vi-ms-rest-dev-ab12-env", EnvBucket(name))
+}
@@ -0,0 +1,79 @@
+package sweeper
+
+import (
+	"fmt"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+const (
+	KindAutoScalingGroup = "auto_scaling_group"
+	KindLaunchTemplate   = "launch_template"
+)
+
+// AutoScalingGroups are deleted with their instances, before the clusters where the instances are registered
+type AutoScalingGroups struct {
+	Client autoscalingiface.AutoScalingAPI
+}
+
+func (k AutoScalingGroups) Name() string { return KindAutoScalingGroup }
+
+func (k AutoScalingGroups) List() ([]Resource, error) {
+	resources := []Resource{}
+	err := k.Client.DescribeAutoScalingGroupsPages(&autoscaling.DescribeAutoScalingGroupsInput{}, func(page *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
+		for _, group := range page.AutoScalingGroups {
+			tags := map[string]string{}
+			for _, tag := range group.Tags {
+				tags[awsSDK.StringValue(tag.Key)] = awsSDK.StringValue(tag.Value)
+			}
+			if resource, ok := taggedResource(KindAutoScalingGroup, awsSDK.StringValue(group.AutoScalingGroupName), tags, awsSDK.TimeValue(group.CreatedTime), nil); ok {
+				resources = append(resources, resource)
+			}
+		}
+		return true
+	})
+	return resources, err
+}
+
+func (k AutoScalingGroups) Delete(resource Resource) error {
+	_, err := k.Client.DeleteAutoScalingGroup(&autoscaling.DeleteAutoScalingGroupInput{AutoScalingGroupName: awsSDK.String(resource.Id), ForceDelete: awsSDK.Bool(true)})
+	return err
+}
+
+// LaunchTemplates are deleted after their auto scaling groups
+type LaunchTemplates struct {
+	Client ec2iface.EC2API
+}
+
+func (k LaunchTemplates) Name() string { return KindLaunchTemplate }
+
+func (k LaunchTemplates) List() ([]Resource, error) {
+	resources := []Resource{}
+	err := k.Client.DescribeLaunchTemplatesPages(&ec2.DescribeLaunchTemplatesInput{
+		Filters: []*ec2.Filter{{Name: awsSDK.String("tag-key"), Values: []*string{awsSDK.String(TagTestID)}}},
+	}, func(page *ec2.DescribeLaunchTemplatesOutput, lastPage bool) bool {
+		for _, template := range page.LaunchTemplates {
+			tags := map[string]string{}
+			for _, tag := range template.Tags {
+				tags[awsSDK.StringValue(tag.Key)] = awsSDK.StringValue(tag.Value)
+			}
+			if resource, ok := taggedResource(KindLaunchTemplate, awsSDK.StringValue(template.LaunchTemplateId), tags, awsSDK.TimeValue(template.CreateTime), nil); ok {
+				resources = append(resources, resource)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describe launch templates: %w", err)
+	}
+	return resources, nil
+}
+
+func (k LaunchTemplates) Delete(resource Resource) error {
+	_, err := k.Client.DeleteLaunchTemplate(&ec2.DeleteLaunchTemplateInput{LaunchTemplateId: awsSDK.String(resource.Id)})
+	return err
+}
@@ -0,0 +1,226 @@
+package sweeper
+
+import (
+	"fmt"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+const (
+	KindEcsService        = "ecs_service"
+	KindEcsCluster        = "ecs_cluster"
+	KindEcsTaskDefinition = "ecs_task_definition"
+
+	// the instances of the deleted auto scaling groups take a few minutes to terminate
+	EcsClusterMaxRetries          = 20
+	EcsClusterSleepBetweenRetries = 15 * time.Second
+
+	// maximum of services or clusters per describe call
+	ecsDescribeBatch = 10
+)
+
+func ecsTags(tags []*ecs.Tag) map[string]string {
+	values := map[string]string{}
+	for _, tag := range tags {
+		values[awsSDK.StringValue(tag.Key)] = awsSDK.StringValue(tag.Value)
+	}
+	return values
+}
+
+func listClusterArns(client ecsiface.ECSAPI) ([]*string, error) {
+	arns := []*string{}
+	err := client.ListClustersPages(&ecs.ListClustersInput{}, func(page *ecs.ListClustersOutput, lastPage bool) bool {
+		arns = append(arns, page.ClusterArns...)
+		return true
+	})
+	return arns, err
+}
+
+// EcsServices are deleted first, their tasks hold the network interfaces, the targets and the instances
+type EcsServices struct {
+	Client ecsiface.ECSAPI
+}
+
+func (k EcsServices) Name() string { return KindEcsService }
+
+func (k EcsServices) List() ([]Resource, error) {
+	clusterArns, err := listClusterArns(k.Client)
+	if err != nil {
+		return nil, err
+	}
+	resources := []Resource{}
+	for _, clusterArn := range clusterArns {
+		serviceArns := []*string{}
+		err := k.Client.ListServicesPages(&ecs.ListServicesInput{Cluster: clusterArn}, func(page *ecs.ListServicesOutput, lastPage bool) bool {
+			serviceArns = append(serviceArns, page.ServiceArns...)
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list services of %s: %w", awsSDK.StringValue(clusterArn), err)
+		}
+		for start := 0; start < len(serviceArns); start += ecsDescribeBatch {
+			end := start + ecsDescribeBatch
+			if end > len(serviceArns) {
+				end = len(serviceArns)
+			}
+			output, err := k.Client.DescribeServices(&ecs.DescribeServicesInput{
+				Cluster:  clusterArn,
+				Services: serviceArns[start:end],
+				Include:  []*string{awsSDK.String(ecs.ServiceFieldTags)},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("describe services of %s: %w", awsSDK.StringValue(clusterArn), err)
+			}
+			for _, service := range output.Services {
+				if resource, ok := taggedResource(KindEcsService, awsSDK.StringValue(service.ServiceArn), ecsTags(service.Tags), awsSDK.TimeValue(service.CreatedAt), map[string]string{"cluster": awsSDK.StringValue(clusterArn)}); ok {
+					resources = append(resources, resource)
+				}
+			}
+		}
+	}
+	return resources, nil
+}
+
+func (k EcsServices) Delete(resource Resource) error {
+	cluster := awsSDK.String(resource.Attributes["cluster"])
+	if _, err := k.Client.UpdateService(&ecs.UpdateServiceInput{Cluster: cluster, Service: awsSDK.String(resource.Id), DesiredCount: awsSDK.Int64(0)}); err != nil {
+		return fmt.Errorf("scale in: %w", err)
+	}
+	_, err := k.Client.DeleteService(&ecs.DeleteServiceInput{Cluster: cluster, Service: awsSDK.String(resource.Id), Force: awsSDK.Bool(true)})
+	return err
+}
+
+// EcsClusters have no creation time, they expire with their services.
+// They are deleted after the auto scaling groups, once the tasks of the services and the container instances are gone
+type EcsClusters struct {
+	Client ecsiface.ECSAPI
+	// MaxRetries and SleepBetweenRetries wait for the tasks and the container instances, EcsClusterMaxRetries and EcsClusterSleepBetweenRetries by default
+	MaxRetries          int
+	SleepBetweenRetries time.Duration
+}
+
+func (k EcsClusters) Name() string { return KindEcsCluster }
+
+func (k EcsClusters) List() ([]Resource, error) {
+	clusterArns, err := listClusterArns(k.Client)
+	if err != nil {
+		return nil, err
+	}
+	resources := []Resource{}
+	for start := 0; start < len(clusterArns); start += ecsDescribeBatch {
+		end := start + ecsDescribeBatch
+		if end > len(clusterArns) {
+			end = len(clusterArns)
+		}
+		output, err := k.Client.DescribeClusters(&ecs.DescribeClustersInput{Clusters: clusterArns[start:end], Include: []*string{awsSDK.String(ecs.ClusterFieldTags)}})
+		if err != nil {
+			return nil, fmt.Errorf("describe clusters: %w", err)
+		}
+		for _, cluster := range output.Clusters {
+			if resource, ok := taggedResource(KindEcsCluster, awsSDK.StringValue(cluster.ClusterArn), ecsTags(cluster.Tags), time.Time{}, nil); ok {
+				resources = append(resources, resource)
+			}
+		}
+	}
+	return resources, nil
+}
+
+// Delete waits for the cluster to be empty, then detaches and deletes the capacity providers of the auto scaling groups before the cluster
+func (k EcsClusters) Delete(resource Resource) error {
+	if err := k.waitEmpty(resource.Id); err != nil {
+		return err
+	}
+	output, err := k.Client.DescribeClusters(&ecs.DescribeClustersInput{Clusters: []*string{awsSDK.String(resource.Id)}})
+	if err != nil {
+		return fmt.Errorf("describe cluster: %w", err)
+	}
+	for _, cluster := range output.Clusters {
+		providers := []string{}
+		for _, provider := range awsSDK.StringValueSlice(cluster.CapacityProviders) {
+			if provider != "FARGATE" && provider != "FARGATE_SPOT" {
+				providers = append(providers, provider)
+			}
+		}
+		if len(providers) == 0 {
+			continue
+		}
+		if _, err := k.Client.PutClusterCapacityProviders(&ecs.PutClusterCapacityProvidersInput{
+			Cluster:                         cluster.ClusterArn,
+			CapacityProviders:               []*string{},
+			DefaultCapacityProviderStrategy: []*ecs.CapacityProviderStrategyItem{},
+		}); err != nil {
+			return fmt.Errorf("detach capacity providers: %w", err)
+		}
+		for _, provider := range providers {
+			if _, err := k.Client.DeleteCapacityProvider(&ecs.DeleteCapacityProviderInput{CapacityProvider: awsSDK.String(provider)}); err != nil {
+				return fmt.Errorf("delete capacity provider %s: %w", provider, err)
+			}
+		}
+	}
+	_, err = k.Client.DeleteCluster(&ecs.DeleteClusterInput{Cluster: awsSDK.String(resource.Id)})
+	return err
+}
+
+// waitEmpty waits for the tasks and the container instances of the cluster to be gone, the cluster cannot be deleted before
+func (k EcsClusters) waitEmpty(cluster string) error {
+	maxRetries, sleepBetweenRetries := k.MaxRetries, k.SleepBetweenRetries
+	if maxRetries == 0 {
+		maxRetries = EcsClusterMaxRetries
+	}
+	if sleepBetweenRetries == 0 {
+		sleepBetweenRetries = EcsClusterSleepBetweenRetries
+	}
+	for i := 0; ; i++ {
+		tasks, err := k.Client.ListTasks(&ecs.ListTasksInput{Cluster: awsSDK.String(cluster)})
+		if err != nil {
+			return fmt.Errorf("list tasks: %w", err)
+		}
+		instances, err := k.Client.ListContainerInstances(&ecs.ListContainerInstancesInput{Cluster: awsSDK.String(cluster)})
+		if err != nil {
+			return fmt.Errorf("list container instances: %w", err)
+		}
+		if len(tasks.TaskArns) == 0 && len(instances.ContainerInstanceArns) == 0 {
+			return nil
+		}
+		if i == maxRetries {
+			return fmt.Errorf("cluster not empty after %d retries, %d tasks and %d container instances", maxRetries, len(tasks.TaskArns), len(instances.ContainerInstanceArns))
+		}
+		time.Sleep(sleepBetweenRetries)
+	}
+}
+
+type EcsTaskDefinitions struct {
+	Client ecsiface.ECSAPI
+}
+
+func (k EcsTaskDefinitions) Name() string { return KindEcsTaskDefinition }
+
+func (k EcsTaskDefinitions) List() ([]Resource, error) {
+	arns := []*string{}
+	err := k.Client.ListTaskDefinitionsPages(&ecs.ListTaskDefinitionsInput{Status: awsSDK.String(ecs.TaskDefinitionStatusActive)}, func(page *ecs.ListTaskDefinitionsOutput, lastPage bool) bool {
+		arns = append(arns, page.TaskDefinitionArns...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	resources := []Resource{}
+	for _, arn := range arns {
+		output, err := k.Client.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{TaskDefinition: arn, Include: []*string{awsSDK.String(ecs.TaskDefinitionFieldTags)}})
+		if err != nil {
+			return nil, fmt.Errorf("describe task definition %s: %w", awsSDK.StringValue(arn), err)
+		}
+		if resource, ok := taggedResource(KindEcsTaskDefinition, awsSDK.StringValue(arn), ecsTags(output.Tags), awsSDK.TimeValue(output.TaskDefinition.RegisteredAt), nil); ok {
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
+
+func (k EcsTaskDefinitions) Delete(resource Resource) error {
+	_, err := k.Client.DeregisterTaskDefinition(&ecs.DeregisterTaskDefinitionInput{TaskDefinition: awsSDK.String(resource.Id)})
+	return err
+}
@@ -0,0 +1,209 @@
+package sweeper
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+)
+
+const (
+	KindLoadBalancer = "load_balancer"
+	KindTargetGroup  = "target_group"
+	KindRoute53      = "route53_record"
+
+	// maximum of resources per DescribeTags call
+	elbDescribeTagsBatch = 20
+)
+
+// elbTags returns the tags of the resources by arn
+func elbTags(client elbv2iface.ELBV2API, arns []*string) (map[string]map[string]string, error) {
+	tags := map[string]map[string]string{}
+	for start := 0; start < len(arns); start += elbDescribeTagsBatch {
+		end := start + elbDescribeTagsBatch
+		if end > len(arns) {
+			end = len(arns)
+		}
+		output, err := client.DescribeTags(&elbv2.DescribeTagsInput{ResourceArns: arns[start:end]})
+		if err != nil {
+			return nil, fmt.Errorf("describe tags: %w", err)
+		}
+		for _, description := range output.TagDescriptions {
+			values := map[string]string{}
+			for _, tag := range description.Tags {
+				values[awsSDK.StringValue(tag.Key)] = awsSDK.StringValue(tag.Value)
+			}
+			tags[awsSDK.StringValue(description.ResourceArn)] = values
+		}
+	}
+	return tags, nil
+}
+
+// LoadBalancers are deleted with their listeners
+type LoadBalancers struct {
+	Client elbv2iface.ELBV2API
+}
+
+func (k LoadBalancers) Name() string { return KindLoadBalancer }
+
+func (k LoadBalancers) List() ([]Resource, error) {
+	loadBalancers := []*elbv2.LoadBalancer{}
+	err := k.Client.DescribeLoadBalancersPages(&elbv2.DescribeLoadBalancersInput{}, func(page *elbv2.DescribeLoadBalancersOutput, lastPage bool) bool {
+		loadBalancers = append(loadBalancers, page.LoadBalancers...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	arns := []*string{}
+	for _, loadBalancer := range loadBalancers {
+		arns = append(arns, loadBalancer.LoadBalancerArn)
+	}
+	tags, err := elbTags(k.Client, arns)
+	if err != nil {
+		return nil, err
+	}
+	resources := []Resource{}
+	for _, loadBalancer := range loadBalancers {
+		arn := awsSDK.StringValue(loadBalancer.LoadBalancerArn)
+		if resource, ok := taggedResource(KindLoadBalancer, arn, tags[arn], awsSDK.TimeValue(loadBalancer.CreatedTime), map[string]string{"dns_name": awsSDK.StringValue(loadBalancer.DNSName)}); ok {
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
+
+func (k LoadBalancers) Delete(resource Resource) error {
+	_, err := k.Client.DeleteLoadBalancer(&elbv2.DeleteLoadBalancerInput{LoadBalancerArn: awsSDK.String(resource.Id)})
+	return err
+}
+
+// TargetGroups have no creation time, they expire with their load balancer
+type TargetGroups struct {
+	Client elbv2iface.ELBV2API
+}
+
+func (k TargetGroups) Name() string { return KindTargetGroup }
+
+func (k TargetGroups) List() ([]Resource, error) {
+	arns := []*string{}
+	err := k.Client.DescribeTargetGroupsPages(&elbv2.DescribeTargetGroupsInput{}, func(page *elbv2.DescribeTargetGroupsOutput, lastPage bool) bool {
+		for _, targetGroup := range page.TargetGroups {
+			arns = append(arns, targetGroup.TargetGroupArn)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	tags, err := elbTags(k.Client, arns)
+	if err != nil {
+		return nil, err
+	}
+	resources := []Resource{}
+	for _, arn := range awsSDK.StringValueSlice(arns) {
+		if resource, ok := taggedResource(KindTargetGroup, arn, tags[arn], time.Time{}, nil); ok {
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
+
+func (k TargetGroups) Delete(resource Resource) error {
+	_, err := k.Client.DeleteTargetGroup(&elbv2.DeleteTargetGroupInput{TargetGroupArn: awsSDK.String(resource.Id)})
+	return err
+}
+
+// Route53Records are the alias records of the load balancers of the expired tests, the records have no tags
+type Route53Records struct {
+	Client route53iface.Route53API
+}
+
+func (k Route53Records) Name() string { return KindRoute53 }
+
+func (k Route53Records) List() ([]Resource, error) { return nil, nil }
+
+// normalizeDnsName removes the `dualstack.` prefix and the trailing dot of the alias targets
+func normalizeDnsName(name string) string {
+	return strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(name), "."), "dualstack.")
+}
+
+func (k Route53Records) Link(expired []Resource) ([]Resource, error) {
+	loadBalancers := map[string]Resource{} // dns name
+	for _, resource := range expired {
+		if resource.Kind == KindLoadBalancer && resource.Attributes["dns_name"] != "" {
+			loadBalancers[normalizeDnsName(resource.Attributes["dns_name"])] = resource
+		}
+	}
+	if len(loadBalancers) == 0 {
+		return nil, nil
+	}
+
+	zones := []*route53.HostedZone{}
+	err := k.Client.ListHostedZonesPages(&route53.ListHostedZonesInput{}, func(page *route53.ListHostedZonesOutput, lastPage bool) bool {
+		zones = append(zones, page.HostedZones...)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list hosted zones: %w", err)
+	}
+	resources := []Resource{}
+	for _, zone := range zones {
+		err := k.Client.ListResourceRecordSetsPages(&route53.ListResourceRecordSetsInput{HostedZoneId: zone.Id}, func(page *route53.ListResourceRecordSetsOutput, lastPage bool) bool {
+			for _, record := range page.ResourceRecordSets {
+				if record.AliasTarget == nil {
+					continue
+				}
+				loadBalancer, ok := loadBalancers[normalizeDnsName(awsSDK.StringValue(record.AliasTarget.DNSName))]
+				if !ok {
+					continue
+				}
+				resources = append(resources, Resource{
+					Kind:    KindRoute53,
+					Id:      awsSDK.StringValue(record.Name) + " " + awsSDK.StringValue(record.Type),
+					TestID:  loadBalancer.TestID,
+					Account: loadBalancer.Account,
+					Region:  loadBalancer.Region,
+					Attributes: map[string]string{
+						"zone_id":        awsSDK.StringValue(zone.Id),
+						"name":           awsSDK.StringValue(record.Name),
+						"type":           awsSDK.StringValue(record.Type),
+						"alias_dns_name": awsSDK.StringValue(record.AliasTarget.DNSName),
+						"alias_zone_id":  awsSDK.StringValue(record.AliasTarget.HostedZoneId),
+						"alias_health":   fmt.Sprint(awsSDK.BoolValue(record.AliasTarget.EvaluateTargetHealth)),
+					},
+				})
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list records of %s: %w", awsSDK.StringValue(zone.Id), err)
+		}
+	}
+	return resources, nil
+}
+
+// Delete needs the exact record, it is rebuilt from the attributes of the listing
+func (k Route53Records) Delete(resource Resource) error {
+	_, err := k.Client.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: awsSDK.String(resource.Attributes["zone_id"]),
+		ChangeBatch: &route53.ChangeBatch{Changes: []*route53.Change{{
+			Action: awsSDK.String(route53.ChangeActionDelete),
+			ResourceRecordSet: &route53.ResourceRecordSet{
+				Name: awsSDK.String(resource.Attributes["name"]),
+				Type: awsSDK.String(resource.Attributes["type"]),
+				AliasTarget: &route53.AliasTarget{
+					DNSName:              awsSDK.String(resource.Attributes["alias_dns_name"]),
+					HostedZoneId:         awsSDK.String(resource.Attributes["alias_zone_id"]),
+					EvaluateTargetHealth: awsSDK.Bool(resource.Attributes["alias_health"] == "true"),
+				},
+			},
+		}}},
+	})
+	return err
+}
@@ -0,0 +1,153 @@
+package sweeper
+
+import (
+	"fmt"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/route53/route53iface"
+)
+
+// fakeKind keeps its resources in memory and records the deletions in the shared slice
+type fakeKind struct {
+	name      string
+	resources []Resource
+	listErr   error
+	deleteErr map[string]error // id
+	deleted   *[]string
+}
+
+func (k *fakeKind) Name() string { return k.name }
+
+func (k *fakeKind) List() ([]Resource, error) {
+	if k.listErr != nil {
+		return nil, k.listErr
+	}
+	return k.resources, nil
+}
+
+func (k *fakeKind) Delete(resource Resource) error {
+	if err := k.deleteErr[resource.Id]; err != nil {
+		return err
+	}
+	*k.deleted = append(*k.deleted, k.name+":"+resource.Id)
+	return nil
+}
+
+// fakeLinker links a resource to each expired resource of the kind followed
+type fakeLinker struct {
+	fakeKind
+	follows string
+}
+
+func (k *fakeLinker) Link(expired []Resource) ([]Resource, error) {
+	resources := []Resource{}
+	for _, resource := range expired {
+		if resource.Kind == k.follows {
+			resources = append(resources, Resource{Kind: k.name, Id: "linked-" + resource.Id, TestID: resource.TestID})
+		}
+	}
+	return resources, nil
+}
+
+type fakeElbv2 struct {
+	elbv2iface.ELBV2API
+	loadBalancers []*elbv2.LoadBalancer
+	tags          map[string][]*elbv2.Tag // arn
+	deleted       []string
+}
+
+func (f *fakeElbv2) DescribeLoadBalancersPages(input *elbv2.DescribeLoadBalancersInput, fn func(*elbv2.DescribeLoadBalancersOutput, bool) bool) error {
+	fn(&elbv2.DescribeLoadBalancersOutput{LoadBalancers: f.loadBalancers}, true)
+	return nil
+}
+
+func (f *fakeElbv2) DescribeTags(input *elbv2.DescribeTagsInput) (*elbv2.DescribeTagsOutput, error) {
+	if len(input.ResourceArns) > elbDescribeTagsBatch {
+		return nil, fmt.Errorf("too many arns: %d", len(input.ResourceArns))
+	}
+	output := &elbv2.DescribeTagsOutput{}
+	for _, arn := range input.ResourceArns {
+		output.TagDescriptions = append(output.TagDescriptions, &elbv2.TagDescription{ResourceArn: arn, Tags: f.tags[awsSDK.StringValue(arn)]})
+	}
+	return output, nil
+}
+
+func (f *fakeElbv2) DeleteLoadBalancer(input *elbv2.DeleteLoadBalancerInput) (*elbv2.DeleteLoadBalancerOutput, error) {
+	f.deleted = append(f.deleted, awsSDK.StringValue(input.LoadBalancerArn))
+	return &elbv2.DeleteLoadBalancerOutput{}, nil
+}
+
+type fakeRoute53 struct {
+	route53iface.Route53API
+	zones   []*route53.HostedZone
+	records map[string][]*route53.ResourceRecordSet // zone id
+	changes []*route53.ChangeResourceRecordSetsInput
+}
+
+func (f *fakeRoute53) ListHostedZonesPages(input *route53.ListHostedZonesInput, fn func(*route53.ListHostedZonesOutput, bool) bool) error {
+	fn(&route53.ListHostedZonesOutput{HostedZones: f.zones}, true)
+	return nil
+}
+
+func (f *fakeRoute53) ListResourceRecordSetsPages(input *route53.ListResourceRecordSetsInput, fn func(*route53.ListResourceRecordSetsOutput, bool) bool) error {
+	fn(&route53.ListResourceRecordSetsOutput{ResourceRecordSets: f.records[awsSDK.StringValue(input.HostedZoneId)]}, true)
+	return nil
+}
+
+func (f *fakeRoute53) ChangeResourceRecordSets(input *route53.ChangeResourceRecordSetsInput) (*route53.ChangeResourceRecordSetsOutput, error) {
+	f.changes = append(f.changes, input)
+	return &route53.ChangeResourceRecordSetsOutput{}, nil
+}
+
+// fakeEcs empties the cluster after some listings of its tasks and container instances, the calls are recorded in order
+type fakeEcs struct {
+	ecsiface.ECSAPI
+	capacityProviders []string
+	// the number of listings that still return tasks or container instances
+	tasksListings     int
+	instancesListings int
+	calls             []string
+}
+
+func (f *fakeEcs) ListTasks(input *ecs.ListTasksInput) (*ecs.ListTasksOutput, error) {
+	output := &ecs.ListTasksOutput{}
+	if f.tasksListings > 0 {
+		f.tasksListings--
+		output.TaskArns = []*string{awsSDK.String("arn:task/draining")}
+	}
+	return output, nil
+}
+
+func (f *fakeEcs) ListContainerInstances(input *ecs.ListContainerInstancesInput) (*ecs.ListContainerInstancesOutput, error) {
+	output := &ecs.ListContainerInstancesOutput{}
+	if f.instancesListings > 0 {
+		f.instancesListings--
+		output.ContainerInstanceArns = []*string{awsSDK.String("arn:container-instance/terminating")}
+	}
+	f.calls = append(f.calls, fmt.Sprintf("list:%d", len(output.ContainerInstanceArns)))
+	return output, nil
+}
+
+func (f *fakeEcs) DescribeClusters(input *ecs.DescribeClustersInput) (*ecs.DescribeClustersOutput, error) {
+	return &ecs.DescribeClustersOutput{Clusters: []*ecs.Cluster{{ClusterArn: input.Clusters[0], CapacityProviders: awsSDK.StringSlice(f.capacityProviders)}}}, nil
+}
+
+func (f *fakeEcs) PutClusterCapacityProviders(input *ecs.PutClusterCapacityProvidersInput) (*ecs.PutClusterCapacityProvidersOutput, error) {
+	f.calls = append(f.calls, "detach")
+	return &ecs.PutClusterCapacityProvidersOutput{}, nil
+}
+
+func (f *fakeEcs) DeleteCapacityProvider(input *ecs.DeleteCapacityProviderInput) (*ecs.DeleteCapacityProviderOutput, error) {
+	f.calls = append(f.calls, "delete_capacity_provider:"+awsSDK.StringValue(input.CapacityProvider))
+	return &ecs.DeleteCapacityProviderOutput{}, nil
+}
+
+func (f *fakeEcs) DeleteCluster(input *ecs.DeleteClusterInput) (*ecs.DeleteClusterOutput, error) {
+	f.calls = append(f.calls, "delete_cluster:"+awsSDK.StringValue(input.Cluster))
+	return &ecs.DeleteClusterOutput{}, nil
+}
@@ -0,0 +1,260 @@
+package sweeper
+
+import (
+	"fmt"
+	"strings"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+)
+
+const (
+	KindIamUser  = "iam_user"
+	KindIamGroup = "iam_group"
+	KindIamRole  = "iam_role"
+)
+
+func iamTags(tags []*iam.Tag) map[string]string {
+	values := map[string]string{}
+	for _, tag := range tags {
+		values[awsSDK.StringValue(tag.Key)] = awsSDK.StringValue(tag.Value)
+	}
+	return values
+}
+
+// IamUsers are deleted with their access keys, login profile, groups memberships and policies
+type IamUsers struct {
+	Client iamiface.IAMAPI
+}
+
+func (k IamUsers) Name() string { return KindIamUser }
+
+func (k IamUsers) List() ([]Resource, error) {
+	users := []*iam.User{}
+	err := k.Client.ListUsersPages(&iam.ListUsersInput{}, func(page *iam.ListUsersOutput, lastPage bool) bool {
+		users = append(users, page.Users...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	resources := []Resource{}
+	for _, user := range users {
+		output, err := k.Client.ListUserTags(&iam.ListUserTagsInput{UserName: user.UserName})
+		if err != nil {
+			return nil, fmt.Errorf("list tags of %s: %w", awsSDK.StringValue(user.UserName), err)
+		}
+		if resource, ok := taggedResource(KindIamUser, awsSDK.StringValue(user.UserName), iamTags(output.Tags), awsSDK.TimeValue(user.CreateDate), nil); ok {
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
+
+func (k IamUsers) Delete(resource Resource) error {
+	name := awsSDK.String(resource.Id)
+
+	keys, err := k.Client.ListAccessKeys(&iam.ListAccessKeysInput{UserName: name})
+	if err != nil {
+		return fmt.Errorf("list access keys: %w", err)
+	}
+	for _, key := range keys.AccessKeyMetadata {
+		if _, err := k.Client.DeleteAccessKey(&iam.DeleteAccessKeyInput{UserName: name, AccessKeyId: key.AccessKeyId}); err != nil {
+			return fmt.Errorf("delete access key: %w", err)
+		}
+	}
+
+	groups, err := k.Client.ListGroupsForUser(&iam.ListGroupsForUserInput{UserName: name})
+	if err != nil {
+		return fmt.Errorf("list groups: %w", err)
+	}
+	for _, group := range groups.Groups {
+		if _, err := k.Client.RemoveUserFromGroup(&iam.RemoveUserFromGroupInput{UserName: name, GroupName: group.GroupName}); err != nil {
+			return fmt.Errorf("remove from group %s: %w", awsSDK.StringValue(group.GroupName), err)
+		}
+	}
+
+	attached, err := k.Client.ListAttachedUserPolicies(&iam.ListAttachedUserPoliciesInput{UserName: name})
+	if err != nil {
+		return fmt.Errorf("list attached policies: %w", err)
+	}
+	for _, policy := range attached.AttachedPolicies {
+		if _, err := k.Client.DetachUserPolicy(&iam.DetachUserPolicyInput{UserName: name, PolicyArn: policy.PolicyArn}); err != nil {
+			return fmt.Errorf("detach policy %s: %w", awsSDK.StringValue(policy.PolicyArn), err)
+		}
+	}
+	inline, err := k.Client.ListUserPolicies(&iam.ListUserPoliciesInput{UserName: name})
+	if err != nil {
+		return fmt.Errorf("list policies: %w", err)
+	}
+	for _, policy := range inline.PolicyNames {
+		if _, err := k.Client.DeleteUserPolicy(&iam.DeleteUserPolicyInput{UserName: name, PolicyName: policy}); err != nil {
+			return fmt.Errorf("delete policy %s: %w", awsSDK.StringValue(policy), err)
+		}
+	}
+
+	if _, err := k.Client.DeleteLoginProfile(&iam.DeleteLoginProfileInput{UserName: name}); err != nil {
+		if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != iam.ErrCodeNoSuchEntityException {
+			return fmt.Errorf("delete login profile: %w", err)
+		}
+	}
+
+	_, err = k.Client.DeleteUser(&iam.DeleteUserInput{UserName: name})
+	return err
+}
+
+// IamGroups have no tags, they are the groups named with the suffix of an expired test
+type IamGroups struct {
+	Client iamiface.IAMAPI
+}
+
+func (k IamGroups) Name() string { return KindIamGroup }
+
+func (k IamGroups) List() ([]Resource, error) { return nil, nil }
+
+func (k IamGroups) Link(expired []Resource) ([]Resource, error) {
+	tests := map[string]Resource{} // name suffix
+	for _, resource := range expired {
+		if resource.Account != "" {
+			tests[resource.NameSuffix()] = resource
+		}
+	}
+	if len(tests) == 0 {
+		return nil, nil
+	}
+
+	resources := []Resource{}
+	err := k.Client.ListGroupsPages(&iam.ListGroupsInput{}, func(page *iam.ListGroupsOutput, lastPage bool) bool {
+		for _, group := range page.Groups {
+			name := awsSDK.StringValue(group.GroupName)
+			for suffix, test := range tests {
+				if strings.HasSuffix(strings.ToLower(name), suffix) {
+					resources = append(resources, Resource{
+						Kind:      KindIamGroup,
+						Id:        name,
+						TestID:    test.TestID,
+						Account:   test.Account,
+						Region:    test.Region,
+						CreatedAt: awsSDK.TimeValue(group.CreateDate),
+					})
+					break
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list groups: %w", err)
+	}
+	return resources, nil
+}
+
+func (k IamGroups) Delete(resource Resource) error {
+	name := awsSDK.String(resource.Id)
+
+	group, err := k.Client.GetGroup(&iam.GetGroupInput{GroupName: name})
+	if err != nil {
+		return fmt.Errorf("get group: %w", err)
+	}
+	for _, user := range group.Users {
+		if _, err := k.Client.RemoveUserFromGroup(&iam.RemoveUserFromGroupInput{UserName: user.UserName, GroupName: name}); err != nil {
+			return fmt.Errorf("remove user %s: %w", awsSDK.StringValue(user.UserName), err)
+		}
+	}
+
+	attached, err := k.Client.ListAttachedGroupPolicies(&iam.ListAttachedGroupPoliciesInput{GroupName: name})
+	if err != nil {
+		return fmt.Errorf("list attached policies: %w", err)
+	}
+	for _, policy := range attached.AttachedPolicies {
+		if _, err := k.Client.DetachGroupPolicy(&iam.DetachGroupPolicyInput{GroupName: name, PolicyArn: policy.PolicyArn}); err != nil {
+			return fmt.Errorf("detach policy %s: %w", awsSDK.StringValue(policy.PolicyArn), err)
+		}
+	}
+	inline, err := k.Client.ListGroupPolicies(&iam.ListGroupPoliciesInput{GroupName: name})
+	if err != nil {
+		return fmt.Errorf("list policies: %w", err)
+	}
+	for _, policy := range inline.PolicyNames {
+		if _, err := k.Client.DeleteGroupPolicy(&iam.DeleteGroupPolicyInput{GroupName: name, PolicyName: policy}); err != nil {
+			return fmt.Errorf("delete policy %s: %w", awsSDK.StringValue(policy), err)
+		}
+	}
+
+	_, err = k.Client.DeleteGroup(&iam.DeleteGroupInput{GroupName: name})
+	return err
+}
+
+// IamRoles are deleted with their instance profiles and policies
+type IamRoles struct {
+	Client iamiface.IAMAPI
+}
+
+func (k IamRoles) Name() string { return KindIamRole }
+
+func (k IamRoles) List() ([]Resource, error) {
+	roles := []*iam.Role{}
+	err := k.Client.ListRolesPages(&iam.ListRolesInput{}, func(page *iam.ListRolesOutput, lastPage bool) bool {
+		roles = append(roles, page.Roles...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	resources := []Resource{}
+	for _, role := range roles {
+		// service linked roles cannot be deleted
+		if strings.HasPrefix(awsSDK.StringValue(role.Path), "/aws-service-role/") {
+			continue
+		}
+		output, err := k.Client.ListRoleTags(&iam.ListRoleTagsInput{RoleName: role.RoleName})
+		if err != nil {
+			return nil, fmt.Errorf("list tags of %s: %w", awsSDK.StringValue(role.RoleName), err)
+		}
+		if resource, ok := taggedResource(KindIamRole, awsSDK.StringValue(role.RoleName), iamTags(output.Tags), awsSDK.TimeValue(role.CreateDate), nil); ok {
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
+
+func (k IamRoles) Delete(resource Resource) error {
+	name := awsSDK.String(resource.Id)
+
+	profiles, err := k.Client.ListInstanceProfilesForRole(&iam.ListInstanceProfilesForRoleInput{RoleName: name})
+	if err != nil {
+		return fmt.Errorf("list instance profiles: %w", err)
+	}
+	for _, profile := range profiles.InstanceProfiles {
+		if _, err := k.Client.RemoveRoleFromInstanceProfile(&iam.RemoveRoleFromInstanceProfileInput{RoleName: name, InstanceProfileName: profile.InstanceProfileName}); err != nil {
+			return fmt.Errorf("remove from instance profile %s: %w", awsSDK.StringValue(profile.InstanceProfileName), err)
+		}
+		if _, err := k.Client.DeleteInstanceProfile(&iam.DeleteInstanceProfileInput{InstanceProfileName: profile.InstanceProfileName}); err != nil {
+			return fmt.Errorf("delete instance profile %s: %w", awsSDK.StringValue(profile.InstanceProfileName), err)
+		}
+	}
+
+	attached, err := k.Client.ListAttachedRolePolicies(&iam.ListAttachedRolePoliciesInput{RoleName: name})
+	if err != nil {
+		return fmt.Errorf("list attached policies: %w", err)
+	}
+	for _, policy := range attached.AttachedPolicies {
+		if _, err := k.Client.DetachRolePolicy(&iam.DetachRolePolicyInput{RoleName: name, PolicyArn: policy.PolicyArn}); err != nil {
+			return fmt.Errorf("detach policy %s: %w", awsSDK.StringValue(policy.PolicyArn), err)
+		}
+	}
+	inline, err := k.Client.ListRolePolicies(&iam.ListRolePoliciesInput{RoleName: name})
+	if err != nil {
+		return fmt.Errorf("list policies: %w", err)
+	}
+	for _, policy := range inline.PolicyNames {
+		if _, err := k.Client.DeleteRolePolicy(&iam.DeleteRolePolicyInput{RoleName: name, PolicyName: policy}); err != nil {
+			return fmt.Errorf("delete policy %s: %w", awsSDK.StringValue(policy), err)
+		}
+	}
+
+	_, err = k.Client.DeleteRole(&iam.DeleteRoleInput{RoleName: name})
+	return err
+}
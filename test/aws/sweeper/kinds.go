@@ -0,0 +1,39 @@
+package sweeper
+
+import (
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Kinds returns all the kinds in the order of deletion:
+// the records and services before the load balancers and instances, the auto scaling groups before the clusters
+// because a cluster with container instances cannot be deleted, the roles last because the others use them
+func Kinds(sess *session.Session) []Kind {
+	ecsClient := ecs.New(sess)
+	elbClient := elbv2.New(sess)
+	iamClient := iam.New(sess)
+	return []Kind{
+		Route53Records{Client: route53.New(sess)},
+		EcsServices{Client: ecsClient},
+		AutoScalingGroups{Client: autoscaling.New(sess)},
+		EcsClusters{Client: ecsClient},
+		EcsTaskDefinitions{Client: ecsClient},
+		LoadBalancers{Client: elbClient},
+		TargetGroups{Client: elbClient},
+		LaunchTemplates{Client: ec2.New(sess)},
+		EcrRepositories{Client: ecr.New(sess)},
+		S3Buckets{Client: s3.New(sess)},
+		DynamodbTables{Client: dynamodb.New(sess)},
+		IamUsers{Client: iamClient},
+		IamGroups{Client: iamClient},
+		IamRoles{Client: iamClient},
+	}
+}
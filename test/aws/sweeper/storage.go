@@ -0,0 +1,165 @@
+package sweeper
+
+import (
+	"errors"
+	"fmt"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+const (
+	KindEcrRepository = "ecr_repository"
+	KindS3Bucket      = "s3_bucket"
+	KindDynamodbTable = "dynamodb_table"
+
+	// error of GetBucketTagging for the buckets without tags
+	errCodeNoSuchTagSet = "NoSuchTagSet"
+)
+
+// EcrRepositories are deleted with their images
+type EcrRepositories struct {
+	Client ecriface.ECRAPI
+}
+
+func (k EcrRepositories) Name() string { return KindEcrRepository }
+
+func (k EcrRepositories) List() ([]Resource, error) {
+	repositories := []*ecr.Repository{}
+	err := k.Client.DescribeRepositoriesPages(&ecr.DescribeRepositoriesInput{}, func(page *ecr.DescribeRepositoriesOutput, lastPage bool) bool {
+		repositories = append(repositories, page.Repositories...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	resources := []Resource{}
+	for _, repository := range repositories {
+		output, err := k.Client.ListTagsForResource(&ecr.ListTagsForResourceInput{ResourceArn: repository.RepositoryArn})
+		if err != nil {
+			return nil, fmt.Errorf("list tags of %s: %w", awsSDK.StringValue(repository.RepositoryName), err)
+		}
+		tags := map[string]string{}
+		for _, tag := range output.Tags {
+			tags[awsSDK.StringValue(tag.Key)] = awsSDK.StringValue(tag.Value)
+		}
+		if resource, ok := taggedResource(KindEcrRepository, awsSDK.StringValue(repository.RepositoryName), tags, awsSDK.TimeValue(repository.CreatedAt), nil); ok {
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
+
+func (k EcrRepositories) Delete(resource Resource) error {
+	_, err := k.Client.DeleteRepository(&ecr.DeleteRepositoryInput{RepositoryName: awsSDK.String(resource.Id), Force: awsSDK.Bool(true)})
+	return err
+}
+
+// S3Buckets are emptied of all the versions of their objects before being deleted
+type S3Buckets struct {
+	Client s3iface.S3API
+}
+
+func (k S3Buckets) Name() string { return KindS3Bucket }
+
+func (k S3Buckets) List() ([]Resource, error) {
+	output, err := k.Client.ListBuckets(&s3.ListBucketsInput{})
+	if err != nil {
+		return nil, err
+	}
+	resources := []Resource{}
+	for _, bucket := range output.Buckets {
+		tagging, err := k.Client.GetBucketTagging(&s3.GetBucketTaggingInput{Bucket: bucket.Name})
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == errCodeNoSuchTagSet {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get tags of %s: %w", awsSDK.StringValue(bucket.Name), err)
+		}
+		tags := map[string]string{}
+		for _, tag := range tagging.TagSet {
+			tags[awsSDK.StringValue(tag.Key)] = awsSDK.StringValue(tag.Value)
+		}
+		if resource, ok := taggedResource(KindS3Bucket, awsSDK.StringValue(bucket.Name), tags, awsSDK.TimeValue(bucket.CreationDate), nil); ok {
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
+
+func (k S3Buckets) Delete(resource Resource) error {
+	bucket := awsSDK.String(resource.Id)
+	var deleteErr error
+	err := k.Client.ListObjectVersionsPages(&s3.ListObjectVersionsInput{Bucket: bucket}, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		objects := []*s3.ObjectIdentifier{}
+		for _, version := range page.Versions {
+			objects = append(objects, &s3.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+		}
+		for _, marker := range page.DeleteMarkers {
+			objects = append(objects, &s3.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+		}
+		if len(objects) == 0 {
+			return true
+		}
+		if _, deleteErr = k.Client.DeleteObjects(&s3.DeleteObjectsInput{Bucket: bucket, Delete: &s3.Delete{Objects: objects, Quiet: awsSDK.Bool(true)}}); deleteErr != nil {
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("list object versions: %w", err)
+	}
+	if deleteErr != nil {
+		return fmt.Errorf("delete objects: %w", deleteErr)
+	}
+	_, err = k.Client.DeleteBucket(&s3.DeleteBucketInput{Bucket: bucket})
+	return err
+}
+
+type DynamodbTables struct {
+	Client dynamodbiface.DynamoDBAPI
+}
+
+func (k DynamodbTables) Name() string { return KindDynamodbTable }
+
+func (k DynamodbTables) List() ([]Resource, error) {
+	names := []*string{}
+	err := k.Client.ListTablesPages(&dynamodb.ListTablesInput{}, func(page *dynamodb.ListTablesOutput, lastPage bool) bool {
+		names = append(names, page.TableNames...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	resources := []Resource{}
+	for _, name := range names {
+		table, err := k.Client.DescribeTable(&dynamodb.DescribeTableInput{TableName: name})
+		if err != nil {
+			return nil, fmt.Errorf("describe table %s: %w", awsSDK.StringValue(name), err)
+		}
+		output, err := k.Client.ListTagsOfResource(&dynamodb.ListTagsOfResourceInput{ResourceArn: table.Table.TableArn})
+		if err != nil {
+			return nil, fmt.Errorf("list tags of %s: %w", awsSDK.StringValue(name), err)
+		}
+		tags := map[string]string{}
+		for _, tag := range output.Tags {
+			tags[awsSDK.StringValue(tag.Key)] = awsSDK.StringValue(tag.Value)
+		}
+		if resource, ok := taggedResource(KindDynamodbTable, awsSDK.StringValue(name), tags, awsSDK.TimeValue(table.Table.CreationDateTime), nil); ok {
+			resources = append(resources, resource)
+		}
+	}
+	return resources, nil
+}
+
+func (k DynamodbTables) Delete(resource Resource) error {
+	_, err := k.Client.DeleteTable(&dynamodb.DeleteTableInput{TableName: awsSDK.String(resource.Id)})
+	return err
+}
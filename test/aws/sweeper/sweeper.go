@@ -0,0 +1,195 @@
+// Package sweeper deletes the resources of the tests that were not destroyed, e.g. after a timeout or a failed destroy.
+//
+// The resources are found with the tags of SetupMicroservice, `TestID`, `Account` and `Region`.
+// A test is expired when its oldest resource is older than the TTL, then all its resources are deleted,
+// the untagged ones are linked to the tagged ones, e.g. the records of a load balancer.
+package sweeper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	TagTestID  = "TestID"
+	TagAccount = "Account"
+	TagRegion  = "Region"
+)
+
+type Resource struct {
+	Kind string
+	// the identifier used to delete the resource, the name or the arn
+	Id      string
+	TestID  string
+	Account string
+	Region  string
+	// zero when the API does not return it, the test is expired with its other resources
+	CreatedAt time.Time
+	// used to link the untagged resources, e.g. the DNS name of a load balancer
+	Attributes map[string]string
+}
+
+func (r Resource) String() string {
+	return fmt.Sprintf("%s %s (test %s)", r.Kind, r.Id, r.TestID)
+}
+
+// NameSuffix is the suffix of the names of the test, `<account>-<test id>` in lower case like SetupMicroservice
+func (r Resource) NameSuffix() string {
+	return strings.ToLower(r.Account + "-" + r.TestID)
+}
+
+// Kind lists and deletes one type of resource
+type Kind interface {
+	Name() string
+	// List returns the resources with the TestID tag
+	List() ([]Resource, error)
+	Delete(resource Resource) error
+}
+
+// Linker is a kind without tags, its resources are found from the ones of the expired tests
+type Linker interface {
+	Kind
+	Link(expired []Resource) ([]Resource, error)
+}
+
+// taggedResource returns the resource when it has the TestID tag
+func taggedResource(kind, id string, tags map[string]string, createdAt time.Time, attributes map[string]string) (Resource, bool) {
+	testID, ok := tags[TagTestID]
+	if !ok || testID == "" {
+		return Resource{}, false
+	}
+	return Resource{
+		Kind:       kind,
+		Id:         id,
+		TestID:     testID,
+		Account:    tags[TagAccount],
+		Region:     tags[TagRegion],
+		CreatedAt:  createdAt,
+		Attributes: attributes,
+	}, true
+}
+
+type Sweeper struct {
+	// Kinds are deleted in order, the dependents first
+	Kinds []Kind
+	// only the resources with these tags are swept, any when empty
+	Account string
+	Region  string
+	TTL     time.Duration
+	// the resources are listed but not deleted
+	DryRun bool
+
+	Now func() time.Time
+	Log func(format string, args ...any)
+}
+
+type Failure struct {
+	Resource Resource
+	Err      error
+}
+
+type Result struct {
+	// ExpiredTestIDs are sorted
+	ExpiredTestIDs []string
+	// Resources are the resources of the expired tests, in the order of deletion
+	Resources []Resource
+	Deleted   []Resource
+	Failures  []Failure
+}
+
+// Sweep lists the resources of all the kinds then deletes the ones of the expired tests,
+// a listing failure stops before any deletion, a deletion failure does not stop the others
+func (s *Sweeper) Sweep() (*Result, error) {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	log := func(format string, args ...any) {}
+	if s.Log != nil {
+		log = s.Log
+	}
+
+	listed := []Resource{}
+	for _, kind := range s.Kinds {
+		if _, ok := kind.(Linker); ok {
+			continue
+		}
+		resources, err := kind.List()
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", kind.Name(), err)
+		}
+		for _, resource := range resources {
+			if (s.Account == "" || resource.Account == s.Account) && (s.Region == "" || resource.Region == s.Region) {
+				listed = append(listed, resource)
+			}
+		}
+	}
+
+	// a test is as old as its oldest resource
+	oldest := map[string]time.Time{}
+	for _, resource := range listed {
+		if resource.CreatedAt.IsZero() {
+			continue
+		}
+		if createdAt, ok := oldest[resource.TestID]; !ok || resource.CreatedAt.Before(createdAt) {
+			oldest[resource.TestID] = resource.CreatedAt
+		}
+	}
+	result := &Result{}
+	expired := map[string]bool{}
+	for testID, createdAt := range oldest {
+		if now().Sub(createdAt) > s.TTL {
+			expired[testID] = true
+			result.ExpiredTestIDs = append(result.ExpiredTestIDs, testID)
+		}
+	}
+	sort.Strings(result.ExpiredTestIDs)
+
+	selected := map[string][]Resource{}
+	expiredResources := []Resource{}
+	for _, resource := range listed {
+		if expired[resource.TestID] {
+			selected[resource.Kind] = append(selected[resource.Kind], resource)
+			expiredResources = append(expiredResources, resource)
+		}
+	}
+	for _, kind := range s.Kinds {
+		linker, ok := kind.(Linker)
+		if !ok {
+			continue
+		}
+		resources, err := linker.Link(expiredResources)
+		if err != nil {
+			return nil, fmt.Errorf("link %s: %w", kind.Name(), err)
+		}
+		selected[kind.Name()] = append(selected[kind.Name()], resources...)
+	}
+
+	for _, kind := range s.Kinds {
+		for _, resource := range selected[kind.Name()] {
+			result.Resources = append(result.Resources, resource)
+			if s.DryRun {
+				log("would delete %s", resource)
+				continue
+			}
+			if err := kind.Delete(resource); err != nil {
+				log("failed to delete %s: %v", resource, err)
+				result.Failures = append(result.Failures, Failure{Resource: resource, Err: err})
+				continue
+			}
+			log("deleted %s", resource)
+			result.Deleted = append(result.Deleted, resource)
+		}
+	}
+
+	if len(result.Failures) > 0 {
+		messages := []string{}
+		for _, failure := range result.Failures {
+			messages = append(messages, fmt.Sprintf("%s: %v", failure.Resource, failure.Err))
+		}
+		return result, fmt.Errorf("%d resources not deleted:\n%s", len(result.Failures), strings.Join(messages, "\n"))
+	}
+	return result, nil
+}
@@ -0,0 +1,227 @@
+package sweeper
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/route53"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+var now = time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func resource(kind, id, testID string, age time.Duration) Resource {
+	createdAt := time.Time{}
+	if age > 0 {
+		createdAt = now.Add(-age)
+	}
+	return Resource{Kind: kind, Id: id, TestID: testID, Account: "Dev", Region: "us-east-1", CreatedAt: createdAt}
+}
+
+// newFakeSweeper sweeps services, clusters then repositories with a TTL of 6 hours
+func newFakeSweeper(deleted *[]string, linked bool) (*Sweeper, map[string]*fakeKind) {
+	kinds := map[string]*fakeKind{
+		KindEcsService: {name: KindEcsService, deleted: deleted, resources: []Resource{
+			resource(KindEcsService, "service-old", "old", 8*time.Hour),
+			resource(KindEcsService, "service-new", "new", time.Hour),
+		}},
+		// the clusters have no creation time
+		KindEcsCluster: {name: KindEcsCluster, deleted: deleted, resources: []Resource{
+			resource(KindEcsCluster, "cluster-old", "old", 0),
+			resource(KindEcsCluster, "cluster-new", "new", 0),
+			resource(KindEcsCluster, "cluster-orphan", "orphan", 0),
+		}},
+		KindEcrRepository: {name: KindEcrRepository, deleted: deleted, resources: []Resource{
+			resource(KindEcrRepository, "repository-old", "old", 7*time.Hour),
+			resource(KindEcrRepository, "repository-other", "other", 7*time.Hour),
+		}},
+	}
+	kinds[KindEcrRepository].resources[1].Account = "Prod"
+	sweeper := &Sweeper{
+		Account: "Dev",
+		Region:  "us-east-1",
+		TTL:     6 * time.Hour,
+		Now:     func() time.Time { return now },
+	}
+	if linked {
+		sweeper.Kinds = append(sweeper.Kinds, &fakeLinker{fakeKind: fakeKind{name: KindRoute53, deleted: deleted}, follows: KindEcsService})
+	}
+	sweeper.Kinds = append(sweeper.Kinds, kinds[KindEcsService], kinds[KindEcsCluster], kinds[KindEcrRepository])
+	return sweeper, kinds
+}
+
+func Test_Unit_Sweep(t *testing.T) {
+	deleted := []string{}
+	sweeper, _ := newFakeSweeper(&deleted, true)
+	result, err := sweeper.Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, "old", strings.Join(result.ExpiredTestIDs, ","))
+	// in the order of the kinds, the linked records first
+	util.Equal(t, "route53_record:linked-service-old,ecs_service:service-old,ecs_cluster:cluster-old,ecr_repository:repository-old", strings.Join(deleted, ","))
+	util.Equal(t, 4, len(result.Resources))
+	util.Equal(t, 4, len(result.Deleted))
+}
+
+func Test_Unit_Sweep_DryRun(t *testing.T) {
+	deleted := []string{}
+	sweeper, _ := newFakeSweeper(&deleted, false)
+	sweeper.DryRun = true
+	logs := []string{}
+	sweeper.Log = func(format string, args ...any) { logs = append(logs, fmt.Sprintf(format, args...)) }
+	result, err := sweeper.Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, 0, len(deleted))
+	util.Equal(t, 3, len(result.Resources))
+	util.Equal(t, 0, len(result.Deleted))
+	util.Equal(t, "would delete ecs_service service-old (test old)", logs[0])
+}
+
+func Test_Unit_Sweep_Filter(t *testing.T) {
+	deleted := []string{}
+	sweeper, _ := newFakeSweeper(&deleted, false)
+	sweeper.Account = ""
+	result, err := sweeper.Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the repository of the other account is swept without account filter
+	util.Equal(t, "old,other", strings.Join(result.ExpiredTestIDs, ","))
+	util.Equal(t, 4, len(deleted))
+}
+
+func Test_Unit_Sweep_Failures(t *testing.T) {
+	deleted := []string{}
+	sweeper, kinds := newFakeSweeper(&deleted, false)
+	kinds[KindEcsService].deleteErr = map[string]error{"service-old": fmt.Errorf("service is draining")}
+	result, err := sweeper.Sweep()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "ecs_service service-old (test old): service is draining") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// the other resources are still deleted
+	util.Equal(t, "ecs_cluster:cluster-old,ecr_repository:repository-old", strings.Join(deleted, ","))
+	util.Equal(t, 1, len(result.Failures))
+}
+
+func Test_Unit_Sweep_ListError(t *testing.T) {
+	deleted := []string{}
+	sweeper, kinds := newFakeSweeper(&deleted, false)
+	kinds[KindEcrRepository].listErr = fmt.Errorf("access denied")
+	_, err := sweeper.Sweep()
+	if err == nil || !strings.Contains(err.Error(), "list ecr_repository: access denied") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	util.Equal(t, 0, len(deleted))
+}
+
+func Test_Unit_Sweep_LoadBalancerRecords(t *testing.T) {
+	elb := &fakeElbv2{tags: map[string][]*elbv2.Tag{}}
+	for i := 0; i < 25; i++ {
+		arn := fmt.Sprintf("arn:lb/%d", i)
+		elb.loadBalancers = append(elb.loadBalancers, &elbv2.LoadBalancer{
+			LoadBalancerArn: awsSDK.String(arn),
+			DNSName:         awsSDK.String(fmt.Sprintf("lb-%d.us-east-1.elb.amazonaws.com", i)),
+			CreatedTime:     awsSDK.Time(now.Add(-time.Duration(i) * time.Hour)),
+		})
+		// the first load balancer is not from a test
+		if i > 0 {
+			elb.tags[arn] = []*elbv2.Tag{
+				{Key: awsSDK.String(TagTestID), Value: awsSDK.String(fmt.Sprintf("id%d", i))},
+				{Key: awsSDK.String(TagAccount), Value: awsSDK.String("Dev")},
+				{Key: awsSDK.String(TagRegion), Value: awsSDK.String("us-east-1")},
+			}
+		}
+	}
+	dns := &fakeRoute53{
+		zones: []*route53.HostedZone{{Id: awsSDK.String("/hostedzone/Z1")}},
+		records: map[string][]*route53.ResourceRecordSet{"/hostedzone/Z1": {
+			{Name: awsSDK.String("ns.example.com."), Type: awsSDK.String("NS")},
+			{Name: awsSDK.String("old.example.com."), Type: awsSDK.String("A"), AliasTarget: &route53.AliasTarget{DNSName: awsSDK.String("dualstack.LB-24.us-east-1.elb.amazonaws.com."), HostedZoneId: awsSDK.String("Z35")}},
+			{Name: awsSDK.String("new.example.com."), Type: awsSDK.String("A"), AliasTarget: &route53.AliasTarget{DNSName: awsSDK.String("lb-1.us-east-1.elb.amazonaws.com."), HostedZoneId: awsSDK.String("Z35")}},
+		}},
+	}
+	sweeper := &Sweeper{
+		Kinds: []Kind{Route53Records{Client: dns}, LoadBalancers{Client: elb}},
+		TTL:   20 * time.Hour,
+		Now:   func() time.Time { return now },
+	}
+	result, err := sweeper.Sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, "id21,id22,id23,id24", strings.Join(result.ExpiredTestIDs, ","))
+	util.Equal(t, "arn:lb/21,arn:lb/22,arn:lb/23,arn:lb/24", strings.Join(elb.deleted, ","))
+	util.Equal(t, 1, len(dns.changes))
+	change := dns.changes[0].ChangeBatch.Changes[0]
+	util.Equal(t, route53.ChangeActionDelete, awsSDK.StringValue(change.Action))
+	util.Equal(t, "old.example.com.", awsSDK.StringValue(change.ResourceRecordSet.Name))
+	util.Equal(t, "Z35", awsSDK.StringValue(change.ResourceRecordSet.AliasTarget.HostedZoneId))
+	util.Equal(t, "id24", result.Resources[0].TestID)
+}
+
+func Test_Unit_Kinds_Order(t *testing.T) {
+	sess, err := session.NewSession(awsSDK.NewConfig().WithRegion("us-east-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, kind := range Kinds(sess) {
+		names = append(names, kind.Name())
+	}
+	// the instances of the auto scaling groups are deregistered from the clusters before their deletion
+	util.Equal(t, "route53_record,ecs_service,auto_scaling_group,ecs_cluster,ecs_task_definition,load_balancer,target_group,launch_template,ecr_repository,s3_bucket,dynamodb_table,iam_user,iam_group,iam_role", strings.Join(names, ","))
+}
+
+func Test_Unit_EcsClusters_Delete(t *testing.T) {
+	testCases := []struct {
+		name              string
+		tasksListings     int
+		instancesListings int
+		expectedCalls     string
+		errMsg            string
+	}{
+		{
+			name:          "empty",
+			expectedCalls: "list:0,detach,delete_capacity_provider:cp-ec2,delete_cluster:cluster-old",
+		},
+		{
+			name:              "draining",
+			tasksListings:     2,
+			instancesListings: 1,
+			expectedCalls:     "list:1,list:0,list:0,detach,delete_capacity_provider:cp-ec2,delete_cluster:cluster-old",
+		},
+		{
+			name:              "not empty",
+			instancesListings: 10,
+			expectedCalls:     "list:1,list:1,list:1",
+			errMsg:            "cluster not empty after 2 retries, 0 tasks and 1 container instances",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			client := &fakeEcs{capacityProviders: []string{"cp-ec2", "FARGATE"}, tasksListings: testCase.tasksListings, instancesListings: testCase.instancesListings}
+			kind := EcsClusters{Client: client, MaxRetries: 2, SleepBetweenRetries: time.Nanosecond}
+			err := kind.Delete(resource(KindEcsCluster, "cluster-old", "old", 0))
+			switch {
+			case testCase.errMsg == "" && err != nil:
+				t.Fatal(err)
+			case testCase.errMsg != "" && (err == nil || err.Error() != testCase.errMsg):
+				t.Fatalf("expected error %q, got %v", testCase.errMsg, err)
+			}
+			util.Equal(t, testCase.expectedCalls, strings.Join(client.calls, ","))
+		})
+	}
+}
@@ -0,0 +1,155 @@
+// Package config reads the settings of the tests from the environment and an optional `.env` file.
+//
+// The settings are read on first use, so the test packages are imported without them,
+// and the tests are skipped when the settings they require are missing
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vistimi/infrastructure-modules/test/dotenv"
+)
+
+// FileEnv is the path of the env file, `.env` in the current folder or its parents up to the root of the repository by default
+const FileEnv = "ENV_FILE"
+
+// Group is a set of settings required together by a test
+type Group string
+
+const (
+	Aws    Group = "aws"
+	Domain Group = "domain"
+	Vpc    Group = "vpc"
+	Github Group = "github"
+)
+
+// Config holds the settings, `env` is the variable of a field, `required` the group requiring it and `default` its value when empty
+type Config struct {
+	AccountName   string `env:"AWS_PROFILE_NAME" required:"aws"`
+	AccountId     string `env:"AWS_ACCOUNT_ID" required:"aws"`
+	AccountRegion string `env:"AWS_REGION_NAME" required:"aws"`
+	DomainName    string `env:"DOMAIN_NAME" required:"domain"`
+	DomainSuffix  string `env:"DOMAIN_SUFFIX" required:"domain"`
+	VpcId         string `env:"VPC_ID" required:"vpc"`
+	Arch          string `env:"ARCH" default:"x86_64"`
+	GithubOwner   string `env:"GITHUB_OWNER" required:"github"`
+	GithubToken   string `env:"GITHUB_TOKEN" required:"github"`
+
+	// file is the env file read, empty without one
+	file string
+}
+
+var (
+	once    sync.Once
+	loaded  *Config
+	loadErr error
+)
+
+// Get returns the settings, read once for all the tests, and skips the test when a setting of the groups is missing
+func Get(t *testing.T, groups ...Group) *Config {
+	once.Do(func() {
+		loaded, loadErr = ReadE()
+	})
+	if loadErr != nil {
+		t.Fatal(loadErr)
+	}
+	if err := loaded.MissingE(groups...); err != nil {
+		source := "the environment"
+		if loaded.file != "" {
+			source += " or " + loaded.file
+		}
+		t.Skipf("%v, set them in %s", err, source)
+	}
+	return loaded
+}
+
+// ReadE reads the env file, when there is one, and the environment
+func ReadE() (*Config, error) {
+	path, explicit := os.LookupEnv(FileEnv)
+	if !explicit {
+		path = findFile(".env")
+	}
+	file := dotenv.New()
+	if path != "" {
+		var err error
+		file, err = dotenv.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", FileEnv, err)
+		}
+	}
+	config := Load(os.LookupEnv, file)
+	config.file = path
+	return config, nil
+}
+
+// Load fills the fields with the variables of the environment, then of the file, then the defaults
+func Load(lookup func(key string) (string, bool), file *dotenv.Env) *Config {
+	config := &Config{}
+	value := reflect.ValueOf(config).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key := field.Tag.Get("env")
+		if key == "" {
+			continue
+		}
+		variable, ok := lookup(key)
+		if !ok || variable == "" {
+			variable, _ = file.Get(key)
+		}
+		if variable == "" {
+			variable = field.Tag.Get("default")
+		}
+		value.Field(i).SetString(variable)
+	}
+	return config
+}
+
+// MissingE returns an error with the empty variables required by the groups
+func (c *Config) MissingE(groups ...Group) error {
+	required := map[Group]bool{}
+	for _, group := range groups {
+		required[group] = true
+	}
+	missing := []string{}
+	value := reflect.ValueOf(c).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if required[Group(field.Tag.Get("required"))] && value.Field(i).String() == "" {
+			missing = append(missing, field.Tag.Get("env"))
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("missing env variables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Domain is the name of the domain with its suffix, `<DOMAIN_NAME>.<DOMAIN_SUFFIX>`
+func (c *Config) Domain() string {
+	return c.DomainName + "." + c.DomainSuffix
+}
+
+// findFile looks for the file in the current folder and its parents, up to the folder with the go.mod, empty when not found
+func findFile(name string) string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for ; ; dir = filepath.Dir(dir) {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil || dir == filepath.Dir(dir) {
+			return ""
+		}
+	}
+}
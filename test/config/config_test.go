@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vistimi/infrastructure-modules/test/dotenv"
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+func Test_Unit_Config_Load(t *testing.T) {
+	environment := map[string]string{"AWS_PROFILE_NAME": "dev", "AWS_REGION_NAME": "", "DOMAIN_NAME": "vistimi"}
+	lookup := func(key string) (string, bool) {
+		value, ok := environment[key]
+		return value, ok
+	}
+	file, err := dotenv.ParseString("AWS_PROFILE_NAME=file\nAWS_REGION_NAME=us-east-1\nDOMAIN_SUFFIX=com\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := Load(lookup, file)
+	// the environment overrides the file, except when empty
+	util.Equal(t, "dev", config.AccountName)
+	util.Equal(t, "us-east-1", config.AccountRegion)
+	util.Equal(t, "vistimi.com", config.Domain())
+	util.Equal(t, "x86_64", config.Arch)
+
+	testCases := []struct {
+		name     string
+		groups   []Group
+		expected string
+	}{
+		{name: "none", groups: nil, expected: ""},
+		{name: "domain", groups: []Group{Domain}, expected: ""},
+		{name: "aws", groups: []Group{Aws}, expected: "missing env variables: AWS_ACCOUNT_ID"},
+		{name: "aws and github", groups: []Group{Aws, Github}, expected: "missing env variables: AWS_ACCOUNT_ID, GITHUB_OWNER, GITHUB_TOKEN"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := config.MissingE(tc.groups...)
+			message := ""
+			if err != nil {
+				message = err.Error()
+			}
+			util.Equal(t, tc.expected, message)
+		})
+	}
+}
+
+func Test_Unit_Config_Read(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("VPC_ID=vpc-0\nARCH=arm64\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(FileEnv, path)
+	t.Setenv("ARCH", "")
+
+	config, err := ReadE()
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, "vpc-0", config.VpcId)
+	util.Equal(t, "arm64", config.Arch)
+
+	// the file given must exist
+	t.Setenv(FileEnv, path+"0")
+	if _, err := ReadE(); err == nil {
+		t.Error("expected an error for a missing env file")
+	}
+}
@@ -0,0 +1,277 @@
+// Package dotenv reads and writes the env files uploaded in the env bucket of the microservices.
+//
+// ECS reads the values of the environment files literally, quotes are only written when a value requires them
+package dotenv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var keyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Env is an ordered set of variables, the order of the first definition is kept
+type Env struct {
+	keys   []string
+	values map[string]string
+}
+
+func New() *Env {
+	return &Env{values: map[string]string{}}
+}
+
+// FromMap sorts the keys of the map
+func FromMap(variables map[string]string) (*Env, error) {
+	keys := make([]string, 0, len(variables))
+	for key := range variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	env := New()
+	for _, key := range keys {
+		if err := env.Set(key, variables[key]); err != nil {
+			return nil, err
+		}
+	}
+	return env, nil
+}
+
+func ValidateKey(key string) error {
+	if !keyRegexp.MatchString(key) {
+		return fmt.Errorf("invalid key %q, expected %s", key, keyRegexp.String())
+	}
+	return nil
+}
+
+// Set overrides the value of an existing key
+func (e *Env) Set(key, value string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if _, ok := e.values[key]; !ok {
+		e.keys = append(e.keys, key)
+	}
+	e.values[key] = value
+	return nil
+}
+
+func (e *Env) Get(key string) (string, bool) {
+	value, ok := e.values[key]
+	return value, ok
+}
+
+func (e *Env) Keys() []string {
+	return append([]string{}, e.keys...)
+}
+
+func (e *Env) Len() int {
+	return len(e.keys)
+}
+
+func (e *Env) Map() map[string]string {
+	variables := make(map[string]string, len(e.values))
+	for key, value := range e.values {
+		variables[key] = value
+	}
+	return variables
+}
+
+// Merge sets the variables of the others in order, the last definition wins
+func (e *Env) Merge(others ...*Env) *Env {
+	for _, other := range others {
+		if other == nil {
+			continue
+		}
+		for _, key := range other.keys {
+			e.Set(key, other.values[key])
+		}
+	}
+	return e
+}
+
+// Validate checks that the required keys are defined and not empty
+func (e *Env) Validate(required ...string) error {
+	missing := []string{}
+	for _, key := range required {
+		if value, ok := e.values[key]; !ok || value == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing env variables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// String formats the env file
+func (e *Env) String() string {
+	builder := strings.Builder{}
+	for _, key := range e.keys {
+		builder.WriteString(key)
+		builder.WriteString("=")
+		builder.WriteString(Quote(e.values[key]))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// Quote returns the value as is when it is read back identically, a double quoted value otherwise
+func Quote(value string) string {
+	if value == "" || (strings.TrimSpace(value) == value && !strings.ContainsAny(value, "\"'`#\\\n\r\t $")) {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, `$`, `\$`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// ParseError is the line where the parsing failed
+type ParseError struct {
+	Line    int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Parse reads `KEY=value` lines with optional `export`, comments, single quoted literal values and double quoted escaped values, quoted values can be multiline
+func Parse(reader io.Reader) (*Env, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return ParseString(string(data))
+}
+
+func ParseString(data string) (*Env, error) {
+	env := New()
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, &ParseError{Line: lineNumber, Message: fmt.Sprintf("expected KEY=value, got %q", line)}
+		}
+		key = strings.TrimSpace(key)
+		if err := ValidateKey(key); err != nil {
+			return nil, &ParseError{Line: lineNumber, Message: err.Error()}
+		}
+		value = strings.TrimLeft(value, " \t")
+
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			quote := value[0]
+			// join the next lines until the closing quote
+			raw := value[1:]
+			end := closingQuote(raw, quote)
+			for end < 0 {
+				i++
+				if i >= len(lines) {
+					return nil, &ParseError{Line: lineNumber, Message: fmt.Sprintf("unterminated quoted value of %s", key)}
+				}
+				raw += "\n" + lines[i]
+				end = closingQuote(raw, quote)
+			}
+			rest := strings.TrimSpace(raw[end+1:])
+			if rest != "" && !strings.HasPrefix(rest, "#") {
+				return nil, &ParseError{Line: i + 1, Message: fmt.Sprintf("unexpected characters after the quoted value of %s: %q", key, rest)}
+			}
+			value = raw[:end]
+			if quote == '"' {
+				value = unescape(value)
+			}
+		} else {
+			// inline comments require a preceding space
+			if index := strings.Index(value, " #"); index >= 0 {
+				value = value[:index]
+			}
+			value = strings.TrimSpace(value)
+		}
+
+		env.Set(key, value)
+	}
+	return env, nil
+}
+
+func closingQuote(value string, quote byte) int {
+	for i := 0; i < len(value); i++ {
+		if quote == '"' && value[i] == '\\' {
+			i++
+			continue
+		}
+		if value[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+func unescape(value string) string {
+	builder := strings.Builder{}
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			builder.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case 't':
+			builder.WriteByte('\t')
+		default:
+			builder.WriteByte(value[i])
+		}
+	}
+	return builder.String()
+}
+
+func ReadFile(path string) (*Env, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	env, err := Parse(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return env, nil
+}
+
+// WriteFile replaces the file atomically, readers never see a partial file
+func WriteFile(path string, env *Env) error {
+	folder := filepath.Dir(path)
+	if err := os.MkdirAll(folder, 0o755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(folder, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(env.String()); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,143 @@
+package dotenv
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+func Test_Unit_Dotenv_Parse(t *testing.T) {
+	env, err := ParseString(`# comment
+COMMON_NAME=trunk
+export CLOUD_HOST = aws # inline comment
+EMPTY=
+URL=http://example.com/#anchor
+SINGLE='literal \n $HOME'
+DOUBLE="tab\there \"quoted\""
+MULTILINE="first
+second"
+COMMON_NAME=override
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := []string{"COMMON_NAME", "CLOUD_HOST", "EMPTY", "URL", "SINGLE", "DOUBLE", "MULTILINE"}; !reflect.DeepEqual(keys, env.Keys()) {
+		t.Errorf("expected %v, got %v", keys, env.Keys())
+	}
+	expected := map[string]string{
+		"COMMON_NAME": "override",
+		"CLOUD_HOST":  "aws",
+		"EMPTY":       "",
+		"URL":         "http://example.com/#anchor",
+		"SINGLE":      `literal \n $HOME`,
+		"DOUBLE":      "tab\there \"quoted\"",
+		"MULTILINE":   "first\nsecond",
+	}
+	if !reflect.DeepEqual(expected, env.Map()) {
+		t.Errorf("expected %v, got %v", expected, env.Map())
+	}
+}
+
+func Test_Unit_Dotenv_Parse_Errors(t *testing.T) {
+	for name, data := range map[string]string{
+		"no equal":     "COMMON_NAME\n",
+		"invalid key":  "1KEY=value\n",
+		"unterminated": "KEY=\"value\nOTHER=value\n",
+		"after quote":  "KEY=\"value\" trailing\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseString(data); err == nil {
+				t.Fatal("expected error")
+			} else if _, ok := err.(*ParseError); !ok {
+				t.Fatalf("expected parse error, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_Unit_Dotenv_RoundTrip(t *testing.T) {
+	env, err := FromMap(map[string]string{
+		"PLAIN":     "value",
+		"SPACES":    " padded value ",
+		"QUOTES":    `say "hi" it's`,
+		"MULTILINE": "-----BEGIN KEY-----\nabc\n-----END KEY-----",
+		"SPECIAL":   `back\slash $VAR #hash`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, `"-----BEGIN KEY-----\nabc\n-----END KEY-----"`, Quote("-----BEGIN KEY-----\nabc\n-----END KEY-----"))
+	util.Equal(t, "value", Quote("value"))
+
+	parsed, err := ParseString(env.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(env.Map(), parsed.Map()) {
+		t.Errorf("expected %v, got %v", env.Map(), parsed.Map())
+	}
+	if !reflect.DeepEqual(env.Keys(), parsed.Keys()) {
+		t.Errorf("expected %v, got %v", env.Keys(), parsed.Keys())
+	}
+}
+
+func Test_Unit_Dotenv_Merge_Validate(t *testing.T) {
+	base, err := ParseString("COMMON_NAME=\nCLOUD_HOST=aws\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := base.Validate("COMMON_NAME", "CLOUD_HOST"); err == nil {
+		t.Fatal("expected missing COMMON_NAME")
+	}
+
+	override, err := FromMap(map[string]string{"COMMON_NAME": "test", "PORT": "8080"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := New().Merge(base, override, nil)
+	if err := merged.Validate("COMMON_NAME", "CLOUD_HOST", "PORT"); err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, "COMMON_NAME=test\nCLOUD_HOST=aws\nPORT=8080\n", merged.String())
+
+	if err := merged.Set("INVALID-KEY", "value"); err == nil {
+		t.Fatal("expected invalid key")
+	}
+}
+
+func Test_Unit_Dotenv_WriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "env", "override.env")
+	env, err := FromMap(map[string]string{"COMMON_NAME": "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(path, env); err != nil {
+		t.Fatal(err)
+	}
+	// writing again replaces the file instead of appending
+	if err := WriteFile(path, env); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, "COMMON_NAME=test\n", string(data))
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, 1, len(entries))
+
+	read, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(env.Map(), read.Map()) {
+		t.Errorf("expected %v, got %v", env.Map(), read.Map())
+	}
+}
@@ -1,12 +1,11 @@
 package iam_team_test
 
 import (
-	"math/rand"
 	"testing"
-	"time"
 
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
+	"github.com/vistimi/infrastructure-modules/test/config"
+	testGithubModule "github.com/vistimi/infrastructure-modules/test/github/module"
 	"github.com/vistimi/infrastructure-modules/test/util"
 )
 
@@ -24,13 +23,13 @@ var (
 )
 
 func Test_Unit_Global_Config(t *testing.T) {
-	// t.Parallel()
-	rand.Seed(time.Now().UnixNano())
-
-	id := util.RandomID(4)
+	t.Parallel()
+	cfg := config.Get(t, config.Github)
+	id := util.TestID(t, 4)
 
+	modulePath := util.CopyModule(t, path)
 	options := &terraform.Options{
-		TerraformDir: path,
+		TerraformDir: modulePath,
 		Vars: map[string]any{
 			"organization": map[string]any{
 				"variables": []map[string]any{
@@ -86,18 +85,39 @@ func Test_Unit_Global_Config(t *testing.T) {
 		},
 	}
 
-	defer func() {
-		if r := recover(); r != nil {
-			// destroy all resources if panic
-			terraform.Destroy(t, options)
-		}
-		terratestStructure.RunTestStage(t, "cleanup", func() {
-			terraform.Destroy(t, options)
-		})
-	}()
+	lifecycle := util.NewLifecycle(t, options)
+	defer lifecycle.Cleanup()
 
-	terratestStructure.RunTestStage(t, "deploy", func() {
+	util.RunTestStage(t, "deploy", func() {
 		terraform.InitAndApply(t, options)
 	})
-	terratestStructure.RunTestStage(t, "validate", func() {})
+	util.RunTestStage(t, "validate", func() {
+		githubAccesses := []testGithubModule.Access{}
+		for _, access := range accesses {
+			githubAccesses = append(githubAccesses, testGithubModule.Access{Owner: access["owner"].(string), Name: access["name"].(string)})
+		}
+
+		client := testGithubModule.NewClient(cfg.GithubToken)
+		testGithubModule.ValidateVariables(t, client, cfg.GithubOwner, testGithubModule.Variables{
+			Organization: testGithubModule.Organization{
+				Variables: []testGithubModule.Variable{{Key: "ORG_" + id, Value: util.Ptr("test")}},
+				Secrets:   []testGithubModule.Variable{{Key: "ORG_" + id}},
+			},
+			Repositories: []testGithubModule.Repository{
+				{
+					Accesses:  githubAccesses,
+					Variables: []testGithubModule.Variable{{Key: "REPO_" + id, Value: util.Ptr("test")}},
+					Secrets:   []testGithubModule.Variable{{Key: "REPO_" + id}},
+				},
+			},
+			Environments: []testGithubModule.Environment{
+				{
+					Name:      id,
+					Accesses:  githubAccesses,
+					Variables: []testGithubModule.Variable{{Key: "ENV_" + id, Value: util.Ptr("test")}},
+					Secrets:   []testGithubModule.Variable{{Key: "ENV_" + id}},
+				},
+			},
+		})
+	})
 }
@@ -0,0 +1,236 @@
+package module
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBaseURL = "https://api.github.com"
+	APIVersion     = "2022-11-28"
+
+	AcceptJSON = "application/vnd.github+json"
+	AcceptRaw  = "application/vnd.github.v3.raw"
+
+	perPage = 100
+)
+
+// Client is a minimal client for the GitHub REST API, same headers as the curl requests of Makefile_infra
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+
+	// Cache stores the answers with their ETag, conditional requests do not count against the rate limit
+	Cache Cache
+	// MaxRateLimitWait is the longest time to wait for the rate limit to reset, the error is returned otherwise
+	MaxRateLimitWait time.Duration
+
+	sleep func(time.Duration)
+	now   func() time.Time
+}
+
+func NewClient(token string) *Client {
+	return &Client{
+		BaseURL:          DefaultBaseURL,
+		Token:            token,
+		HTTPClient:       &http.Client{Timeout: 30 * time.Second},
+		MaxRateLimitWait: 5 * time.Minute,
+	}
+}
+
+// APIError is returned when GitHub answers with an unexpected status
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("github %s %s: status %d: %s", e.Method, e.URL, e.StatusCode, e.Message)
+}
+
+// IsNotFound returns true if the error is a 404 from GitHub
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*APIError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// RateLimitError is returned when the rate limit resets later than MaxRateLimitWait
+type RateLimitError struct {
+	APIError
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s: rate limit resets at %s", e.APIError.Error(), e.Reset.Format(time.RFC3339))
+}
+
+func (c *Client) newRequest(method, path string, query url.Values, accept string) (*http.Request, error) {
+	u := strings.TrimSuffix(c.BaseURL, "/") + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", APIVersion)
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	return req, nil
+}
+
+// do sends the request and waits for the rate limit to reset before retrying once
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	for retry := 0; ; retry++ {
+		res, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode < 300 || res.StatusCode == http.StatusNotModified {
+			return res, nil
+		}
+
+		apiErr := newAPIError(req, res)
+		res.Body.Close()
+		wait, limited := c.rateLimitWait(res)
+		if !limited {
+			return nil, apiErr
+		}
+		if retry > 0 || wait > c.MaxRateLimitWait {
+			return nil, &RateLimitError{APIError: *apiErr, Reset: c.clock().Add(wait)}
+		}
+		c.wait(wait)
+	}
+}
+
+// rateLimitWait returns how long to wait for primary or secondary rate limits
+// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#rate-limiting
+func (c *Client) rateLimitWait(res *http.Response) (time.Duration, bool) {
+	if res.StatusCode != http.StatusForbidden && res.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if retryAfter := res.Header.Get("Retry-After"); retryAfter != "" {
+		seconds, err := strconv.Atoi(retryAfter)
+		if err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	if res.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err != nil {
+			return time.Minute, true
+		}
+		wait := time.Unix(reset, 0).Sub(c.clock())
+		if wait < 0 {
+			wait = 0
+		}
+		return wait + time.Second, true
+	}
+	return 0, false
+}
+
+func (c *Client) wait(d time.Duration) {
+	if c.sleep != nil {
+		c.sleep(d)
+		return
+	}
+	time.Sleep(d)
+}
+
+func (c *Client) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+func newAPIError(req *http.Request, res *http.Response) *APIError {
+	body, _ := io.ReadAll(res.Body)
+	message := strings.TrimSpace(string(body))
+	var payload struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Message != "" {
+		message = payload.Message
+	}
+	return &APIError{Method: req.Method, URL: req.URL.String(), StatusCode: res.StatusCode, Message: message}
+}
+
+// get returns the body of a GET request, served from the cache when GitHub answers 304 Not Modified
+func (c *Client) get(path string, query url.Values, accept string) (body []byte, cached bool, err error) {
+	req, err := c.newRequest(http.MethodGet, path, query, accept)
+	if err != nil {
+		return nil, false, err
+	}
+	key := accept + " " + req.URL.String()
+	var cachedEntry *CacheEntry
+	if c.Cache != nil {
+		if entry, ok := c.Cache.Get(key); ok {
+			cachedEntry = &entry
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+	}
+
+	res, err := c.do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotModified {
+		if cachedEntry == nil {
+			return nil, false, &APIError{Method: req.Method, URL: req.URL.String(), StatusCode: res.StatusCode, Message: "not modified without cache entry"}
+		}
+		return cachedEntry.Body, true, nil
+	}
+
+	body, err = io.ReadAll(res.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	if etag := res.Header.Get("ETag"); c.Cache != nil && etag != "" {
+		if err := c.Cache.Set(key, CacheEntry{ETag: etag, Body: body}); err != nil {
+			return nil, false, err
+		}
+	}
+	return body, false, nil
+}
+
+// getJSON decodes the JSON answer of a GET request
+func (c *Client) getJSON(path string, query url.Values, out any) error {
+	body, _, err := c.get(path, query, AcceptJSON)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
+
+// getPages requests all the pages of a list endpoint, page returns the amount of items of the page and the total count
+func (c *Client) getPages(path string, page func(decode func(out any) error) (count, total int, err error)) error {
+	collected := 0
+	for i := 1; ; i++ {
+		query := url.Values{"per_page": {fmt.Sprint(perPage)}, "page": {fmt.Sprint(i)}}
+		count, total, err := page(func(out any) error { return c.getJSON(path, query, out) })
+		if err != nil {
+			return err
+		}
+		collected += count
+		if count == 0 || collected >= total {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package module
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DefaultOverrideExtension = "override"
+)
+
+// Content is a file or a folder of a repository
+type Content struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"` // file, dir, symlink or submodule
+	Sha  string `json:"sha"`
+	Size int    `json:"size"`
+}
+
+// LoadOptions are the inputs of the gh-load-folder and gh-load-file targets of Makefile_infra
+type LoadOptions struct {
+	Owner      string // ORGANIZATION_NAME
+	Repository string // REPOSITORY_NAME
+	Branch     string // BRANCH_NAME, the default branch of the repository if empty
+	Path       string // REPOSITORY_CONFIG_PATH_FOLDER or REPOSITORY_CONFIG_PATH_FILE
+	// Destination is the folder where the files are written, TERRAGRUNT_CONFIG_PATH
+	Destination string
+	// Extension is appended to the file names, OVERRIDE_EXTENSION
+	Extension string
+	// Recursive loads the sub folders into the same sub folders of the destination
+	Recursive bool
+	// DryRun lists the files without writing them
+	DryRun bool
+}
+
+// LoadedFile is a file of the repository and where it is written
+type LoadedFile struct {
+	Path        string
+	Destination string
+	Size        int
+	Cached      bool
+}
+
+// OverrideFileName returns the name of the file as written by gh-load-file,
+// `config.yml` becomes `config_override.yml` and `Makefile` becomes `Makefile_override`
+func OverrideFileName(filePath, extension string) string {
+	fields := strings.Split(path.Base(filePath), ".")
+	name := fields[0] + "_" + extension
+	if len(fields) > 1 {
+		name += "." + fields[1]
+	}
+	return name
+}
+
+func (c *Client) ListBranches(owner, repo string) (branches []string, err error) {
+	for i := 1; ; i++ {
+		var page []struct {
+			Name string `json:"name"`
+		}
+		query := url.Values{"per_page": {fmt.Sprint(perPage)}, "page": {fmt.Sprint(i)}}
+		if err := c.getJSON(fmt.Sprintf("/repos/%s/%s/branches", url.PathEscape(owner), url.PathEscape(repo)), query, &page); err != nil {
+			return nil, err
+		}
+		for _, branch := range page {
+			branches = append(branches, branch.Name)
+		}
+		if len(page) < perPage {
+			return branches, nil
+		}
+	}
+}
+
+func (c *Client) GetDefaultBranch(owner, repo string) (string, error) {
+	var repository struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := c.getJSON(fmt.Sprintf("/repos/%s/%s", url.PathEscape(owner), url.PathEscape(repo)), nil, &repository); err != nil {
+		return "", err
+	}
+	if repository.DefaultBranch == "" {
+		return "", fmt.Errorf("no default branch for repository %s/%s", owner, repo)
+	}
+	return repository.DefaultBranch, nil
+}
+
+// ListContents returns the entries of a folder, or the file itself if the path is a file
+func (c *Client) ListContents(owner, repo, contentPath, ref string) ([]Content, error) {
+	body, _, err := c.get(contentsPath(owner, repo, contentPath), refQuery(ref), AcceptJSON)
+	if err != nil {
+		return nil, err
+	}
+	var contents []Content
+	if strings.HasPrefix(strings.TrimSpace(string(body)), "[") {
+		err = json.Unmarshal(body, &contents)
+		return contents, err
+	}
+	var content Content
+	if err := json.Unmarshal(body, &content); err != nil {
+		return nil, err
+	}
+	return []Content{content}, nil
+}
+
+// GetFile returns the raw content of a file
+func (c *Client) GetFile(owner, repo, filePath, ref string) ([]byte, error) {
+	body, _, err := c.get(contentsPath(owner, repo, filePath), refQuery(ref), AcceptRaw)
+	return body, err
+}
+
+// Load writes the files of a repository path into the destination with the override naming of Makefile_infra
+func (c *Client) Load(options LoadOptions) ([]LoadedFile, error) {
+	if options.Owner == "" || options.Repository == "" {
+		return nil, fmt.Errorf("owner and repository are required")
+	}
+	if options.Extension == "" {
+		options.Extension = DefaultOverrideExtension
+	}
+	if options.Branch == "" {
+		branch, err := c.GetDefaultBranch(options.Owner, options.Repository)
+		if err != nil {
+			return nil, err
+		}
+		options.Branch = branch
+	}
+
+	files, err := c.listFiles(options, strings.Trim(options.Path, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := []LoadedFile{}
+	for _, file := range files {
+		destination := filepath.Join(options.Destination, filepath.FromSlash(file.relativeDir), OverrideFileName(file.Path, options.Extension))
+		if options.DryRun {
+			loaded = append(loaded, LoadedFile{Path: file.Path, Destination: destination, Size: file.Size})
+			continue
+		}
+		body, cached, err := c.get(contentsPath(options.Owner, options.Repository, file.Path), refQuery(options.Branch), AcceptRaw)
+		if err != nil {
+			return loaded, err
+		}
+		if err := writeFile(destination, body); err != nil {
+			return loaded, err
+		}
+		loaded = append(loaded, LoadedFile{Path: file.Path, Destination: destination, Size: len(body), Cached: cached})
+	}
+	return loaded, nil
+}
+
+type listedFile struct {
+	Content
+	relativeDir string // folder relative to the loaded path
+}
+
+func (c *Client) listFiles(options LoadOptions, root string) ([]listedFile, error) {
+	files := []listedFile{}
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		contents, err := c.ListContents(options.Owner, options.Repository, dir, options.Branch)
+		if err != nil {
+			return err
+		}
+		for _, content := range contents {
+			switch content.Type {
+			case "file":
+				relativeDir := strings.Trim(strings.TrimPrefix(path.Dir(content.Path), root), "/")
+				if content.Path == root || relativeDir == "." {
+					relativeDir = ""
+				}
+				files = append(files, listedFile{Content: content, relativeDir: relativeDir})
+			case "dir":
+				if options.Recursive {
+					if err := walk(content.Path); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+	return files, walk(root)
+}
+
+func contentsPath(owner, repo, contentPath string) string {
+	segments := []string{}
+	for _, segment := range strings.Split(strings.Trim(contentPath, "/"), "/") {
+		if segment != "" {
+			segments = append(segments, url.PathEscape(segment))
+		}
+	}
+	return fmt.Sprintf("/repos/%s/%s/contents/%s", url.PathEscape(owner), url.PathEscape(repo), strings.Join(segments, "/"))
+}
+
+func refQuery(ref string) url.Values {
+	if ref == "" {
+		return nil
+	}
+	return url.Values{"ref": {ref}}
+}
+
+// writeFile replaces the file atomically so a failed download does not leave a truncated config
+func writeFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+"-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
@@ -0,0 +1,164 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+func setupFakeContents(f *fakeGithub) {
+	f.file("vistimi/scraper-backend", "trunk", "config/config.yml", "databases: []\n")
+	f.file("vistimi/scraper-backend", "trunk", "config/Makefile", "prepare:\n")
+	f.file("vistimi/scraper-backend", "trunk", "config/env/override.env", "COMMON_NAME=trunk\n")
+	f.file("vistimi/scraper-backend", "trunk", "README.md", "# scraper\n")
+	f.file("vistimi/scraper-backend", "feature", "config/config.yml", "databases: [feature]\n")
+}
+
+func Test_Unit_Github_OverrideFileName(t *testing.T) {
+	for filePath, expected := range map[string]string{
+		"config/config.yml":     "config_override.yml",
+		"config/Makefile":       "Makefile_override",
+		"config/override.env":   "override_override.env",
+		"config/archive.tar.gz": "archive_override.tar",
+	} {
+		util.Equal(t, expected, OverrideFileName(filePath, "override"))
+	}
+}
+
+func Test_Unit_Github_Load_Folder(t *testing.T) {
+	f := newFakeGithub(t)
+	setupFakeContents(f)
+	destination := t.TempDir()
+
+	loaded, err := f.client().Load(LoadOptions{Owner: "vistimi", Repository: "scraper-backend", Branch: "trunk", Path: "config", Destination: destination})
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, 2, len(loaded))
+
+	data, err := os.ReadFile(filepath.Join(destination, "config_override.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, "databases: []\n", string(data))
+	if _, err := os.Stat(filepath.Join(destination, "Makefile_override")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(destination, "env")); !os.IsNotExist(err) {
+		t.Fatalf("sub folder loaded without recursion: %v", err)
+	}
+}
+
+func Test_Unit_Github_Load_Recursive_DefaultBranch(t *testing.T) {
+	f := newFakeGithub(t)
+	setupFakeContents(f)
+	destination := t.TempDir()
+
+	loaded, err := f.client().Load(LoadOptions{Owner: "vistimi", Repository: "scraper-backend", Path: "config", Destination: destination, Recursive: true, Extension: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, 3, len(loaded))
+	data, err := os.ReadFile(filepath.Join(destination, "env", "override_test.env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, "COMMON_NAME=trunk\n", string(data))
+}
+
+func Test_Unit_Github_Load_File_Branch(t *testing.T) {
+	f := newFakeGithub(t)
+	setupFakeContents(f)
+	destination := t.TempDir()
+
+	_, err := f.client().Load(LoadOptions{Owner: "vistimi", Repository: "scraper-backend", Branch: "feature", Path: "config/config.yml", Destination: destination})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(destination, "config_override.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, "databases: [feature]\n", string(data))
+}
+
+func Test_Unit_Github_Load_DryRun(t *testing.T) {
+	f := newFakeGithub(t)
+	setupFakeContents(f)
+	destination := t.TempDir()
+
+	loaded, err := f.client().Load(LoadOptions{Owner: "vistimi", Repository: "scraper-backend", Branch: "trunk", Path: "config", Destination: destination, Recursive: true, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, 3, len(loaded))
+	util.Equal(t, filepath.Join(destination, "env", "override_override.env"), loaded[2].Destination)
+	entries, err := os.ReadDir(destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, 0, len(entries))
+}
+
+func Test_Unit_Github_Load_ETag(t *testing.T) {
+	f := newFakeGithub(t)
+	setupFakeContents(f)
+	client := f.client()
+	client.Cache = NewMemoryCache()
+	options := LoadOptions{Owner: "vistimi", Repository: "scraper-backend", Branch: "trunk", Path: "config", Destination: t.TempDir()}
+
+	if _, err := client.Load(options); err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, 0, f.notModified)
+
+	loaded, err := client.Load(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the listing and both files
+	util.Equal(t, 3, f.notModified)
+	for _, file := range loaded {
+		if !file.Cached {
+			t.Errorf("file %s not served from cache", file.Path)
+		}
+	}
+}
+
+func Test_Unit_Github_RateLimit(t *testing.T) {
+	f := newFakeGithub(t)
+	setupFakeContents(f)
+	client := f.client()
+	waited := time.Duration(0)
+	client.sleep = func(d time.Duration) { waited += d }
+
+	f.rateLimited = 1
+	branches, err := client.ListBranches("vistimi", "scraper-backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	util.Equal(t, 2, len(branches))
+	if waited <= 0 || waited > 2*time.Minute {
+		t.Errorf("waited %s for the rate limit reset", waited)
+	}
+
+	f.rateLimited = 1
+	client.MaxRateLimitWait = time.Second
+	_, err = client.GetDefaultBranch("vistimi", "scraper-backend")
+	if _, ok := err.(*RateLimitError); !ok {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+}
+
+func Test_Unit_Github_NotFound(t *testing.T) {
+	f := newFakeGithub(t)
+	setupFakeContents(f)
+
+	_, err := f.client().GetFile("vistimi", "scraper-backend", "config/missing.yml", "trunk")
+	if !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package module
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const fakeToken = "token"
+
+type fakeList struct {
+	key   string // field holding the items in the answer
+	items []any
+}
+
+type fakeRepository struct {
+	defaultBranch string
+	branches      map[string]map[string]string // branch -> file path -> content
+}
+
+// fakeGithub serves the list, repository and contents endpoints of the GitHub API
+type fakeGithub struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu           sync.Mutex
+	lists        map[string]fakeList
+	repositories map[string]*fakeRepository // owner/repo
+	requests     []string
+	notModified  int
+	// rateLimited is the amount of next requests answered with an exhausted rate limit
+	rateLimited int
+}
+
+func newFakeGithub(t *testing.T) *fakeGithub {
+	f := &fakeGithub{t: t, lists: map[string]fakeList{}, repositories: map[string]*fakeRepository{}}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeGithub) client() *Client {
+	client := NewClient(fakeToken)
+	client.BaseURL = f.server.URL
+	return client
+}
+
+func (f *fakeGithub) list(path, key string, items ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lists[path] = fakeList{key: key, items: items}
+}
+
+func (f *fakeGithub) file(ownerRepo, branch, filePath, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	repository, ok := f.repositories[ownerRepo]
+	if !ok {
+		repository = &fakeRepository{defaultBranch: branch, branches: map[string]map[string]string{}}
+		f.repositories[ownerRepo] = repository
+	}
+	if repository.branches[branch] == nil {
+		repository.branches[branch] = map[string]string{}
+	}
+	repository.branches[branch][filePath] = content
+}
+
+func (f *fakeGithub) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.URL.RequestURI())
+
+	if r.Header.Get("Authorization") != "Bearer "+fakeToken {
+		writeJSON(w, r, http.StatusUnauthorized, map[string]any{"message": "Bad credentials"})
+		return
+	}
+	if r.Header.Get("X-GitHub-Api-Version") != APIVersion {
+		writeJSON(w, r, http.StatusBadRequest, map[string]any{"message": "missing api version"})
+		return
+	}
+	if f.rateLimited > 0 {
+		f.rateLimited--
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+		writeJSON(w, r, http.StatusForbidden, map[string]any{"message": "API rate limit exceeded"})
+		return
+	}
+
+	if list, ok := f.lists[r.URL.Path]; ok {
+		start, end := pageBounds(r, len(list.items))
+		f.writeCached(w, r, map[string]any{"total_count": len(list.items), list.key: list.items[start:end]})
+		return
+	}
+
+	// /repos/{owner}/{repo}[/branches|/contents/{path}]
+	segments := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/repos/"), "/", 4)
+	if !strings.HasPrefix(r.URL.Path, "/repos/") || len(segments) < 2 {
+		writeJSON(w, r, http.StatusNotFound, map[string]any{"message": "Not Found"})
+		return
+	}
+	repository, ok := f.repositories[segments[0]+"/"+segments[1]]
+	if !ok {
+		writeJSON(w, r, http.StatusNotFound, map[string]any{"message": "Not Found"})
+		return
+	}
+	switch {
+	case len(segments) == 2:
+		f.writeCached(w, r, map[string]any{"default_branch": repository.defaultBranch})
+	case len(segments) == 3 && segments[2] == "branches":
+		names := []string{}
+		for name := range repository.branches {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		start, end := pageBounds(r, len(names))
+		branches := []map[string]any{}
+		for _, name := range names[start:end] {
+			branches = append(branches, map[string]any{"name": name})
+		}
+		f.writeCached(w, r, branches)
+	case segments[2] == "contents":
+		contentPath := ""
+		if len(segments) == 4 {
+			contentPath = strings.Trim(segments[3], "/")
+		}
+		ref := r.URL.Query().Get("ref")
+		if ref == "" {
+			ref = repository.defaultBranch
+		}
+		f.writeContents(w, r, repository.branches[ref], contentPath)
+	default:
+		writeJSON(w, r, http.StatusNotFound, map[string]any{"message": "Not Found"})
+	}
+}
+
+func (f *fakeGithub) writeContents(w http.ResponseWriter, r *http.Request, files map[string]string, contentPath string) {
+	if content, ok := files[contentPath]; ok {
+		if r.Header.Get("Accept") == AcceptRaw {
+			f.writeRaw(w, r, []byte(content))
+			return
+		}
+		f.writeCached(w, r, Content{Name: path.Base(contentPath), Path: contentPath, Type: "file", Size: len(content)})
+		return
+	}
+
+	entries := map[string]Content{}
+	prefix := contentPath + "/"
+	if contentPath == "" {
+		prefix = ""
+	}
+	for filePath, content := range files {
+		if !strings.HasPrefix(filePath, prefix) {
+			continue
+		}
+		name, _, isDir := strings.Cut(strings.TrimPrefix(filePath, prefix), "/")
+		if isDir {
+			entries[name] = Content{Name: name, Path: prefix + name, Type: "dir"}
+		} else {
+			entries[name] = Content{Name: name, Path: filePath, Type: "file", Size: len(content)}
+		}
+	}
+	if len(entries) == 0 {
+		writeJSON(w, r, http.StatusNotFound, map[string]any{"message": "Not Found"})
+		return
+	}
+	names := []string{}
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	contents := []Content{}
+	for _, name := range names {
+		contents = append(contents, entries[name])
+	}
+	f.writeCached(w, r, contents)
+}
+
+func (f *fakeGithub) writeCached(w http.ResponseWriter, r *http.Request, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	f.writeRaw(w, r, data)
+}
+
+// writeRaw answers 304 Not Modified when the ETag matches
+func (f *fakeGithub) writeRaw(w http.ResponseWriter, r *http.Request, data []byte) {
+	sum := sha256.Sum256(data)
+	etag := fmt.Sprintf(`"%s"`, hex.EncodeToString(sum[:8]))
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		f.notModified++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func pageBounds(r *http.Request, length int) (start, end int) {
+	perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if perPage == 0 {
+		perPage = 30
+	}
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page == 0 {
+		page = 1
+	}
+	start, end = (page-1)*perPage, page*perPage
+	if start > length {
+		start = length
+	}
+	if end > length {
+		end = length
+	}
+	return start, end
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
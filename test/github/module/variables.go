@@ -0,0 +1,275 @@
+package module
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+// Variable is a variable or a secret, the value of a secret cannot be read back and is ignored
+type Variable struct {
+	Key   string
+	Value *string // nil only checks the existence of the key
+}
+
+type Access struct {
+	Owner string
+	Name  string
+}
+
+type Organization struct {
+	Variables []Variable
+	Secrets   []Variable
+}
+
+type Repository struct {
+	Accesses  []Access
+	Variables []Variable
+	Secrets   []Variable
+}
+
+type Environment struct {
+	Name      string
+	Accesses  []Access
+	Variables []Variable
+	Secrets   []Variable
+}
+
+// Variables mirrors the inputs of modules/github/variables
+type Variables struct {
+	Organization Organization
+	Repositories []Repository
+	Environments []Environment
+}
+
+// ActionsVariable is a variable as listed by the GitHub API
+type ActionsVariable struct {
+	Name       string `json:"name"`
+	Value      string `json:"value"`
+	Visibility string `json:"visibility"`
+}
+
+// ActionsSecret is a secret as listed by the GitHub API, without its value
+type ActionsSecret struct {
+	Name       string `json:"name"`
+	Visibility string `json:"visibility"`
+}
+
+// ValidateVariables checks that the variables and secret names of the organization, repositories and environments exist
+func ValidateVariables(t *testing.T, client *Client, owner string, variables Variables) {
+	util.RunTestStage(t, "validate_github_variables", func() {
+		terratestLogger.Log(t, fmt.Sprintf("github owner:: %s", owner))
+		if err := ValidateVariablesE(client, owner, variables); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func ValidateVariablesE(client *Client, owner string, variables Variables) error {
+	errs := []string{}
+
+	if len(variables.Organization.Variables) > 0 {
+		orgVariables, err := client.ListOrganizationVariables(owner)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, compareVariables("organization "+owner, variables.Organization.Variables, orgVariables)...)
+		for _, variable := range orgVariables {
+			if contains(variables.Organization.Variables, variable.Name) && variable.Visibility != "all" {
+				errs = append(errs, fmt.Sprintf("organization %s: variable %s visibility %q, expected \"all\"", owner, variable.Name, variable.Visibility))
+			}
+		}
+	}
+	if len(variables.Organization.Secrets) > 0 {
+		orgSecrets, err := client.ListOrganizationSecrets(owner)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, compareSecrets("organization "+owner, variables.Organization.Secrets, orgSecrets)...)
+		for _, secret := range orgSecrets {
+			if contains(variables.Organization.Secrets, secret.Name) && secret.Visibility != "all" {
+				errs = append(errs, fmt.Sprintf("organization %s: secret %s visibility %q, expected \"all\"", owner, secret.Name, secret.Visibility))
+			}
+		}
+	}
+
+	for _, repository := range variables.Repositories {
+		for _, access := range repository.Accesses {
+			scope := fmt.Sprintf("repository %s/%s", access.Owner, access.Name)
+			if len(repository.Variables) > 0 {
+				repoVariables, err := client.ListRepositoryVariables(access.Owner, access.Name)
+				if err != nil {
+					return err
+				}
+				errs = append(errs, compareVariables(scope, repository.Variables, repoVariables)...)
+			}
+			if len(repository.Secrets) > 0 {
+				repoSecrets, err := client.ListRepositorySecrets(access.Owner, access.Name)
+				if err != nil {
+					return err
+				}
+				errs = append(errs, compareSecrets(scope, repository.Secrets, repoSecrets)...)
+			}
+		}
+	}
+
+	for _, environment := range variables.Environments {
+		for _, access := range environment.Accesses {
+			scope := fmt.Sprintf("environment %s of repository %s/%s", environment.Name, access.Owner, access.Name)
+			environmentNames, err := client.ListEnvironments(access.Owner, access.Name)
+			if err != nil {
+				return err
+			}
+			if !containsName(environmentNames, environment.Name) {
+				errs = append(errs, fmt.Sprintf("%s: environment not found in %v", scope, environmentNames))
+				continue
+			}
+			if len(environment.Variables) > 0 {
+				envVariables, err := client.ListEnvironmentVariables(access.Owner, access.Name, environment.Name)
+				if err != nil {
+					return err
+				}
+				errs = append(errs, compareVariables(scope, environment.Variables, envVariables)...)
+			}
+			if len(environment.Secrets) > 0 {
+				envSecrets, err := client.ListEnvironmentSecrets(access.Owner, access.Name, environment.Name)
+				if err != nil {
+					return err
+				}
+				errs = append(errs, compareSecrets(scope, environment.Secrets, envSecrets)...)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("github variables mismatch:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// github stores the names in upper case
+func compareVariables(scope string, expected []Variable, actual []ActionsVariable) (errs []string) {
+	actualMap := map[string]ActionsVariable{}
+	for _, variable := range actual {
+		actualMap[strings.ToUpper(variable.Name)] = variable
+	}
+	for _, variable := range expected {
+		got, ok := actualMap[strings.ToUpper(variable.Key)]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s: variable %s not found", scope, variable.Key))
+			continue
+		}
+		if variable.Value != nil && got.Value != *variable.Value {
+			errs = append(errs, fmt.Sprintf("%s: variable %s has value %q, expected %q", scope, variable.Key, got.Value, *variable.Value))
+		}
+	}
+	return errs
+}
+
+func compareSecrets(scope string, expected []Variable, actual []ActionsSecret) (errs []string) {
+	actualMap := map[string]bool{}
+	for _, secret := range actual {
+		actualMap[strings.ToUpper(secret.Name)] = true
+	}
+	for _, secret := range expected {
+		if !actualMap[strings.ToUpper(secret.Key)] {
+			errs = append(errs, fmt.Sprintf("%s: secret %s not found", scope, secret.Key))
+		}
+	}
+	return errs
+}
+
+func contains(variables []Variable, name string) bool {
+	for _, variable := range variables {
+		if strings.EqualFold(variable.Key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) ListOrganizationVariables(org string) ([]ActionsVariable, error) {
+	return c.listVariables(fmt.Sprintf("/orgs/%s/actions/variables", url.PathEscape(org)))
+}
+
+func (c *Client) ListOrganizationSecrets(org string) ([]ActionsSecret, error) {
+	return c.listSecrets(fmt.Sprintf("/orgs/%s/actions/secrets", url.PathEscape(org)))
+}
+
+func (c *Client) ListRepositoryVariables(owner, repo string) ([]ActionsVariable, error) {
+	return c.listVariables(fmt.Sprintf("/repos/%s/%s/actions/variables", url.PathEscape(owner), url.PathEscape(repo)))
+}
+
+func (c *Client) ListRepositorySecrets(owner, repo string) ([]ActionsSecret, error) {
+	return c.listSecrets(fmt.Sprintf("/repos/%s/%s/actions/secrets", url.PathEscape(owner), url.PathEscape(repo)))
+}
+
+func (c *Client) ListEnvironmentVariables(owner, repo, environment string) ([]ActionsVariable, error) {
+	return c.listVariables(fmt.Sprintf("/repos/%s/%s/environments/%s/variables", url.PathEscape(owner), url.PathEscape(repo), url.PathEscape(environment)))
+}
+
+func (c *Client) ListEnvironmentSecrets(owner, repo, environment string) ([]ActionsSecret, error) {
+	return c.listSecrets(fmt.Sprintf("/repos/%s/%s/environments/%s/secrets", url.PathEscape(owner), url.PathEscape(repo), url.PathEscape(environment)))
+}
+
+func (c *Client) ListEnvironments(owner, repo string) (names []string, err error) {
+	err = c.getPages(fmt.Sprintf("/repos/%s/%s/environments", url.PathEscape(owner), url.PathEscape(repo)), func(decode func(out any) error) (int, int, error) {
+		var page struct {
+			TotalCount   int `json:"total_count"`
+			Environments []struct {
+				Name string `json:"name"`
+			} `json:"environments"`
+		}
+		if err := decode(&page); err != nil {
+			return 0, 0, err
+		}
+		for _, environment := range page.Environments {
+			names = append(names, environment.Name)
+		}
+		return len(page.Environments), page.TotalCount, nil
+	})
+	return names, err
+}
+
+func (c *Client) listVariables(path string) (variables []ActionsVariable, err error) {
+	err = c.getPages(path, func(decode func(out any) error) (int, int, error) {
+		var page struct {
+			TotalCount int               `json:"total_count"`
+			Variables  []ActionsVariable `json:"variables"`
+		}
+		if err := decode(&page); err != nil {
+			return 0, 0, err
+		}
+		variables = append(variables, page.Variables...)
+		return len(page.Variables), page.TotalCount, nil
+	})
+	return variables, err
+}
+
+func (c *Client) listSecrets(path string) (secrets []ActionsSecret, err error) {
+	err = c.getPages(path, func(decode func(out any) error) (int, int, error) {
+		var page struct {
+			TotalCount int             `json:"total_count"`
+			Secrets    []ActionsSecret `json:"secrets"`
+		}
+		if err := decode(&page); err != nil {
+			return 0, 0, err
+		}
+		secrets = append(secrets, page.Secrets...)
+		return len(page.Secrets), page.TotalCount, nil
+	})
+	return secrets, err
+}
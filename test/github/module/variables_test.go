@@ -0,0 +1,132 @@
+package module
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/vistimi/infrastructure-modules/test/util"
+)
+
+var (
+	accesses = []Access{{Owner: "vistimi", Name: "infrastructure-modules"}}
+)
+
+func setupFakeVariables(f *fakeGithub) {
+	orgVariables := []any{}
+	for i := 0; i < 150; i++ {
+		orgVariables = append(orgVariables, ActionsVariable{Name: fmt.Sprintf("OTHER_%d", i), Value: "other", Visibility: "private"})
+	}
+	orgVariables = append(orgVariables, ActionsVariable{Name: "ORG_ABCD", Value: "test", Visibility: "all"})
+	f.list("/orgs/vistimi/actions/variables", "variables", orgVariables...)
+	f.list("/orgs/vistimi/actions/secrets", "secrets", ActionsSecret{Name: "ORG_ABCD", Visibility: "all"})
+	f.list("/repos/vistimi/infrastructure-modules/actions/variables", "variables", ActionsVariable{Name: "REPO_ABCD", Value: "test"})
+	f.list("/repos/vistimi/infrastructure-modules/actions/secrets", "secrets", ActionsSecret{Name: "REPO_ABCD"})
+	f.list("/repos/vistimi/infrastructure-modules/environments", "environments", map[string]any{"name": "abcd"}, map[string]any{"name": "ad1"})
+	f.list("/repos/vistimi/infrastructure-modules/environments/abcd/variables", "variables", ActionsVariable{Name: "ENV_ABCD", Value: "test"})
+	f.list("/repos/vistimi/infrastructure-modules/environments/abcd/secrets", "secrets", ActionsSecret{Name: "ENV_ABCD"})
+	f.list("/repos/vistimi/infrastructure-modules/environments/ad1/variables", "variables",
+		ActionsVariable{Name: "AWS_ACCESS_KEY", Value: "AKIA"},
+		ActionsVariable{Name: "AWS_REGION_NAME", Value: "us-east-1"},
+	)
+	f.list("/repos/vistimi/infrastructure-modules/environments/ad1/secrets", "secrets", ActionsSecret{Name: "AWS_SECRET_KEY"})
+}
+
+func Test_Unit_Github_ValidateVariables(t *testing.T) {
+	f := newFakeGithub(t)
+	setupFakeVariables(f)
+
+	// the terraform inputs use lower case keys for the test ids
+	err := ValidateVariablesE(f.client(), "vistimi", Variables{
+		Organization: Organization{
+			Variables: []Variable{{Key: "ORG_abcd", Value: util.Ptr("test")}},
+			Secrets:   []Variable{{Key: "ORG_abcd"}},
+		},
+		Repositories: []Repository{{
+			Accesses:  accesses,
+			Variables: []Variable{{Key: "REPO_abcd", Value: util.Ptr("test")}},
+			Secrets:   []Variable{{Key: "REPO_abcd"}},
+		}},
+		Environments: []Environment{
+			{
+				Name:      "abcd",
+				Accesses:  accesses,
+				Variables: []Variable{{Key: "ENV_abcd", Value: util.Ptr("test")}},
+				Secrets:   []Variable{{Key: "ENV_abcd"}},
+			},
+			{
+				Name:      "ad1",
+				Accesses:  accesses,
+				Variables: []Variable{{Key: "AWS_ACCESS_KEY"}, {Key: "AWS_REGION_NAME", Value: util.Ptr("us-east-1")}},
+				Secrets:   []Variable{{Key: "AWS_SECRET_KEY"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages := 0
+	for _, request := range f.requests {
+		if strings.HasPrefix(request, "/orgs/vistimi/actions/variables") {
+			pages++
+		}
+	}
+	util.Equal(t, 2, pages)
+}
+
+func Test_Unit_Github_ValidateVariables_Mismatch(t *testing.T) {
+	f := newFakeGithub(t)
+	setupFakeVariables(f)
+	f.list("/orgs/vistimi/actions/secrets", "secrets", ActionsSecret{Name: "ORG_ABCD", Visibility: "private"})
+
+	err := ValidateVariablesE(f.client(), "vistimi", Variables{
+		Organization: Organization{
+			Secrets: []Variable{{Key: "ORG_abcd"}},
+		},
+		Repositories: []Repository{{
+			Accesses:  accesses,
+			Variables: []Variable{{Key: "REPO_abcd", Value: util.Ptr("other")}, {Key: "REPO_missing"}},
+		}},
+		Environments: []Environment{
+			{
+				Name:     "ad1",
+				Accesses: accesses,
+				Secrets:  []Variable{{Key: "AWS_SECRET_KEY"}, {Key: "AWS_SESSION_TOKEN"}},
+			},
+			{
+				Name:      "dev1",
+				Accesses:  accesses,
+				Variables: []Variable{{Key: "AWS_ACCESS_KEY"}},
+			},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected mismatch error")
+	}
+	for _, expected := range []string{
+		`organization vistimi: secret ORG_ABCD visibility "private"`,
+		`repository vistimi/infrastructure-modules: variable REPO_abcd has value "test", expected "other"`,
+		`repository vistimi/infrastructure-modules: variable REPO_missing not found`,
+		`environment ad1 of repository vistimi/infrastructure-modules: secret AWS_SESSION_TOKEN not found`,
+		`environment dev1 of repository vistimi/infrastructure-modules: environment not found`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("missing %q in error:\n%s", expected, err)
+		}
+	}
+}
+
+func Test_Unit_Github_ValidateVariables_Unauthorized(t *testing.T) {
+	f := newFakeGithub(t)
+	setupFakeVariables(f)
+	client := f.client()
+	client.Token = "wrong"
+
+	err := ValidateVariablesE(client, "vistimi", Variables{Organization: Organization{Variables: []Variable{{Key: "ORG_abcd"}}}})
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("expected api error, got %v", err)
+	}
+	util.Equal(t, 401, apiErr.StatusCode)
+}
@@ -1,13 +1,140 @@
 package util
 
-import "math/rand"
+import (
+	cryptoRand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	// TestSeedEnv reproduces the ids of a run, the seed is logged by TestID
+	TestSeedEnv = "TEST_SEED"
+	// IDMaxAttempts is the number of ids generated before giving up when they are all in use
+	IDMaxAttempts = 10
+)
 
 var letterRunes = []rune("abcdefghijklmnopqrstuvwxyz")
 
+// IDInUse returns true when the id is already used, e.g. by the resources of another run
+type IDInUse func(id string) (bool, error)
+
+var (
+	idSeedOnce sync.Once
+	idSeed     int64
+	idSeedErr  error
+
+	idCallsMu sync.Mutex
+	idCalls   = map[string]int{} // test name
+)
+
+// RandomID returns a crypto random id, use TestID for the ids of the resources of a test
 func RandomID(n int) string {
 	b := make([]rune, n)
 	for i := range b {
-		b[i] = letterRunes[rand.Intn(len(letterRunes))]
+		index, err := cryptoRand.Int(cryptoRand.Reader, big.NewInt(int64(len(letterRunes))))
+		if err != nil {
+			panic(fmt.Sprintf("crypto random: %v", err))
+		}
+		b[i] = letterRunes[index.Int64()]
 	}
 	return string(b)
 }
+
+// IDSeed is the seed of the run, from TEST_SEED or crypto random so that parallel jobs do not share it
+func IDSeed() (int64, error) {
+	idSeedOnce.Do(func() {
+		idSeed, idSeedErr = idSeedE(os.Getenv)
+	})
+	return idSeed, idSeedErr
+}
+
+func idSeedE(getenv func(string) string) (int64, error) {
+	if value := strings.TrimSpace(getenv(TestSeedEnv)); value != "" {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s %q is not an integer: %w", TestSeedEnv, value, err)
+		}
+		return seed, nil
+	}
+	b := make([]byte, 8)
+	if _, err := cryptoRand.Read(b); err != nil {
+		return 0, fmt.Errorf("crypto random seed: %w", err)
+	}
+	return int64(binary.BigEndian.Uint64(b) >> 1), nil
+}
+
+// TestID returns an id of n letters for the resources of the test that is not in use, by the checks given or in the state directories,
+// the seed is logged to reproduce it, the id of the previous run is reused when resuming
+func TestID(t *testing.T, n int, inUse ...IDInUse) string {
+	return resumeTestID(t, func() string {
+		seed, err := IDSeed()
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, err := TestIDE(seed, t.Name(), n, testIDChecks(t.Name(), os.Getenv, inUse)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("test id %s, %s=%d to reproduce", id, TestSeedEnv, seed)
+		return id
+	})
+}
+
+// testIDChecks adds the ids saved in the state directories of the other tests,
+// except with TEST_SEED that reproduces the ids of a run even when its state is kept
+func testIDChecks(testName string, getenv func(string) string, inUse []IDInUse) []IDInUse {
+	if strings.TrimSpace(getenv(TestSeedEnv)) != "" {
+		return inUse
+	}
+	return append([]IDInUse{StateIDInUse(testName)}, inUse...)
+}
+
+// TestIDE derives the id from the seed, the name of the test and the number of ids already given to the test,
+// so the ids of a test do not depend on the order of the parallel tests
+func TestIDE(seed int64, testName string, n int, inUse ...IDInUse) (string, error) {
+	idCallsMu.Lock()
+	call := idCalls[testName]
+	idCalls[testName]++
+	idCallsMu.Unlock()
+
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%s/%d", testName, call)
+	random := rand.New(rand.NewSource(seed ^ int64(hash.Sum64())))
+
+	for attempt := 0; attempt < IDMaxAttempts; attempt++ {
+		b := make([]rune, n)
+		for i := range b {
+			b[i] = letterRunes[random.Intn(len(letterRunes))]
+		}
+		id := string(b)
+		used, err := idInUse(id, inUse)
+		if err != nil {
+			return "", err
+		}
+		if !used {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("no id of %d letters available for %s after %d attempts", n, testName, IDMaxAttempts)
+}
+
+func idInUse(id string, inUse []IDInUse) (bool, error) {
+	for _, check := range inUse {
+		used, err := check(id)
+		if err != nil {
+			return false, fmt.Errorf("check id %s: %w", id, err)
+		}
+		if used {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,85 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_Unit_TestIDE(t *testing.T) {
+	name := t.Name() + "/reproducible"
+	first, err := TestIDE(42, name, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := TestIDE(42, name, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equal(t, 6, len(first))
+	if first == second {
+		t.Errorf("the second id of the test is the same as the first: %s", first)
+	}
+
+	// a run with the same seed gives the same ids to the test
+	idCallsMu.Lock()
+	delete(idCalls, name)
+	idCallsMu.Unlock()
+	reproduced, err := TestIDE(42, name, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equal(t, first, reproduced)
+
+	other, err := TestIDE(43, t.Name()+"/other-seed", 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == first {
+		t.Errorf("the ids of different seeds are the same: %s", other)
+	}
+}
+
+func Test_Unit_TestIDE_InUse(t *testing.T) {
+	used := map[string]bool{}
+	inUse := func(id string) (bool, error) {
+		if len(used) < 3 {
+			used[id] = true
+			return true, nil
+		}
+		return used[id], nil
+	}
+	id, err := TestIDE(1, t.Name(), 4, inUse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used[id] {
+		t.Errorf("id %s is in use", id)
+	}
+	Equal(t, 3, len(used))
+
+	_, err = TestIDE(1, t.Name(), 4, func(id string) (bool, error) { return true, nil })
+	if err == nil || !strings.Contains(err.Error(), "after 10 attempts") {
+		t.Errorf("expected no id available, got %v", err)
+	}
+	_, err = TestIDE(1, t.Name(), 4, func(id string) (bool, error) { return false, fmt.Errorf("access denied") })
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("expected the error of the check, got %v", err)
+	}
+}
+
+func Test_Unit_IDSeed(t *testing.T) {
+	seed, err := idSeedE(func(string) string { return " 1234 " })
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equal(t, 1234, int(seed))
+	if _, err := idSeedE(func(string) string { return "abc" }); err == nil || !strings.Contains(err.Error(), TestSeedEnv) {
+		t.Errorf("expected an invalid seed, got %v", err)
+	}
+	first, _ := idSeedE(func(string) string { return "" })
+	second, _ := idSeedE(func(string) string { return "" })
+	if first == second || first < 0 {
+		t.Errorf("expected different positive random seeds, got %d and %d", first, second)
+	}
+}
@@ -0,0 +1,48 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// KeepOnFailureEnv keeps the resources of a failed test to investigate them, the test logs how to access and destroy them
+const KeepOnFailureEnv = "KEEP_ON_FAILURE"
+
+// KeepOnFailure is true when the resources of a failed test are not destroyed
+func KeepOnFailure() bool {
+	return os.Getenv(KeepOnFailureEnv) != ""
+}
+
+// DestroyCommand is the command that resumes the test from its saved state only to destroy its resources
+func DestroyCommand(testName string) string {
+	return fmt.Sprintf("SKIP_%s=true SKIP_validate=true go test %s -count=1 -run '%s'", stageDeploy, testPackage(), testPattern(testName))
+}
+
+// testPattern matches exactly the test and its subtests, each level of the name is a separate expression
+func testPattern(testName string) string {
+	levels := []string{}
+	for _, level := range strings.Split(testName, "/") {
+		levels = append(levels, "^"+regexp.QuoteMeta(level)+"$")
+	}
+	return strings.Join(levels, "/")
+}
+
+// testPackage is the package of the running test relative to the root of the repository, the current package when unknown
+func testPackage() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	root, err := repositoryRoot(dir)
+	if err != nil {
+		return "."
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return "."
+	}
+	return "./" + filepath.ToSlash(rel)
+}
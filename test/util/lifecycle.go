@@ -0,0 +1,159 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	terratestLogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	terratestTesting "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// DestroyRetryableErrors are the transient errors of a destroy, the resources are still being released by AWS
+var DestroyRetryableErrors = map[string]string{
+	".*DependencyViolation.*":                               "a dependent resource is still being deleted",
+	".*InvalidNetworkInterface.InUse.*":                     "the network interface is still in use",
+	".*[Nn]etwork interface .* (is )?(currently )?in use.*": "the network interface is still in use",
+	".*ResourceInUse.*":                                     "the resource is still in use",
+	".*has tasks in the state.*":                            "the tasks of the service are still stopping",
+	".*Throttling.*":                                        "the API is throttled",
+}
+
+const (
+	DestroyMaxRetries          = 5
+	DestroySleepBetweenRetries = 30 * time.Second
+)
+
+// Lifecycle destroys the terraform resources of a test exactly once, whether the test passes, fails or panics
+//
+//	lifecycle := util.NewLifecycle(t, options)
+//	defer lifecycle.Cleanup()
+type Lifecycle struct {
+	t       *testing.T
+	options *terraform.Options
+
+	once sync.Once
+	// Leftovers are the resources of the state after a failed destroy
+	Leftovers []string
+
+	// replaced in the tests
+	destroy   func(t terratestTesting.TestingT, options *terraform.Options) (string, error)
+	stateList func(t terratestTesting.TestingT, options *terraform.Options) (string, error)
+	errorf    func(format string, args ...any)
+	failed    func() bool
+}
+
+// NewLifecycle registers the options and saves them to resume the test, the retries of the destroy default to DestroyRetryableErrors, DestroyMaxRetries and DestroySleepBetweenRetries
+func NewLifecycle(t *testing.T, options *terraform.Options) *Lifecycle {
+	ResumeOptions(t, options)
+	return &Lifecycle{
+		t:       t,
+		options: options,
+		destroy: terraform.DestroyE,
+		stateList: func(t terratestTesting.TestingT, options *terraform.Options) (string, error) {
+			return terraform.RunTerraformCommandE(t, options, "state", "list")
+		},
+		errorf: t.Errorf,
+		failed: t.Failed,
+	}
+}
+
+// Cleanup is deferred, it destroys the resources in the cleanup stage, also after a panic that it re-raises.
+// With SKIP_cleanup the resources are kept, even after a panic, to resume the test against them,
+// with KEEP_ON_FAILURE the resources of a failed test are kept with its state to destroy them later
+func (l *Lifecycle) Cleanup() {
+	r := recover()
+	if KeepOnFailure() && (r != nil || l.failed()) {
+		l.keep()
+		if r != nil {
+			panic(r)
+		}
+		return
+	}
+	RunTestStage(l.t, stageCleanup, l.Destroy)
+	if r != nil {
+		panic(r)
+	}
+}
+
+// Destroy runs the destroy the first time only, a failure marks the test as failed and records the leftovers, it never panics
+func (l *Lifecycle) Destroy() {
+	l.once.Do(func() {
+		if err := l.destroyE(); err != nil {
+			l.errorf("destroy: %v", err)
+			l.recordLeftovers()
+		}
+	})
+}
+
+func (l *Lifecycle) destroyE() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	options := l.retryOptions()
+	_, err = l.destroy(l.t, options)
+	return err
+}
+
+// retryOptions adds the retries of the destroy to a copy of the options, the ones of the test are kept
+func (l *Lifecycle) retryOptions() *terraform.Options {
+	options := *l.options
+	options.RetryableTerraformErrors = map[string]string{}
+	for pattern, message := range DestroyRetryableErrors {
+		options.RetryableTerraformErrors[pattern] = message
+	}
+	for pattern, message := range l.options.RetryableTerraformErrors {
+		options.RetryableTerraformErrors[pattern] = message
+	}
+	if options.MaxRetries == 0 {
+		options.MaxRetries = DestroyMaxRetries
+	}
+	if options.TimeBetweenRetries == 0 {
+		options.TimeBetweenRetries = DestroySleepBetweenRetries
+	}
+	return &options
+}
+
+// keep skips the cleanup stage in the report and logs the command to destroy the resources from the saved state
+func (l *Lifecycle) keep() {
+	report := reportOf(l.t)
+	report.end(report.begin(stageCleanup), OutcomeSkipped, KeepOnFailureEnv)
+	terratestLogger.Log(l.t, fmt.Sprintf("%s is set, the resources of %s are kept with the state in %s, destroy them with:\n%s", KeepOnFailureEnv, l.options.TerraformDir, StateDir(l.t.Name()), DestroyCommand(l.t.Name())))
+}
+
+// recordLeftovers lists the resources still in the state, they are logged, reported and written in `leftovers.txt` of the artifacts
+func (l *Lifecycle) recordLeftovers() {
+	output, err := l.stateList(l.t, l.options)
+	if err != nil {
+		terratestLogger.Log(l.t, fmt.Sprintf("leftovers unknown, state list: %v", err))
+		return
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			l.Leftovers = append(l.Leftovers, line)
+		}
+	}
+	if len(l.Leftovers) == 0 {
+		return
+	}
+
+	terratestLogger.Log(l.t, fmt.Sprintf("leftovers of %s:\n%s", l.options.TerraformDir, strings.Join(l.Leftovers, "\n")))
+	for _, leftover := range l.Leftovers {
+		ReportResource(l.t, "leftover", leftover)
+	}
+	dir := ArtifactsDir(l.t.Name())
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		terratestLogger.Log(l.t, fmt.Sprintf("leftovers not written: %v", err))
+		return
+	}
+	if err := os.WriteFile(filepath.Join(dir, "leftovers.txt"), []byte(strings.Join(l.Leftovers, "\n")+"\n"), 0o644); err != nil {
+		terratestLogger.Log(l.t, fmt.Sprintf("leftovers not written: %v", err))
+	}
+}
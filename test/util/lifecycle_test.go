@@ -0,0 +1,158 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	terratestTesting "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+type fakeLifecycle struct {
+	*Lifecycle
+	destroyCalls int
+	options      []*terraform.Options
+	errs         []string
+}
+
+// newFakeLifecycle destroys with the error or panic given, the state keeps the leftovers
+func newFakeLifecycle(t *testing.T, err error, panicValue any, leftovers string) *fakeLifecycle {
+	t.Setenv(ArtifactsDirEnv, t.TempDir())
+	t.Setenv(StateDirEnv, t.TempDir())
+	options := &terraform.Options{TerraformDir: "modules/aws/container/microservice", RetryableTerraformErrors: map[string]string{".*timeout.*": "timeout"}}
+	f := &fakeLifecycle{Lifecycle: NewLifecycle(t, options)}
+	f.destroy = func(t terratestTesting.TestingT, options *terraform.Options) (string, error) {
+		f.destroyCalls++
+		f.options = append(f.options, options)
+		if panicValue != nil {
+			panic(panicValue)
+		}
+		return "", err
+	}
+	f.stateList = func(t terratestTesting.TestingT, options *terraform.Options) (string, error) {
+		return leftovers, nil
+	}
+	f.errorf = func(format string, args ...any) {
+		f.errs = append(f.errs, fmt.Sprintf(format, args...))
+	}
+	return f
+}
+
+func Test_Unit_Lifecycle(t *testing.T) {
+	lifecycle := newFakeLifecycle(t, nil, nil, "")
+	func() {
+		defer lifecycle.Cleanup()
+	}()
+	lifecycle.Destroy()
+
+	Equal(t, 1, lifecycle.destroyCalls)
+	Equal(t, 0, len(lifecycle.errs))
+	options := lifecycle.options[0]
+	Equal(t, DestroyMaxRetries, options.MaxRetries)
+	Equal(t, "timeout", options.RetryableTerraformErrors[".*timeout.*"])
+	Equal(t, "a dependent resource is still being deleted", options.RetryableTerraformErrors[".*DependencyViolation.*"])
+	// the options of the test are untouched
+	Equal(t, 1, len(lifecycle.Lifecycle.options.RetryableTerraformErrors))
+	Equal(t, 0, lifecycle.Lifecycle.options.MaxRetries)
+}
+
+func Test_Unit_Lifecycle_Panic(t *testing.T) {
+	testCases := []struct {
+		skip                 string
+		expectedDestroyCalls int
+	}{
+		{skip: "", expectedDestroyCalls: 1},
+		// the stack is kept to resume the validations, e.g. after a panic of a validator
+		{skip: "true", expectedDestroyCalls: 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run("skip cleanup "+testCase.skip, func(t *testing.T) {
+			t.Setenv("SKIP_cleanup", testCase.skip)
+			lifecycle := newFakeLifecycle(t, nil, nil, "")
+			r := func() (r any) {
+				defer func() { r = recover() }()
+				defer lifecycle.Cleanup()
+				panic("validation failed")
+			}()
+			Equal(t, "validation failed", fmt.Sprint(r))
+			Equal(t, testCase.expectedDestroyCalls, lifecycle.destroyCalls)
+		})
+	}
+}
+
+func Test_Unit_Lifecycle_SkipCleanup(t *testing.T) {
+	t.Setenv("SKIP_cleanup", "true")
+	lifecycle := newFakeLifecycle(t, nil, nil, "")
+	func() {
+		defer lifecycle.Cleanup()
+	}()
+	Equal(t, 0, lifecycle.destroyCalls)
+}
+
+func Test_Unit_Lifecycle_Leftovers(t *testing.T) {
+	lifecycle := newFakeLifecycle(t, fmt.Errorf("DependencyViolation: resource sg-0 has a dependent object"), nil, "module.ecs.aws_security_group.this\nmodule.vpc.aws_subnet.this[0]\n")
+	func() {
+		defer lifecycle.Cleanup()
+	}()
+	Equal(t, 1, lifecycle.destroyCalls)
+	Equal(t, "destroy: DependencyViolation: resource sg-0 has a dependent object", strings.Join(lifecycle.errs, "\n"))
+	Equal(t, "module.ecs.aws_security_group.this,module.vpc.aws_subnet.this[0]", strings.Join(lifecycle.Leftovers, ","))
+
+	content, err := os.ReadFile(filepath.Join(ArtifactsDir(t.Name()), "leftovers.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equal(t, "module.ecs.aws_security_group.this\nmodule.vpc.aws_subnet.this[0]\n", string(content))
+}
+
+func Test_Unit_Lifecycle_DestroyPanic(t *testing.T) {
+	// the panic of the destroy does not hide the one of the test
+	lifecycle := newFakeLifecycle(t, nil, "destroy failed", "")
+	r := func() (r any) {
+		defer func() { r = recover() }()
+		defer lifecycle.Cleanup()
+		panic("apply failed")
+	}()
+	Equal(t, "apply failed", fmt.Sprint(r))
+	Equal(t, 1, lifecycle.destroyCalls)
+	Equal(t, "destroy: panic: destroy failed", strings.Join(lifecycle.errs, "\n"))
+}
+
+func Test_Unit_Lifecycle_KeepOnFailure(t *testing.T) {
+	t.Run("failed", func(t *testing.T) {
+		t.Setenv(KeepOnFailureEnv, "true")
+		lifecycle := newFakeLifecycle(t, nil, nil, "")
+		lifecycle.failed = func() bool { return true }
+		func() {
+			defer lifecycle.Cleanup()
+		}()
+		Equal(t, 0, lifecycle.destroyCalls)
+	})
+	t.Run("panic", func(t *testing.T) {
+		t.Setenv(KeepOnFailureEnv, "true")
+		lifecycle := newFakeLifecycle(t, nil, nil, "")
+		r := func() (r any) {
+			defer func() { r = recover() }()
+			defer lifecycle.Cleanup()
+			panic("validation failed")
+		}()
+		Equal(t, "validation failed", fmt.Sprint(r))
+		Equal(t, 0, lifecycle.destroyCalls)
+	})
+	t.Run("passed", func(t *testing.T) {
+		t.Setenv(KeepOnFailureEnv, "true")
+		lifecycle := newFakeLifecycle(t, nil, nil, "")
+		func() {
+			defer lifecycle.Cleanup()
+		}()
+		Equal(t, 1, lifecycle.destroyCalls)
+	})
+}
+
+func Test_Unit_DestroyCommand(t *testing.T) {
+	Equal(t, "SKIP_deploy=true SKIP_validate=true go test ./test/util -count=1 -run '^Test_Unit_Microservice_Rest$/^trunk\\.v1$'", DestroyCommand("Test_Unit_Microservice_Rest/trunk.v1"))
+}
@@ -0,0 +1,345 @@
+package util
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
+)
+
+// ArtifactsDirEnv is the directory of the reports and diagnostics, written in `<ARTIFACTS_DIR>/<test name>`, the temporary directory by default
+const ArtifactsDirEnv = "ARTIFACTS_DIR"
+
+const (
+	OutcomePassed  = "passed"
+	OutcomeFailed  = "failed"
+	OutcomePanic   = "panic"
+	OutcomeSkipped = "skipped"
+	// the stage did not end, e.g. the test timed out
+	OutcomeRunning = "running"
+)
+
+// ArtifactsDir is the directory of the artifacts of a test, the subtests are flattened
+func ArtifactsDir(testName string) string {
+	dir := os.Getenv(ArtifactsDirEnv)
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "artifacts")
+	}
+	return filepath.Join(dir, strings.ReplaceAll(testName, "/", "_"))
+}
+
+type ResourceReport struct {
+	Kind string `json:"kind"`
+	Id   string `json:"id"`
+}
+
+type EndpointReport struct {
+	Target         string `json:"target"`
+	ExpectedStatus int    `json:"expected_status,omitempty"`
+	Status         int    `json:"status,omitempty"`
+	Attempts       int    `json:"attempts"`
+	Passed         bool   `json:"passed"`
+	Message        string `json:"message,omitempty"`
+}
+
+type StageReport struct {
+	// nested stages are joined with `/`, e.g. `validate/validate_ecs`
+	Name      string           `json:"name"`
+	Start     time.Time        `json:"start"`
+	End       time.Time        `json:"end"`
+	Outcome   string           `json:"outcome"`
+	Message   string           `json:"message,omitempty"`
+	Retries   int              `json:"retries"`
+	Resources []ResourceReport `json:"resources,omitempty"`
+	Endpoints []EndpointReport `json:"endpoints,omitempty"`
+}
+
+// Report records the stages of a scenario, it is written when the test ends, even after a panic
+type Report struct {
+	Test    string         `json:"test"`
+	Start   time.Time      `json:"start"`
+	End     time.Time      `json:"end"`
+	Outcome string         `json:"outcome"`
+	Stages  []*StageReport `json:"stages"`
+
+	mu sync.Mutex
+	// open stages, the last one is the current one
+	open []*StageReport
+}
+
+var (
+	reportsMu sync.Mutex
+	reports   = map[string]*Report{} // test name
+)
+
+// testNamer is implemented by *testing.T and terratest's TestingT, so that the E functions can report too
+type testNamer interface {
+	Name() string
+}
+
+// RunTestStage runs the stage like terratest, `SKIP_<stage>` skips it, and records it in the report of the test
+func RunTestStage(t *testing.T, stageName string, stage func()) {
+	report := reportOf(t)
+	stageReport := report.begin(stageName)
+	if os.Getenv("SKIP_"+stageName) != "" {
+		report.end(stageReport, OutcomeSkipped, "")
+		terratestStructure.RunTestStage(t, stageName, stage)
+		return
+	}
+
+	failedBefore := t.Failed()
+	defer func() {
+		r := recover()
+		switch {
+		case r != nil:
+			report.end(stageReport, OutcomePanic, fmt.Sprint(r))
+			panic(r)
+		case t.Failed() && !failedBefore:
+			report.end(stageReport, OutcomeFailed, "")
+		default:
+			report.end(stageReport, OutcomePassed, "")
+		}
+	}()
+	terratestStructure.RunTestStage(t, stageName, stage)
+}
+
+// reportOf returns the report of the test, the first call registers its writing at the end of the test
+func reportOf(t *testing.T) *Report {
+	reportsMu.Lock()
+	defer reportsMu.Unlock()
+	if report, ok := reports[t.Name()]; ok {
+		return report
+	}
+	report := &Report{Test: t.Name(), Start: time.Now(), Stages: []*StageReport{}}
+	reports[t.Name()] = report
+	t.Cleanup(func() {
+		reportsMu.Lock()
+		delete(reports, t.Name())
+		reportsMu.Unlock()
+		report.finish(t.Failed())
+		dir := ArtifactsDir(t.Name())
+		if err := report.WriteE(dir); err != nil {
+			t.Logf("report not written: %v", err)
+			return
+		}
+		t.Logf("report written in %s", dir)
+	})
+	return report
+}
+
+// currentStage returns the innermost stage running for the test, nil otherwise
+func currentStage(t testNamer) (*Report, *StageReport) {
+	reportsMu.Lock()
+	report, ok := reports[t.Name()]
+	reportsMu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	if len(report.open) == 0 {
+		return report, nil
+	}
+	return report, report.open[len(report.open)-1]
+}
+
+// ReportRetry counts a retry in the current stage
+func ReportRetry(t testNamer) {
+	if report, stage := currentStage(t); stage != nil {
+		report.mu.Lock()
+		defer report.mu.Unlock()
+		stage.Retries++
+	}
+}
+
+// ReportResource records a resource involved in the current stage
+func ReportResource(t testNamer, kind, id string) {
+	if report, stage := currentStage(t); stage != nil {
+		report.mu.Lock()
+		defer report.mu.Unlock()
+		stage.Resources = append(stage.Resources, ResourceReport{Kind: kind, Id: id})
+	}
+}
+
+// ReportEndpoint records the result of an endpoint check in the current stage
+func ReportEndpoint(t testNamer, endpoint EndpointReport) {
+	if report, stage := currentStage(t); stage != nil {
+		report.mu.Lock()
+		defer report.mu.Unlock()
+		stage.Endpoints = append(stage.Endpoints, endpoint)
+	}
+}
+
+func (r *Report) begin(stageName string) *StageReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := []string{}
+	for _, stage := range r.open {
+		names = append(names, stage.Name)
+	}
+	if len(names) > 0 {
+		stageName = names[len(names)-1] + "/" + stageName
+	}
+	stage := &StageReport{Name: stageName, Start: time.Now(), Outcome: OutcomeRunning}
+	r.Stages = append(r.Stages, stage)
+	r.open = append(r.open, stage)
+	return stage
+}
+
+func (r *Report) end(stage *StageReport, outcome, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stage.End = time.Now()
+	stage.Outcome = outcome
+	stage.Message = message
+	for i := len(r.open) - 1; i >= 0; i-- {
+		if r.open[i] == stage {
+			r.open = r.open[:i]
+			break
+		}
+	}
+}
+
+// finish sets the outcome of the test, a panic in a stage is kept even if the scenario recovered from it
+func (r *Report) finish(failed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.End = time.Now()
+	r.Outcome = OutcomePassed
+	if failed {
+		r.Outcome = OutcomeFailed
+	}
+	for _, stage := range r.Stages {
+		if stage.Outcome == OutcomeRunning {
+			stage.End = r.End
+		}
+		if stage.Outcome == OutcomePanic {
+			r.Outcome = OutcomePanic
+		}
+	}
+}
+
+// WriteE writes the report as `report.json` and `junit.xml` in the directory
+func (r *Report) WriteE(dir string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create report directory: %w", err)
+	}
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode report: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "report.json"), content, 0o644); err != nil {
+		return fmt.Errorf("write report.json: %w", err)
+	}
+	content, err = xml.MarshalIndent(r.junit(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode junit: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "junit.xml"), append([]byte(xml.Header), content...), 0o644); err != nil {
+		return fmt.Errorf("write junit.xml: %w", err)
+	}
+	return nil
+}
+
+// https://github.com/testmoapp/junitxml
+type junitTestSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// junit maps the stages to test cases, the failures are the failed stages, the errors are the panics and the stages that did not end
+func (r *Report) junit() junitTestSuites {
+	suite := junitSuite{
+		Name:      r.Test,
+		Tests:     len(r.Stages),
+		Time:      seconds(r.End.Sub(r.Start)),
+		Timestamp: r.Start.UTC().Format(time.RFC3339),
+	}
+	for _, stage := range r.Stages {
+		testCase := junitTestCase{ClassName: r.Test, Name: stage.Name, Time: seconds(stage.End.Sub(stage.Start)), SystemOut: stage.details()}
+		switch stage.Outcome {
+		case OutcomeFailed:
+			suite.Failures++
+			testCase.Failure = &junitMessage{Message: fmt.Sprintf("stage %s failed", stage.Name)}
+		case OutcomePanic:
+			suite.Errors++
+			testCase.Error = &junitMessage{Message: fmt.Sprintf("stage %s panicked", stage.Name), Text: stage.Message}
+		case OutcomeRunning:
+			suite.Errors++
+			testCase.Error = &junitMessage{Message: fmt.Sprintf("stage %s did not end", stage.Name)}
+		case OutcomeSkipped:
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{Message: fmt.Sprintf("SKIP_%s is set", stage.Name[strings.LastIndex(stage.Name, "/")+1:])}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	return junitTestSuites{Suites: []junitSuite{suite}}
+}
+
+// details are the retries, resources and endpoints of the stage in text
+func (s *StageReport) details() string {
+	lines := []string{}
+	if s.Retries > 0 {
+		lines = append(lines, fmt.Sprintf("retries: %d", s.Retries))
+	}
+	for _, resource := range s.Resources {
+		lines = append(lines, fmt.Sprintf("resource %s: %s", resource.Kind, resource.Id))
+	}
+	for _, endpoint := range s.Endpoints {
+		outcome := OutcomeFailed
+		if endpoint.Passed {
+			outcome = OutcomePassed
+		}
+		line := fmt.Sprintf("endpoint %s: %s after %d attempts", endpoint.Target, outcome, endpoint.Attempts)
+		if endpoint.Status != 0 {
+			line += fmt.Sprintf(", status %d expected %d", endpoint.Status, endpoint.ExpectedStatus)
+		}
+		if endpoint.Message != "" {
+			line += ", " + endpoint.Message
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func seconds(duration time.Duration) string {
+	if duration < 0 {
+		duration = 0
+	}
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
@@ -0,0 +1,110 @@
+package util
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Unit_RunTestStage_Report(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(ArtifactsDirEnv, dir)
+	t.Setenv("SKIP_cleanup", "true")
+
+	t.Run("scenario", func(t *testing.T) {
+		// the panic is recovered like the destroy of the scenarios
+		func() {
+			defer func() { recover() }()
+			RunTestStage(t, "deploy", func() {
+				ReportResource(t, "ecs_cluster", "vi-rest-test")
+				panic("apply failed")
+			})
+		}()
+		RunTestStage(t, "validate", func() {
+			RunTestStage(t, "validate_ecs", func() {
+				ReportRetry(t)
+				ReportRetry(t)
+			})
+			RunTestStage(t, "validate_rest_endpoints_load_balancer", func() {
+				ReportEndpoint(t, EndpointReport{Target: "http://lb:80/", ExpectedStatus: 200, Status: 200, Attempts: 1, Passed: true})
+			})
+		})
+		RunTestStage(t, "cleanup", func() {
+			t.Error("skipped stages do not run")
+		})
+	})
+
+	content, err := os.ReadFile(filepath.Join(dir, "Test_Unit_RunTestStage_Report_scenario", "report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report Report
+	if err := json.Unmarshal(content, &report); err != nil {
+		t.Fatal(err)
+	}
+	Equal(t, OutcomePanic, report.Outcome)
+	names := []string{}
+	for _, stage := range report.Stages {
+		names = append(names, stage.Name+":"+stage.Outcome)
+	}
+	Equal(t, "deploy:panic,validate:passed,validate/validate_ecs:passed,validate/validate_rest_endpoints_load_balancer:passed,cleanup:skipped", strings.Join(names, ","))
+	Equal(t, "apply failed", report.Stages[0].Message)
+	Equal(t, "vi-rest-test", report.Stages[0].Resources[0].Id)
+	Equal(t, 2, report.Stages[2].Retries)
+	Equal(t, "http://lb:80/", report.Stages[3].Endpoints[0].Target)
+
+	content, err = os.ReadFile(filepath.Join(dir, "Test_Unit_RunTestStage_Report_scenario", "junit.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(content, &suites); err != nil {
+		t.Fatal(err)
+	}
+	suite := suites.Suites[0]
+	Equal(t, "Test_Unit_RunTestStage_Report/scenario", suite.Name)
+	Equal(t, 5, suite.Tests)
+	Equal(t, 1, suite.Errors)
+	Equal(t, 1, suite.Skipped)
+	Equal(t, 0, suite.Failures)
+	Equal(t, "apply failed", suite.Cases[0].Error.Text)
+	Equal(t, "retries: 2", suite.Cases[2].SystemOut)
+	Equal(t, "endpoint http://lb:80/: passed after 1 attempts, status 200 expected 200", suite.Cases[3].SystemOut)
+}
+
+func Test_Unit_Report_Junit(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	report := Report{
+		Test:  "Test_Unit_Microservice_Rest_ECS_EC2_Httpd/trunk",
+		Start: start,
+		End:   start.Add(90 * time.Second),
+		Stages: []*StageReport{
+			{Name: "deploy", Start: start, End: start.Add(time.Minute), Outcome: OutcomePassed},
+			{Name: "validate/validate_ecs", Start: start.Add(time.Minute), End: start.Add(90 * time.Second), Outcome: OutcomeFailed, Retries: 5},
+			{Name: "cleanup", Start: start.Add(90 * time.Second), Outcome: OutcomeRunning},
+		},
+	}
+	suite := report.junit().Suites[0]
+	Equal(t, "90.000", suite.Time)
+	Equal(t, "2023-01-01T00:00:00Z", suite.Timestamp)
+	Equal(t, 1, suite.Failures)
+	Equal(t, 1, suite.Errors)
+	Equal(t, "60.000", suite.Cases[0].Time)
+	Equal(t, "stage validate/validate_ecs failed", suite.Cases[1].Failure.Message)
+	Equal(t, "stage cleanup did not end", suite.Cases[2].Error.Message)
+	Equal(t, "0.000", suite.Cases[2].Time)
+}
+
+func Test_Unit_ReportRetry_NoStage(t *testing.T) {
+	// no report outside of the stages, e.g. the unit tests of the E functions
+	ReportRetry(t)
+	ReportResource(t, "ecs_cluster", "vi-rest-test")
+	ReportEndpoint(t, EndpointReport{})
+	if _, stage := currentStage(t); stage != nil {
+		t.Fatalf("unexpected stage %+v", stage)
+	}
+}
@@ -0,0 +1,175 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
+)
+
+// StateDirEnv is the directory of the state of the tests, written in `<STATE_DIR>/<test name>`, the temporary directory by default
+const StateDirEnv = "STATE_DIR"
+
+const (
+	// the stages skipped to resume a test, deploy once then iterate on the validations and destroy with the last run
+	stageDeploy  = "deploy"
+	stageCleanup = "cleanup"
+
+	stateTestID = "TestID"
+	// the timestamp of the state directories of the previous runs kept aside
+	previousStateDirLayout = "20060102150405"
+)
+
+// previousStateDirRegexp matches the state directories of the previous runs kept aside, `<test name>-<timestamp>`
+var previousStateDirRegexp = regexp.MustCompile(`-\d{14}$`)
+
+var (
+	stateDirsMu sync.Mutex
+	stateDirs   = map[string]bool{} // test name
+	stateIDs    = map[string]int{}  // test name
+)
+
+// StateDir is the directory kept between the runs of a test to resume its stages,
+// it holds the copy of the modules with the terraform state, the id and the options of the test
+func StateDir(testName string) string {
+	return filepath.Join(stateRoot(), strings.ReplaceAll(testName, "/", "_"))
+}
+
+func stateRoot() string {
+	if dir := os.Getenv(StateDirEnv); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "state")
+}
+
+// StateIDInUse returns true when the id is saved in the state directory of another test, running or kept, its resources may still exist.
+// The directory of the test and the ones of the previous runs kept aside are ignored, the sweeper finds the resources left by those
+func StateIDInUse(testName string) IDInUse {
+	return func(id string) (bool, error) {
+		paths, err := filepath.Glob(terratestStructure.FormatTestDataPath(filepath.Join(stateRoot(), "*"), stateTestID+"-*.json"))
+		if err != nil {
+			return false, err
+		}
+		for _, path := range paths {
+			// <state root>/<test name>/.test-data/TestID-N.json
+			dir := filepath.Dir(filepath.Dir(path))
+			if dir == StateDir(testName) || previousStateDirRegexp.MatchString(dir) {
+				continue
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return false, fmt.Errorf("read %s: %w", path, err)
+			}
+			var savedID string
+			if err := json.Unmarshal(b, &savedID); err != nil {
+				return false, fmt.Errorf("decode %s: %w", path, err)
+			}
+			if savedID == id {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// Resuming is true when the deploy stage is skipped, the test reuses the id, the options and the state of the previous run
+func Resuming() bool {
+	return os.Getenv("SKIP_"+stageDeploy) != ""
+}
+
+// TestStateDir returns the state directory of the test, the first call registers its removal when the test passes and the resources are destroyed
+func TestStateDir(t *testing.T) string {
+	dir := StateDir(t.Name())
+	stateDirsMu.Lock()
+	defer stateDirsMu.Unlock()
+	if stateDirs[t.Name()] {
+		return dir
+	}
+	stateDirs[t.Name()] = true
+	// the state of a previous run is kept aside, its resources may still exist
+	if !Resuming() && files.IsExistingDir(dir) {
+		previous := dir + "-" + time.Now().Format(previousStateDirLayout)
+		if err := os.Rename(dir, previous); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("state of the previous run moved to %s", previous)
+	}
+	t.Cleanup(func() {
+		stateDirsMu.Lock()
+		delete(stateDirs, t.Name())
+		delete(stateIDs, t.Name())
+		stateDirsMu.Unlock()
+		if t.Failed() || os.Getenv("SKIP_"+stageCleanup) != "" {
+			t.Logf("state kept in %s, resume with SKIP_%s=true", dir, stageDeploy)
+			return
+		}
+		os.RemoveAll(dir)
+	})
+	return dir
+}
+
+// resumeTestID returns the id saved by the previous run when resuming, it saves the id otherwise
+func resumeTestID(t *testing.T, generate func() string) string {
+	dir := TestStateDir(t)
+	stateDirsMu.Lock()
+	name := fmt.Sprintf("%s-%d", stateTestID, stateIDs[t.Name()])
+	stateIDs[t.Name()]++
+	stateDirsMu.Unlock()
+	if Resuming() {
+		if !files.FileExists(terratestStructure.FormatTestDataPath(dir, name+".json")) {
+			t.Fatalf("no %s to resume in %s, run the %s stage first", name, dir, stageDeploy)
+		}
+		return terratestStructure.LoadString(t, dir, name)
+	}
+	id := generate()
+	terratestStructure.SaveString(t, dir, name, id)
+	return id
+}
+
+// ResumeOptions saves the terraform options of the test, or when resuming checks that they are the ones of the deployed stack
+func ResumeOptions(t *testing.T, options *terraform.Options) {
+	dir := TestStateDir(t)
+	if !Resuming() {
+		terratestStructure.SaveTerraformOptions(t, dir, options)
+		return
+	}
+	if err := sameOptionsE(options, terratestStructure.LoadTerraformOptions(t, dir)); err != nil {
+		t.Fatalf("cannot resume from %s: %v", dir, err)
+	}
+}
+
+// sameOptionsE compares the variables as saved in json, their types are lost in the file
+func sameOptionsE(options, saved *terraform.Options) error {
+	vars, err := jsonValue(options.Vars)
+	if err != nil {
+		return err
+	}
+	savedVars, err := jsonValue(saved.Vars)
+	if err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(vars, savedVars) {
+		return fmt.Errorf("the variables differ from the deployed ones:\n%v\n%v", vars, savedVars)
+	}
+	return nil
+}
+
+func jsonValue(value any) (any, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var decoded any
+	err = json.Unmarshal(b, &decoded)
+	return decoded, err
+}
@@ -0,0 +1,123 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	terratestStructure "github.com/gruntwork-io/terratest/modules/test-structure"
+)
+
+func Test_Unit_Resume(t *testing.T) {
+	t.Setenv(StateDirEnv, t.TempDir())
+	t.Setenv(ArtifactsDirEnv, t.TempDir())
+	t.Setenv("SKIP_cleanup", "true")
+
+	vars := func(id string) map[string]any {
+		return map[string]any{"name": "vi-" + id, "min_size": 1, "capacities": []map[string]any{{"type": "ON_DEMAND", "weight": 50}}}
+	}
+
+	var id, path string
+	t.Run("deploy", func(t *testing.T) {
+		id = TestID(t, 4)
+		path = CopyModule(t, "../../modules/aws/data/bucket")
+		ResumeOptions(t, &terraform.Options{TerraformDir: path, Vars: vars(id)})
+	})
+	// the cleanup stage is skipped, the state is kept
+	deployDir := StateDir(t.Name() + "/deploy")
+	if !files.IsExistingDir(deployDir) {
+		t.Fatalf("state not kept in %s", deployDir)
+	}
+
+	// the same test is resumed from its state
+	resumeDir := StateDir(t.Name() + "/resume")
+	if err := os.Rename(deployDir, resumeDir); err != nil {
+		t.Fatal(err)
+	}
+	path = strings.Replace(path, deployDir, resumeDir, 1)
+	t.Setenv("SKIP_deploy", "true")
+	t.Run("resume", func(t *testing.T) {
+		Equal(t, id, TestID(t, 4))
+		Equal(t, path, CopyModule(t, "../../modules/aws/data/bucket"))
+		ResumeOptions(t, &terraform.Options{TerraformDir: path, Vars: vars(id)})
+	})
+	if !files.FileExists(filepath.Join(path, "main.tf")) {
+		t.Errorf("module not copied in %s", path)
+	}
+}
+
+func Test_Unit_Resume_PreviousState(t *testing.T) {
+	t.Setenv(StateDirEnv, t.TempDir())
+	dir := StateDir(t.Name())
+	if err := os.MkdirAll(filepath.Join(dir, "modules"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	Equal(t, dir, TestStateDir(t))
+	if files.IsExistingDir(filepath.Join(dir, "modules")) {
+		t.Errorf("state of the previous run not moved")
+	}
+	previous, _ := filepath.Glob(dir + "-*")
+	Equal(t, 1, len(previous))
+}
+
+func Test_Unit_StateIDInUse(t *testing.T) {
+	t.Setenv(StateDirEnv, t.TempDir())
+	// the id of another test, the one of the test and the one of a previous run kept aside
+	terratestStructure.SaveString(t, StateDir("Test_Unit_Other"), stateTestID+"-0", "abcd")
+	terratestStructure.SaveString(t, StateDir(t.Name()), stateTestID+"-0", "efgh")
+	terratestStructure.SaveString(t, StateDir("Test_Unit_Other")+"-20230601120000", stateTestID+"-1", "ijkl")
+
+	inUse := StateIDInUse(t.Name())
+	for id, expected := range map[string]bool{"abcd": true, "efgh": false, "ijkl": false, "mnop": false} {
+		used, err := inUse(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used != expected {
+			t.Errorf("id %s in use %t, expected %t", id, used, expected)
+		}
+	}
+
+	// the ids in use are skipped by TestIDE
+	id, err := TestIDE(42, t.Name(), 4, inUse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == "abcd" {
+		t.Errorf("id %s in use", id)
+	}
+}
+
+func Test_Unit_TestIDChecks(t *testing.T) {
+	t.Setenv(StateDirEnv, t.TempDir())
+	terratestStructure.SaveString(t, StateDir("Test_Unit_Other"), stateTestID+"-0", "abcd")
+
+	// the ids of a seed are reproduced without the state directories
+	for seed, expected := range map[string]bool{"": true, "42": false} {
+		checks := testIDChecks(t.Name(), func(string) string { return seed }, nil)
+		used, err := idInUse("abcd", checks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used != expected {
+			t.Errorf("seed %q: id abcd in use %t, expected %t", seed, used, expected)
+		}
+	}
+}
+
+func Test_Unit_SameOptions(t *testing.T) {
+	options := &terraform.Options{TerraformDir: "modules/a", Vars: map[string]any{"min_size": 1, "types": []string{"t3.small"}}}
+	// the numbers and the slices are decoded from the saved json
+	saved := &terraform.Options{TerraformDir: "modules/a", Vars: map[string]any{"min_size": float64(1), "types": []any{"t3.small"}}}
+	if err := sameOptionsE(options, saved); err != nil {
+		t.Error(err)
+	}
+
+	saved.Vars["min_size"] = float64(2)
+	if err := sameOptionsE(options, saved); err == nil || !strings.Contains(err.Error(), "the variables differ") {
+		t.Errorf("expected different variables, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+)
+
+// CopyModule copies the module and its local modules in the state directory of the test and returns the path of the module in it,
+// the relative sources between the modules resolve in the copy and the state, lock and override files of the test stay in it.
+// The copy of the previous run is reused when resuming
+func CopyModule(t *testing.T, modulePath string) string {
+	dest := filepath.Join(TestStateDir(t), "modules")
+	if Resuming() {
+		path, err := modulePathIn(modulePath, dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !files.IsExistingDir(path) {
+			t.Fatalf("no module to resume in %s, run the %s stage first", path, stageDeploy)
+		}
+		return path
+	}
+	if err := os.RemoveAll(dest); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path, err := CopyModuleE(modulePath, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// CopyModuleE copies the module and the local modules it sources in the destination,
+// at the same paths as in their repository, the folder with the go.mod, so that the relative sources resolve in the copy
+func CopyModuleE(modulePath, dest string) (string, error) {
+	absModulePath, err := filepath.Abs(modulePath)
+	if err != nil {
+		return "", err
+	}
+	if !files.IsExistingDir(absModulePath) {
+		return "", fmt.Errorf("module folder not found: %s", absModulePath)
+	}
+	root, err := repositoryRoot(absModulePath)
+	if err != nil {
+		return "", err
+	}
+	modulePaths, err := localModules(root, absModulePath)
+	if err != nil {
+		return "", err
+	}
+	for _, path := range modulePaths {
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(filepath.Join(dest, relPath), 0o755); err != nil {
+			return "", err
+		}
+		if err := files.CopyFolderContentsWithFilter(path, filepath.Join(dest, relPath), moduleFilter); err != nil {
+			return "", fmt.Errorf("copy %s: %w", path, err)
+		}
+	}
+	return modulePathIn(absModulePath, dest)
+}
+
+// localSourceRegexp matches the sources of the modules in a local folder, the other sources are downloaded by terraform init
+var localSourceRegexp = regexp.MustCompile(`\bsource\s*=\s*"(\.\.?/[^"]*)"`)
+
+// localModules returns the module and the local modules sourced by it and by them, in the repository root
+func localModules(root, modulePath string) ([]string, error) {
+	modulePaths := []string{}
+	visited := map[string]bool{}
+	queue := []string{modulePath}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		if visited[path] {
+			continue
+		}
+		visited[path] = true
+		modulePaths = append(modulePaths, path)
+
+		tfPaths, err := filepath.Glob(filepath.Join(path, "*.tf"))
+		if err != nil {
+			return nil, err
+		}
+		for _, tfPath := range tfPaths {
+			if !moduleFilter(tfPath) {
+				continue
+			}
+			content, err := os.ReadFile(tfPath)
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range localSourceRegexp.FindAllStringSubmatch(string(content), -1) {
+				sourcePath := filepath.Join(path, filepath.FromSlash(match[1]))
+				if relPath, err := filepath.Rel(root, sourcePath); err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+					return nil, fmt.Errorf("module %s in %s: outside of the repository %s", match[1], tfPath, root)
+				}
+				if !files.IsExistingDir(sourcePath) {
+					return nil, fmt.Errorf("module %s in %s: folder not found: %s", match[1], tfPath, sourcePath)
+				}
+				queue = append(queue, sourcePath)
+			}
+		}
+	}
+	sort.Strings(modulePaths)
+	return modulePaths, nil
+}
+
+// modulePathIn is the path of the module in the copy of its repository
+func modulePathIn(modulePath, dest string) (string, error) {
+	absModulePath, err := filepath.Abs(modulePath)
+	if err != nil {
+		return "", err
+	}
+	root, err := repositoryRoot(absModulePath)
+	if err != nil {
+		return "", err
+	}
+	relModulePath, err := filepath.Rel(root, absModulePath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dest, relModulePath), nil
+}
+
+// repositoryRoot is the first parent folder with a go.mod
+func repositoryRoot(path string) (string, error) {
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if files.FileExists(filepath.Join(dir, "go.mod")) {
+			return dir, nil
+		}
+		if dir == filepath.Dir(dir) {
+			return "", fmt.Errorf("no go.mod in the parent folders of %s", path)
+		}
+	}
+}
+
+// moduleFilter keeps the files of the modules, without the state, the caches and the files written by the other tests
+func moduleFilter(path string) bool {
+	name := filepath.Base(path)
+	switch {
+	case files.PathIsTerraformLockFile(path):
+		return true
+	case strings.HasPrefix(name, "."):
+		return false
+	case files.PathContainsTerraformStateOrVars(path), strings.HasSuffix(name, ".tfstate"):
+		return false
+	case strings.Contains(name, "_override."), strings.HasSuffix(name, ".go"):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_Unit_CopyModuleE(t *testing.T) {
+	root := t.TempDir()
+	for path, content := range map[string]string{
+		"go.mod":                                 "module example",
+		"modules/a/main.tf":                      "module \"b\" {\n  source = \"../b\"\n}\nmodule \"vpc\" {\n  source = \"terraform-aws-modules/vpc/aws\"\n}",
+		"modules/a/.terraform.lock.hcl":          "lock",
+		"modules/a/terraform.tfstate":            "{}",
+		"modules/a/terraform.tfstate.backup":     "{}",
+		"modules/a/backend_override.tf":          "override",
+		"modules/a/.terraform/modules/b/main.tf": "cache",
+		"modules/b/main.tf":                      "module \"c\" {\n  source = \"../../shared/c\"\n}",
+		"modules/b/templates/user_data.sh":       "#!/bin/bash",
+		"modules/unused/main.tf":                 "",
+		"shared/c/main.tf":                       "module \"b\" {\n  source = \"../../modules/b\"\n}",
+		"test/a/a_test.go":                       "package a",
+		".git/HEAD":                              "ref",
+	} {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dest := t.TempDir()
+	path, err := CopyModuleE(filepath.Join(root, "modules", "a"), dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Equal(t, filepath.Join(dest, "modules", "a"), path)
+
+	copied := []string{}
+	filepath.Walk(dest, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(dest, path)
+			copied = append(copied, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(copied)
+	// only the module and the modules it sources, at the same paths
+	Equal(t, "modules/a/.terraform.lock.hcl,modules/a/main.tf,modules/b/main.tf,modules/b/templates/user_data.sh,shared/c/main.tf", strings.Join(copied, ","))
+
+	if _, err := CopyModuleE(filepath.Join(root, "modules", "missing"), dest); err == nil || !strings.Contains(err.Error(), "module folder not found") {
+		t.Errorf("expected a missing module, got %v", err)
+	}
+
+	for source, errMsg := range map[string]string{"../missing": "folder not found", "../../../outside": "outside of the repository"} {
+		if err := os.WriteFile(filepath.Join(root, "modules", "unused", "main.tf"), []byte(`module "d" { source = "`+source+`" }`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := CopyModuleE(filepath.Join(root, "modules", "unused"), t.TempDir()); err == nil || !strings.Contains(err.Error(), errMsg) {
+			t.Errorf("source %s: expected error containing %q, got %v", source, errMsg, err)
+		}
+	}
+}